@@ -0,0 +1,54 @@
+// Package cli implements the mcp-middleware subcommands that run from a shell
+// instead of starting an MCP server.
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// command is a subcommand. It returns the process exit code.
+type command func(ctx context.Context, args []string, stdout, stderr io.Writer) int
+
+var commands = map[string]command{
+	"doctor":     runDoctor,
+	"dashboards": runDashboards,
+	"tools":      runTools,
+	"call":       runCall,
+}
+
+// IsCommand reports whether name is a subcommand.
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+	return ok
+}
+
+// Run runs the subcommand named by args[0] with the remaining arguments and
+// returns the process exit code.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || !IsCommand(args[0]) {
+		fmt.Fprintf(stderr, "unknown command (must be one of %s)\n", strings.Join(commandNames(), ", "))
+		return 2
+	}
+	return commands[args[0]](ctx, args[1:], stdout, stderr)
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseFlags parses args with fs, reporting errors to stderr. It returns false
+// if the command should exit.
+func parseFlags(fs *flag.FlagSet, args []string, stderr io.Writer) bool {
+	fs.SetOutput(stderr)
+	return fs.Parse(args) == nil
+}
@@ -0,0 +1,315 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"mcp-middleware/config"
+	"mcp-middleware/server/tools"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// dashboardCommands are the "dashboards" subcommands.
+var dashboardCommands = map[string]command{
+	"export": runDashboardsExport,
+	"import": runDashboardsImport,
+	"plan":   runDashboardsPlan,
+	"apply":  runDashboardsApply,
+}
+
+// runDashboards implements "dashboards <subcommand>".
+func runDashboards(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || dashboardCommands[args[0]] == nil {
+		fmt.Fprintln(stderr, "usage: mcp-middleware dashboards export <key> [--out file] | dashboards import <file> [--on-conflict skip|rename|overwrite] [--dry-run] | dashboards plan [--dir dir] [--adopt] [--json] | dashboards apply [--dir dir] [--adopt] [--yes] [--json]")
+		return 2
+	}
+	return dashboardCommands[args[0]](ctx, args[1:], stdout, stderr)
+}
+
+// runDashboardsExport implements "dashboards export <key> [--out file]".
+func runDashboardsExport(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dashboards export", flag.ContinueOnError)
+	var flags config.Flags
+	flags.Register(fs)
+	out := fs.String("out", "", "Write the document to this file instead of stdout")
+	verbose := fs.Bool("verbose", false, "Log the upstream requests")
+	positional, ok := parseInterspersed(fs, args, stderr)
+	if !ok {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: mcp-middleware dashboards export <key> [--out file]")
+		return 2
+	}
+
+	defer quietLogs(*verbose)()
+	srv, err := newServer(flags)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	result, err := srv.CallTool(ctx, "export_dashboard", map[string]any{"report_key": positional[0]})
+	if err != nil {
+		fmt.Fprintf(stderr, "export failed: %v\n", err)
+		return 1
+	}
+	if result.IsError {
+		fmt.Fprintf(stderr, "export failed: %s\n", resultText(result))
+		return 1
+	}
+
+	if *out == "" {
+		return printJSON(stdout, stderr, result.StructuredContent)
+	}
+	file, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to create %s: %v\n", *out, err)
+		return 1
+	}
+	code := printJSON(file, stderr, result.StructuredContent)
+	if err := file.Close(); err != nil && code == 0 {
+		fmt.Fprintf(stderr, "failed to write %s: %v\n", *out, err)
+		return 1
+	}
+	if code == 0 {
+		fmt.Fprintf(stderr, "Exported dashboard %s to %s\n", positional[0], *out)
+	}
+	return code
+}
+
+// runDashboardsImport implements "dashboards import <file> [--on-conflict
+// policy] [--dry-run]". A file of "-" reads the document from stdin.
+func runDashboardsImport(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dashboards import", flag.ContinueOnError)
+	var flags config.Flags
+	flags.Register(fs)
+	onConflict := fs.String("on-conflict", "skip", "What to do when the dashboard key exists: skip, rename or overwrite")
+	dryRun := fs.Bool("dry-run", false, "Print what the import would do without changing anything")
+	jsonOutput := fs.Bool("json", false, "Print the import result as JSON")
+	verbose := fs.Bool("verbose", false, "Log the upstream requests")
+	positional, ok := parseInterspersed(fs, args, stderr)
+	if !ok {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: mcp-middleware dashboards import <file> [--on-conflict skip|rename|overwrite] [--dry-run]")
+		return 2
+	}
+
+	var data []byte
+	var err error
+	if positional[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(positional[0])
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to read %s: %v\n", positional[0], err)
+		return 1
+	}
+	var document map[string]any
+	if err := json.Unmarshal(data, &document); err != nil {
+		fmt.Fprintf(stderr, "%s is not a dashboard document: %v\n", positional[0], err)
+		return 1
+	}
+
+	defer quietLogs(*verbose)()
+	srv, err := newServer(flags)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	result, err := srv.CallTool(ctx, "import_dashboard", map[string]any{
+		"document":    document,
+		"on_conflict": *onConflict,
+		"dry_run":     *dryRun,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "import failed: %v\n", err)
+		return 1
+	}
+	if result.IsError {
+		fmt.Fprintf(stderr, "import failed: %s\n", resultText(result))
+		return 1
+	}
+	if *jsonOutput {
+		return printJSON(stdout, stderr, result.StructuredContent)
+	}
+
+	imported, ok := result.StructuredContent.(*tools.ImportDashboardResult)
+	if !ok {
+		fmt.Fprintln(stdout, resultText(result))
+		return 0
+	}
+	fmt.Fprintln(stdout, imported.Message)
+	for _, widget := range imported.Widgets {
+		fmt.Fprintf(stdout, "  %s (%s)\n", widget.Label, widget.Key)
+	}
+	return 0
+}
+
+// runDashboardsPlan implements "dashboards plan [dir] [--adopt]".
+func runDashboardsPlan(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	return planDashboards(ctx, "plan", args, stdout, stderr)
+}
+
+// runDashboardsApply implements "dashboards apply [dir] [--adopt] [--yes]".
+func runDashboardsApply(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	return planDashboards(ctx, "apply", args, stdout, stderr)
+}
+
+// planDashboards plans the dashboards in the directory, given as an argument
+// or with --dir, against the account and, for apply, carries out the plan. A
+// plan that removes widgets is only applied with --yes.
+func planDashboards(ctx context.Context, subcommand string, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dashboards "+subcommand, flag.ContinueOnError)
+	var flags config.Flags
+	flags.Register(fs)
+	dir := fs.String("dir", "dashboards", "Directory of dashboard YAML or JSON files")
+	adopt := fs.Bool("adopt", false, "Take over existing dashboards that aren't managed by mcp-middleware yet")
+	jsonOutput := fs.Bool("json", false, "Print the plan as JSON")
+	verbose := fs.Bool("verbose", false, "Log the upstream requests")
+	usage := "usage: mcp-middleware dashboards plan [dir] [--adopt] [--json]"
+	var yes *bool
+	if subcommand == "apply" {
+		yes = fs.Bool("yes", false, "Apply plans that remove widgets")
+		usage = "usage: mcp-middleware dashboards apply [dir] [--adopt] [--yes] [--json]"
+	}
+	positional, ok := parseInterspersed(fs, args, stderr)
+	if !ok {
+		return 2
+	}
+	if len(positional) > 1 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+	if len(positional) == 1 {
+		*dir = positional[0]
+	}
+
+	docs, files, err := tools.LoadDashboardDir(*dir)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	defer quietLogs(*verbose)()
+	srv, err := newServer(flags)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	client, err := srv.ClientFor("")
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	plan, err := tools.PlanDashboards(ctx, client, docs, files, *adopt)
+	if err != nil {
+		fmt.Fprintf(stderr, "plan failed: %v\n", err)
+		return 1
+	}
+	if *jsonOutput {
+		if code := printJSON(stdout, stderr, plan); code != 0 {
+			return code
+		}
+	} else {
+		printPlan(stdout, plan)
+	}
+	if subcommand == "plan" {
+		return 0
+	}
+	if removed := plannedRemovals(plan); removed > 0 && !*yes {
+		fmt.Fprintf(stderr, "The plan removes %d widgets; rerun with --yes to apply it.\n", removed)
+		return 1
+	}
+
+	logf := func(format string, args ...any) {
+		if !*jsonOutput {
+			fmt.Fprintf(stdout, format+"\n", args...)
+		}
+	}
+	if err := tools.ApplyDashboardPlan(ctx, client, plan, logf); err != nil {
+		fmt.Fprintf(stderr, "apply failed: %v\n", err)
+		return 1
+	}
+	logf("Apply complete.")
+	return 0
+}
+
+// plannedRemovals counts the widgets plan removes.
+func plannedRemovals(plan *tools.DashboardPlan) int {
+	removed := 0
+	for _, item := range plan.Dashboards {
+		removed += len(item.Removed)
+	}
+	return removed
+}
+
+// printPlan prints plan in a Terraform-like form: + for additions, ~ for
+// changes and - for removals.
+func printPlan(w io.Writer, plan *tools.DashboardPlan) {
+	var creates, updates, added, changed, removed, moved int
+	for _, item := range plan.Dashboards {
+		switch item.Action {
+		case tools.PlanCreate:
+			creates++
+			fmt.Fprintf(w, "+ dashboard %s (%q): create with %d widgets\n", item.Key, item.Label, len(item.Added))
+		case tools.PlanUpdate:
+			updates++
+			fmt.Fprintf(w, "~ dashboard %s (%q)\n", item.Key, item.Label)
+			for _, setting := range item.Settings {
+				if setting.From == "" && setting.To == "" {
+					fmt.Fprintf(w, "    ~ %s\n", setting.Field)
+				} else {
+					fmt.Fprintf(w, "    ~ %s: %q -> %q\n", setting.Field, setting.From, setting.To)
+				}
+			}
+			for _, widget := range item.Added {
+				fmt.Fprintf(w, "    + widget %q\n", widget.Label)
+			}
+			for _, widget := range item.Changed {
+				fmt.Fprintf(w, "    ~ widget %q: %s\n", widget.Label, strings.Join(widget.Fields, ", "))
+			}
+			for _, widget := range item.Removed {
+				fmt.Fprintf(w, "    - widget %q\n", widget.Label)
+			}
+			for _, widget := range item.Moved {
+				fmt.Fprintf(w, "    ~ widget %q moves from %s to %s\n", widget.Label, widget.From, widget.To)
+			}
+		case tools.PlanSkip:
+			fmt.Fprintf(w, "! dashboard %s (%q) exists but isn't managed by mcp-middleware; it is skipped (use --adopt to take it over)\n", item.Key, item.Label)
+		default:
+			fmt.Fprintf(w, "  dashboard %s (%q): no changes\n", item.Key, item.Label)
+		}
+		added += len(item.Added)
+		changed += len(item.Changed)
+		removed += len(item.Removed)
+		moved += len(item.Moved)
+	}
+	for _, key := range plan.Unlisted {
+		fmt.Fprintf(w, "! dashboard %s is managed but has no file; it is left unchanged\n", key)
+	}
+	fmt.Fprintf(w, "\nPlan: %d dashboards to create, %d to update; %d widgets to add, %d to change, %d to remove, %d to move.\n",
+		creates, updates, added, changed, removed, moved)
+}
+
+// resultText joins the text content of a tool result.
+func resultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
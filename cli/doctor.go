@@ -0,0 +1,319 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"mcp-middleware/config"
+	"mcp-middleware/middleware"
+)
+
+// Check statuses
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// doctorTimeout bounds the whole doctor run.
+const doctorTimeout = 60 * time.Second
+
+// Check is the result of one doctor check. Fix suggests how to resolve a
+// failure or warning.
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// DoctorReport is the result of a doctor run.
+type DoctorReport struct {
+	Account string  `json:"account,omitempty"`
+	BaseURL string  `json:"base_url,omitempty"`
+	OK      bool    `json:"ok"`
+	Checks  []Check `json:"checks"`
+}
+
+func (r *DoctorReport) add(check Check) {
+	r.Checks = append(r.Checks, check)
+}
+
+// skipRemaining records the checks that can't run after an earlier failure.
+func (r *DoctorReport) skipRemaining(names []string, reason string) {
+	for _, name := range names {
+		r.add(Check{Name: name, Status: StatusSkip, Message: reason})
+	}
+}
+
+// apiChecks are the upstream calls doctor makes with the configured credentials.
+var apiChecks = []struct {
+	name string
+	call func(ctx context.Context, client *middleware.Client) error
+}{
+	{"api_resources", func(ctx context.Context, client *middleware.Client) error {
+		_, err := client.GetResources(ctx)
+		return err
+	}},
+	{"api_dashboards", func(ctx context.Context, client *middleware.Client) error {
+		_, err := client.GetDashboards(ctx, &middleware.GetDashboardsParams{Limit: 1})
+		return err
+	}},
+	{"api_incidents", func(ctx context.Context, client *middleware.Client) error {
+		now := time.Now()
+		_, err := client.GetIncidents(ctx, &middleware.GetIncidentsParams{
+			FromTs: now.Add(-24 * time.Hour).UnixMilli(),
+			ToTs:   now.UnixMilli(),
+			Page:   1,
+		})
+		return err
+	}},
+}
+
+func checkNames(from string) []string {
+	names := []string{"credentials", "base_url", "dns"}
+	for _, check := range apiChecks {
+		names = append(names, check.name)
+	}
+	for i, name := range names {
+		if name == from {
+			return names[i:]
+		}
+	}
+	return nil
+}
+
+func runDoctor(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	var flags config.Flags
+	flags.Register(fs)
+	jsonOutput := fs.Bool("json", false, "Print the report as JSON")
+	verbose := fs.Bool("verbose", false, "Log the upstream requests")
+	if !parseFlags(fs, args, stderr) {
+		return 2
+	}
+
+	defer quietLogs(*verbose)()
+
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	report := Doctor(ctx, flags)
+
+	if *jsonOutput {
+		if code := printJSON(stdout, stderr, report); code != 0 {
+			return code
+		}
+	} else {
+		printReport(stdout, report)
+	}
+
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// Doctor loads the configuration for flags and checks that the selected
+// account's base URL resolves and that its credentials work against the API.
+func Doctor(ctx context.Context, flags config.Flags) *DoctorReport {
+	report := &DoctorReport{Checks: []Check{}}
+	defer func() {
+		report.OK = true
+		for _, check := range report.Checks {
+			if check.Status == StatusFail {
+				report.OK = false
+			}
+		}
+	}()
+
+	cfg, err := config.LoadWithFlags(flags)
+	if err != nil {
+		report.add(Check{
+			Name:    "config",
+			Status:  StatusFail,
+			Message: err.Error(),
+			Fix:     "Set the missing or invalid setting in the environment, .env or the config file (see .env.example and config.example.yaml)",
+		})
+		report.skipRemaining(checkNames("credentials"), "configuration is invalid")
+		return report
+	}
+	account, err := cfg.GetAccount("")
+	if err != nil {
+		report.add(Check{Name: "config", Status: StatusFail, Message: err.Error(), Fix: "Set DEFAULT_ACCOUNT or pass --account"})
+		report.skipRemaining(checkNames("credentials"), "configuration is invalid")
+		return report
+	}
+	report.Account = account.Name
+	report.BaseURL = account.MiddlewareBaseURL
+
+	message := "configuration is valid"
+	if cfg.ConfigFile != "" {
+		message += " (config file " + cfg.ConfigFile + ")"
+	}
+	report.add(Check{Name: "config", Status: StatusPass, Message: message})
+
+	apiKey, authorization, err := account.ResolveCredentials(ctx)
+	if err != nil {
+		report.add(Check{
+			Name:    "credentials",
+			Status:  StatusFail,
+			Message: err.Error(),
+			Fix:     "Check that the credential file exists and isn't empty, or run the credential command by hand",
+		})
+		report.skipRemaining(checkNames("base_url"), "no credentials")
+		return report
+	}
+	report.add(Check{Name: "credentials", Status: StatusPass, Message: credentialSource(account)})
+
+	baseURL, check := checkBaseURL(account.MiddlewareBaseURL)
+	report.add(check)
+	if check.Status == StatusFail {
+		report.skipRemaining(checkNames("dns"), "base URL is invalid")
+		return report
+	}
+
+	check = checkDNS(ctx, baseURL.Hostname())
+	report.add(check)
+	if check.Status == StatusFail {
+		report.skipRemaining(checkNames(apiChecks[0].name), "base URL does not resolve")
+		return report
+	}
+
+	client := middleware.NewClientWithAuth(account.MiddlewareBaseURL, apiKey, authorization)
+	for _, apiCheck := range apiChecks {
+		start := time.Now()
+		if err := apiCheck.call(ctx, client); err != nil {
+			message, fix := diagnoseAPIError(err)
+			report.add(Check{Name: apiCheck.name, Status: StatusFail, Message: message, Fix: fix})
+			continue
+		}
+		report.add(Check{Name: apiCheck.name, Status: StatusPass, Message: fmt.Sprintf("ok (%d ms)", time.Since(start).Milliseconds())})
+	}
+	return report
+}
+
+func credentialSource(account *config.Account) string {
+	switch {
+	case account.AuthorizationToken != "":
+		return "using the authorization token"
+	case account.MiddlewareAPIKey != "":
+		return "using the API key"
+	case account.AuthorizationFile != "":
+		return "read the authorization token from " + account.AuthorizationFile
+	case account.MiddlewareAPIKeyFile != "":
+		return "read the API key from " + account.MiddlewareAPIKeyFile
+	default:
+		return "read the API key from the credential command"
+	}
+}
+
+// checkBaseURL checks that the base URL is an http(s) URL of a project rather
+// than of an API path, which the client adds itself.
+func checkBaseURL(raw string) (*url.URL, Check) {
+	check := Check{Name: "base_url"}
+	baseURL, err := url.Parse(raw)
+	if err != nil || baseURL.Host == "" || (baseURL.Scheme != "http" && baseURL.Scheme != "https") {
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("%q is not an http(s) URL", raw)
+		check.Fix = "Set MIDDLEWARE_BASE_URL to your project URL, e.g. https://your-project.middleware.io"
+		return nil, check
+	}
+
+	path := strings.TrimSuffix(baseURL.Path, "/")
+	switch {
+	case strings.HasSuffix(path, "/api/v1") || strings.HasSuffix(path, "/api"):
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("base URL includes the API path %s", baseURL.Path)
+		check.Fix = "Remove the path from MIDDLEWARE_BASE_URL; /api/v1 is added to every request"
+	case path != "":
+		check.Status = StatusWarn
+		check.Message = fmt.Sprintf("base URL has a path (%s); requests go to %s/api/v1", baseURL.Path, strings.TrimSuffix(raw, "/"))
+		check.Fix = "Middleware project URLs normally have no path, e.g. https://your-project.middleware.io"
+	default:
+		check.Status = StatusPass
+		check.Message = raw
+	}
+	return baseURL, check
+}
+
+func checkDNS(ctx context.Context, host string) Check {
+	check := Check{Name: "dns"}
+	if net.ParseIP(host) != nil {
+		check.Status = StatusPass
+		check.Message = host + " is an IP address"
+		return check
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("failed to resolve %s: %v", host, err)
+		check.Fix = "Check the project name in MIDDLEWARE_BASE_URL and your DNS or VPN settings"
+		return check
+	}
+	check.Status = StatusPass
+	check.Message = fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", "))
+	return check
+}
+
+// diagnoseAPIError explains a failed API call and suggests a fix.
+func diagnoseAPIError(err error) (message, fix string) {
+	var apiErr *middleware.APIError
+	if !errors.As(err, &apiErr) {
+		if strings.Contains(err.Error(), "failed to unmarshal response") {
+			return err.Error(), "The base URL doesn't look like a Middleware project; set MIDDLEWARE_BASE_URL to https://your-project.middleware.io"
+		}
+		return err.Error(), "Check your network and proxy settings and that MIDDLEWARE_BASE_URL is reachable from this machine"
+	}
+
+	switch {
+	case apiErr.HTML && (apiErr.StatusCode == http.StatusOK || isLoginPage(apiErr.Message)):
+		return "received an HTML page (probably a login page) instead of JSON",
+			"The request was answered by the web app rather than the API; check that MIDDLEWARE_BASE_URL is your project URL and that the API key is valid"
+	case apiErr.StatusCode == http.StatusUnauthorized:
+		return "the credentials were rejected (401)",
+			"Check MIDDLEWARE_API_KEY or AUTHORIZATION; create a new key at https://app.middleware.io/settings/api-keys"
+	case apiErr.StatusCode == http.StatusForbidden:
+		return "the credentials lack permission for this endpoint (403)",
+			"Use an API key of a user with access to this part of the project"
+	case apiErr.StatusCode == http.StatusNotFound || apiErr.HTML:
+		return fmt.Sprintf("endpoint not found (%d)", apiErr.StatusCode),
+			"Check MIDDLEWARE_BASE_URL: it should be https://your-project.middleware.io without a path"
+	default:
+		return apiErr.Error(), "The Middleware API returned an error; retry later or check the Middleware status page"
+	}
+}
+
+func isLoginPage(body string) bool {
+	body = strings.ToLower(body)
+	return strings.Contains(body, "login") || strings.Contains(body, "sign in") || strings.Contains(body, "signin")
+}
+
+func printReport(w io.Writer, report *DoctorReport) {
+	fmt.Fprintln(w, "Middleware MCP Server doctor")
+	if report.Account != "" {
+		fmt.Fprintf(w, "Account: %s (%s)\n", report.Account, report.BaseURL)
+	}
+	fmt.Fprintln(w)
+
+	counts := make(map[string]int)
+	for _, check := range report.Checks {
+		counts[check.Status]++
+		fmt.Fprintf(w, "[%s] %-15s %s\n", strings.ToUpper(check.Status), check.Name, check.Message)
+		if check.Fix != "" {
+			fmt.Fprintf(w, "       %-15s Fix: %s\n", "", check.Fix)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d passed, %d warnings, %d failed, %d skipped\n",
+		counts[StatusPass], counts[StatusWarn], counts[StatusFail], counts[StatusSkip])
+}
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"mcp-middleware/config"
+	"mcp-middleware/server"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// argFlags collects repeated --arg key=value flags.
+type argFlags []string
+
+func (a *argFlags) String() string {
+	return strings.Join(*a, ", ")
+}
+
+func (a *argFlags) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("invalid argument %q (must be key=value)", value)
+	}
+	*a = append(*a, value)
+	return nil
+}
+
+// newServer loads the configuration and creates a server with every allowed
+// tool registered, as a server started without dynamic toolsets would have.
+func newServer(flags config.Flags) (*server.Server, error) {
+	static := false
+	flags.DynamicToolsets = &static
+
+	cfg, err := config.LoadWithFlags(flags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return server.New(cfg), nil
+}
+
+// quietLogs discards the log output unless verbose is set, and returns a
+// function restoring it.
+func quietLogs(verbose bool) func() {
+	if verbose {
+		return func() {}
+	}
+	writer := log.Writer()
+	log.SetOutput(io.Discard)
+	return func() { log.SetOutput(writer) }
+}
+
+// runTools implements "tools list" and "tools schema <name>".
+func runTools(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "schema") {
+		fmt.Fprintln(stderr, "usage: mcp-middleware tools list [--json] | tools schema <name>")
+		return 2
+	}
+	subcommand := args[0]
+
+	fs := flag.NewFlagSet("tools "+subcommand, flag.ContinueOnError)
+	var flags config.Flags
+	flags.Register(fs)
+	jsonOutput := fs.Bool("json", false, "Print the tools as JSON, as tools/list returns them")
+	positional, ok := parseInterspersed(fs, args[1:], stderr)
+	if !ok {
+		return 2
+	}
+
+	defer quietLogs(false)()
+	srv, err := newServer(flags)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if subcommand == "schema" {
+		if len(positional) != 1 {
+			fmt.Fprintln(stderr, "usage: mcp-middleware tools schema <name>")
+			return 2
+		}
+		for _, tool := range srv.Tools() {
+			if tool.Name == positional[0] {
+				return printJSON(stdout, stderr, json.RawMessage(inputSchema(tool)))
+			}
+		}
+		fmt.Fprintf(stderr, "unknown tool: %s (see mcp-middleware tools list)\n", positional[0])
+		return 1
+	}
+
+	if len(positional) != 0 {
+		fmt.Fprintln(stderr, "usage: mcp-middleware tools list [--json]")
+		return 2
+	}
+	if *jsonOutput {
+		return printJSON(stdout, stderr, mcp.ListToolsResult{Tools: srv.Tools()})
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, tool := range srv.Tools() {
+		mode := "write"
+		if tool.Annotations.ReadOnlyHint != nil && *tool.Annotations.ReadOnlyHint {
+			mode = "read"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", tool.Name, mode, server.DescriptionSummary(tool.Description))
+	}
+	w.Flush()
+	return 0
+}
+
+// runCall implements "call <tool> [--args '{...}'] [--arg key=value]...".
+func runCall(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("call", flag.ContinueOnError)
+	var flags config.Flags
+	flags.Register(fs)
+	argsJSON := fs.String("args", "", "Tool arguments as a JSON object")
+	var argPairs argFlags
+	fs.Var(&argPairs, "arg", "Tool argument as key=value; repeatable. Values that parse as JSON are used as such, others as strings")
+	jsonOutput := fs.Bool("json", false, "Print the whole tool result as JSON")
+	verbose := fs.Bool("verbose", false, "Log the upstream requests")
+	positional, ok := parseInterspersed(fs, args, stderr)
+	if !ok {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: mcp-middleware call <tool> [--args '{...}'] [--arg key=value]...")
+		return 2
+	}
+
+	toolArgs, err := parseToolArgs(*argsJSON, argPairs)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	defer quietLogs(*verbose)()
+	srv, err := newServer(flags)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	result, err := srv.CallTool(ctx, positional[0], toolArgs)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s failed: %v\n", positional[0], err)
+		return 1
+	}
+
+	if *jsonOutput {
+		if code := printJSON(stdout, stderr, result); code != 0 {
+			return code
+		}
+	} else {
+		out := stdout
+		if result.IsError {
+			out = stderr
+		}
+		for _, content := range result.Content {
+			if text, ok := mcp.AsTextContent(content); ok {
+				fmt.Fprintln(out, indentJSON(text.Text))
+			}
+		}
+	}
+
+	if result.IsError {
+		return 1
+	}
+	return 0
+}
+
+// parseInterspersed parses flags that may come before or after the positional
+// arguments, which the flag package alone stops at.
+func parseInterspersed(fs *flag.FlagSet, args []string, stderr io.Writer) ([]string, bool) {
+	var positional []string
+	for {
+		if !parseFlags(fs, args, stderr) {
+			return nil, false
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, true
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseToolArgs merges the --args object with the --arg pairs, which take precedence.
+func parseToolArgs(argsJSON string, pairs []string) (map[string]any, error) {
+	args := make(map[string]any)
+	if argsJSON != "" {
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return nil, fmt.Errorf("invalid --args: must be a JSON object: %w", err)
+		}
+	}
+
+	for _, pair := range pairs {
+		key, value, _ := strings.Cut(pair, "=")
+		var parsed any
+		if err := json.Unmarshal([]byte(value), &parsed); err == nil {
+			args[key] = parsed
+		} else {
+			args[key] = value
+		}
+	}
+	return args, nil
+}
+
+func inputSchema(tool mcp.Tool) []byte {
+	if tool.RawInputSchema != nil {
+		return tool.RawInputSchema
+	}
+	schema, _ := json.Marshal(tool.InputSchema)
+	return schema
+}
+
+func printJSON(stdout, stderr io.Writer, v any) int {
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintf(stderr, "failed to encode output: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// indentJSON indents text if it is JSON, and returns it unchanged otherwise.
+func indentJSON(text string) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(text), "", "  "); err != nil {
+		return text
+	}
+	return out.String()
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultAccountName is the name of the account formed by MIDDLEWARE_API_KEY,
+// AUTHORIZATION and MIDDLEWARE_BASE_URL.
+const DefaultAccountName = "default"
+
+// Account is a named Middleware project with its own credentials. The
+// credentials are given directly, or read from a file or credential command
+// (see ResolveCredentials).
+type Account struct {
+	Name                 string `yaml:"-" json:"-"`
+	MiddlewareAPIKey     string `yaml:"middleware_api_key" json:"middleware_api_key"`
+	AuthorizationToken   string `yaml:"authorization" json:"authorization"`
+	MiddlewareBaseURL    string `yaml:"middleware_base_url" json:"middleware_base_url"`
+	MiddlewareAPIKeyFile string `yaml:"middleware_api_key_file" json:"middleware_api_key_file"`
+	AuthorizationFile    string `yaml:"authorization_file" json:"authorization_file"`
+	CredentialCommand    string `yaml:"credential_command" json:"credential_command"`
+}
+
+// GetAccount returns the named account, or the default account if name is empty.
+func (c *Config) GetAccount(name string) (*Account, error) {
+	if name == "" {
+		name = c.defaultAccountName()
+	}
+
+	if account, ok := c.Accounts[name]; ok {
+		account.Name = name
+		return &account, nil
+	}
+	if name == DefaultAccountName && c.hasTopLevelAccount() {
+		account := c.topLevelAccount()
+		return &account, nil
+	}
+	return nil, fmt.Errorf("unknown account: %s", name)
+}
+
+// AccountNames returns the names of all configured accounts in sorted order.
+func (c *Config) AccountNames() []string {
+	names := make([]string, 0, len(c.Accounts)+1)
+	for name := range c.Accounts {
+		names = append(names, name)
+	}
+	if _, ok := c.Accounts[DefaultAccountName]; !ok && c.hasTopLevelAccount() {
+		names = append(names, DefaultAccountName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Config) defaultAccountName() string {
+	if c.DefaultAccount != "" {
+		return c.DefaultAccount
+	}
+	if len(c.Accounts) == 1 && !c.hasTopLevelAccount() {
+		for name := range c.Accounts {
+			return name
+		}
+	}
+	return DefaultAccountName
+}
+
+// topLevelAccount returns the account formed by the top-level credentials.
+func (c *Config) topLevelAccount() Account {
+	return Account{
+		Name:                 DefaultAccountName,
+		MiddlewareAPIKey:     c.MiddlewareAPIKey,
+		AuthorizationToken:   c.AuthorizationToken,
+		MiddlewareBaseURL:    c.MiddlewareBaseURL,
+		MiddlewareAPIKeyFile: c.MiddlewareAPIKeyFile,
+		AuthorizationFile:    c.AuthorizationFile,
+		CredentialCommand:    c.CredentialCommand,
+	}
+}
+
+func (c *Config) hasTopLevelAccount() bool {
+	account := c.topLevelAccount()
+	return account.hasCredentials() && c.MiddlewareBaseURL != ""
+}
+
+// validateAccounts checks every named account and that the default account exists.
+func (c *Config) validateAccounts() error {
+	for name, account := range c.Accounts {
+		if !account.hasCredentials() {
+			return fmt.Errorf("account %s: middleware_api_key, authorization, a credential file or credential_command is required", name)
+		}
+		if account.MiddlewareBaseURL == "" {
+			return fmt.Errorf("account %s: middleware_base_url is required", name)
+		}
+	}
+
+	if _, err := c.GetAccount(""); err != nil {
+		if c.DefaultAccount == "" {
+			return fmt.Errorf("DEFAULT_ACCOUNT is required when several accounts are configured")
+		}
+		return fmt.Errorf("invalid DEFAULT_ACCOUNT: %s (must be one of %v)", c.DefaultAccount, c.AccountNames())
+	}
+	return nil
+}
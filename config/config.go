@@ -3,7 +3,12 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +19,17 @@ type Config struct {
 	AuthorizationToken string
 	MiddlewareBaseURL  string
 
+	// Credential sources that are re-read when the API rejects the credentials:
+	// files holding the API key or authorization header, and a command printing the API key
+	MiddlewareAPIKeyFile string
+	AuthorizationFile    string
+	CredentialCommand    string
+
+	// Named accounts, selected per tool call with the account argument, and the
+	// account used when none is given
+	Accounts       map[string]Account
+	DefaultAccount string
+
 	// Application Mode: stdio, http, sse
 	AppMode string
 
@@ -21,50 +37,238 @@ type Config struct {
 	AppHost string
 	AppPort string
 
-	// Tool Exclusion
-	ExcludedTools map[string]bool
+	// Bearer token HTTP and SSE clients must send, if set
+	HTTPAuthToken string
+
+	// REST gateway: serve POST /v1/tools/{name} and an OpenAPI document in http mode
+	RESTGateway bool
+
+	// Tool Selection: toolset names and tool name patterns (globs such as list_*).
+	// Empty EnabledToolsets or IncludedTools allow everything.
+	EnabledToolsets map[string]bool
+	IncludedTools   map[string]bool
+	ExcludedTools   map[string]bool
+
+	// Confirmation before destructive tools: auto, require, off
+	ConfirmDestructive string
+
+	// Read-only mode: mutating tools aren't registered and the client refuses writes
+	ReadOnly bool
+
+	// Dynamic toolsets: start with only list_toolsets/enable_toolset and let the
+	// client enable toolsets at runtime
+	DynamicToolsets bool
+
+	// Directory of custom dashboard templates, added to the built-in ones
+	DashboardTemplatesDir string
+
+	// Local dashboard version history: where snapshots taken before mutating
+	// tool calls are kept, how many to keep per dashboard (0 disables the
+	// history), and how long to keep them (0 keeps them regardless of age)
+	DashboardHistoryDir      string
+	DashboardHistoryVersions int
+	DashboardHistoryMaxAge   time.Duration
+
+	// Watch the config file and reload it when it changes, as on SIGHUP
+	WatchConfig bool
+
+	// Path of the config file the configuration was loaded from, if any
+	ConfigFile string
 }
 
+// Load reads the configuration from the config file named by
+// MCP_MIDDLEWARE_CONFIG, if any, and the environment.
 func Load() (*Config, error) {
+	return LoadWithFlags(Flags{})
+}
+
+// LoadWithFlags reads the configuration with the precedence
+// defaults < config file < environment < flags.
+func LoadWithFlags(flags Flags) (*Config, error) {
 	// Try to load .env file, but don't fail if it doesn't exist
 	_ = godotenv.Load()
 
 	cfg := &Config{
-		MiddlewareAPIKey:   os.Getenv("MIDDLEWARE_API_KEY"),
-		AuthorizationToken: os.Getenv("AUTHORIZATION"),
-		MiddlewareBaseURL:  os.Getenv("MIDDLEWARE_BASE_URL"),
-		AppMode:            getEnvOrDefault("APP_MODE", "stdio"),
-		AppHost:            getEnvOrDefault("APP_HOST", "localhost"),
-		AppPort:            getEnvOrDefault("APP_PORT", "8080"),
+		AppMode:            "stdio",
+		AppHost:            "localhost",
+		AppPort:            "8080",
+		EnabledToolsets:    make(map[string]bool),
+		IncludedTools:      make(map[string]bool),
 		ExcludedTools:      make(map[string]bool),
+		ConfirmDestructive: "auto",
+
+		DashboardHistoryDir:      defaultHistoryDir(),
+		DashboardHistoryVersions: 20,
 	}
 
-	if cfg.MiddlewareAPIKey == "" && cfg.AuthorizationToken == "" {
-		return nil, fmt.Errorf("MIDDLEWARE_API_KEY is required")
+	cfg.ConfigFile = flags.ConfigFile
+	if cfg.ConfigFile == "" {
+		cfg.ConfigFile = os.Getenv(ConfigFileEnv)
 	}
-	if cfg.MiddlewareBaseURL == "" {
-		return nil, fmt.Errorf("MIDDLEWARE_BASE_URL is required")
+	if cfg.ConfigFile != "" {
+		file, err := LoadFile(cfg.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := file.apply(cfg); err != nil {
+			return nil, err
+		}
 	}
 
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+	flags.apply(cfg)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyEnv copies the environment variables that are set onto cfg.
+func applyEnv(cfg *Config) error {
+	cfg.MiddlewareAPIKey = getEnvOrDefault("MIDDLEWARE_API_KEY", cfg.MiddlewareAPIKey)
+	cfg.AuthorizationToken = getEnvOrDefault("AUTHORIZATION", cfg.AuthorizationToken)
+	cfg.MiddlewareBaseURL = getEnvOrDefault("MIDDLEWARE_BASE_URL", cfg.MiddlewareBaseURL)
+	cfg.MiddlewareAPIKeyFile = getEnvOrDefault("MIDDLEWARE_API_KEY_FILE", cfg.MiddlewareAPIKeyFile)
+	cfg.AuthorizationFile = getEnvOrDefault("AUTHORIZATION_FILE", cfg.AuthorizationFile)
+	cfg.CredentialCommand = getEnvOrDefault("CREDENTIAL_COMMAND", cfg.CredentialCommand)
+	cfg.AppMode = getEnvOrDefault("APP_MODE", cfg.AppMode)
+	cfg.AppHost = getEnvOrDefault("APP_HOST", cfg.AppHost)
+	cfg.AppPort = getEnvOrDefault("APP_PORT", cfg.AppPort)
+	cfg.HTTPAuthToken = getEnvOrDefault("HTTP_AUTH_TOKEN", cfg.HTTPAuthToken)
+	if err := getEnvBool("REST_GATEWAY", &cfg.RESTGateway); err != nil {
+		return err
+	}
+	cfg.DefaultAccount = getEnvOrDefault("DEFAULT_ACCOUNT", cfg.DefaultAccount)
+	cfg.ConfirmDestructive = getEnvOrDefault("CONFIRM_DESTRUCTIVE", cfg.ConfirmDestructive)
+	cfg.DashboardTemplatesDir = getEnvOrDefault("DASHBOARD_TEMPLATES_DIR", cfg.DashboardTemplatesDir)
+	cfg.DashboardHistoryDir = getEnvOrDefault("DASHBOARD_HISTORY_DIR", cfg.DashboardHistoryDir)
+	if err := getEnvInt("DASHBOARD_HISTORY_VERSIONS", &cfg.DashboardHistoryVersions); err != nil {
+		return err
+	}
+	if err := getEnvDuration("DASHBOARD_HISTORY_MAX_AGE", &cfg.DashboardHistoryMaxAge); err != nil {
+		return err
+	}
+
+	if toolsetsStr := os.Getenv("ENABLED_TOOLSETS"); toolsetsStr != "" {
+		cfg.EnabledToolsets = parseToolList(toolsetsStr)
+	}
+	if includedStr := os.Getenv("INCLUDED_TOOLS"); includedStr != "" {
+		cfg.IncludedTools = parseToolList(includedStr)
+	}
 	if excludedStr := os.Getenv("EXCLUDED_TOOLS"); excludedStr != "" {
-		for _, tool := range strings.Split(excludedStr, ",") {
-			tool = strings.TrimSpace(tool)
-			if tool != "" {
-				cfg.ExcludedTools[tool] = true
-			}
+		cfg.ExcludedTools = parseToolList(excludedStr)
+	}
+
+	if err := getEnvBool("READ_ONLY", &cfg.ReadOnly); err != nil {
+		return err
+	}
+	if err := getEnvBool("DYNAMIC_TOOLSETS", &cfg.DynamicToolsets); err != nil {
+		return err
+	}
+	if err := getEnvBool("WATCH_CONFIG", &cfg.WatchConfig); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate checks that the configuration is complete and its values are valid.
+func (c *Config) Validate() error {
+	if len(c.Accounts) == 0 {
+		if !c.topLevelAccount().hasCredentials() {
+			return fmt.Errorf("MIDDLEWARE_API_KEY, AUTHORIZATION, MIDDLEWARE_API_KEY_FILE, AUTHORIZATION_FILE or CREDENTIAL_COMMAND is required")
+		}
+		if c.MiddlewareBaseURL == "" {
+			return fmt.Errorf("MIDDLEWARE_BASE_URL is required")
 		}
 	}
+	if err := c.validateAccounts(); err != nil {
+		return err
+	}
 
 	validModes := map[string]bool{"stdio": true, "http": true, "sse": true}
-	if !validModes[cfg.AppMode] {
-		return nil, fmt.Errorf("invalid APP_MODE: %s (must be stdio, http, or sse)", cfg.AppMode)
+	if !validModes[c.AppMode] {
+		return fmt.Errorf("invalid APP_MODE: %s (must be stdio, http, or sse)", c.AppMode)
 	}
 
-	return cfg, nil
+	validConfirmModes := map[string]bool{"auto": true, "require": true, "off": true}
+	if !validConfirmModes[c.ConfirmDestructive] {
+		return fmt.Errorf("invalid CONFIRM_DESTRUCTIVE: %s (must be auto, require, or off)", c.ConfirmDestructive)
+	}
+
+	if c.DashboardHistoryVersions < 0 {
+		return fmt.Errorf("invalid DASHBOARD_HISTORY_VERSIONS: %d (must be 0 or more)", c.DashboardHistoryVersions)
+	}
+	if c.DashboardHistoryMaxAge < 0 {
+		return fmt.Errorf("invalid DASHBOARD_HISTORY_MAX_AGE: %s (must be 0 or more)", c.DashboardHistoryMaxAge)
+	}
+
+	for toolset := range c.EnabledToolsets {
+		if toolset != "all" && !slices.Contains(Toolsets, toolset) {
+			return fmt.Errorf("unknown toolset in ENABLED_TOOLSETS: %s (must be all or one of %s)", toolset, strings.Join(Toolsets, ", "))
+		}
+	}
+
+	for _, patterns := range []map[string]bool{c.IncludedTools, c.ExcludedTools} {
+		for pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid tool pattern: %s", pattern)
+			}
+		}
+	}
+
+	return nil
 }
 
+// Toolsets group related tools so they can be enabled together with ENABLED_TOOLSETS.
+const (
+	ToolsetDashboards = "dashboards"
+	ToolsetWidgets    = "widgets"
+	ToolsetMetrics    = "metrics"
+	ToolsetAlerts     = "alerts"
+	ToolsetErrors     = "errors"
+	ToolsetAccounts   = "accounts"
+)
+
+// Toolsets are the toolset names ENABLED_TOOLSETS accepts besides "all", in
+// registration order.
+var Toolsets = []string{ToolsetDashboards, ToolsetWidgets, ToolsetMetrics, ToolsetAlerts, ToolsetErrors, ToolsetAccounts}
+
 func (c *Config) IsToolExcluded(toolName string) bool {
-	return c.ExcludedTools[toolName]
+	return matchesAny(c.ExcludedTools, toolName)
+}
+
+// IsToolsetEnabled reports whether the tools in toolset may be registered.
+func (c *Config) IsToolsetEnabled(toolset string) bool {
+	return len(c.EnabledToolsets) == 0 || c.EnabledToolsets["all"] || c.EnabledToolsets[toolset]
+}
+
+// IsToolEnabled reports whether a tool in toolset should be registered: its
+// toolset is enabled, it matches INCLUDED_TOOLS (if set), and it isn't excluded.
+func (c *Config) IsToolEnabled(toolset, toolName string) bool {
+	if !c.IsToolsetEnabled(toolset) {
+		return false
+	}
+	if len(c.IncludedTools) > 0 && !matchesAny(c.IncludedTools, toolName) {
+		return false
+	}
+	return !c.IsToolExcluded(toolName)
+}
+
+// matchesAny reports whether name equals or matches any of the glob patterns.
+func matchesAny(patterns map[string]bool, name string) bool {
+	if patterns[name] {
+		return true
+	}
+	for pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -73,3 +277,68 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvBool sets *dst from the boolean environment variable key, if it is set.
+func getEnvBool(key string, dst *bool) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s (must be true or false)", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+// getEnvInt sets *dst from the integer environment variable key, if it is set.
+func getEnvInt(key string, dst *int) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s (must be a whole number)", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+// getEnvDuration sets *dst from the duration environment variable key, such as
+// 720h, if it is set.
+func getEnvDuration(key string, dst *time.Duration) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s (must be a duration such as 720h)", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+// defaultHistoryDir returns the default directory of the dashboard version
+// history, or "" if the user has no config directory.
+func defaultHistoryDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "mcp-middleware", "dashboard-history")
+}
+
+// parseToolList parses a comma-separated list of tool names into a set.
+func parseToolList(list string) map[string]bool {
+	tools := make(map[string]bool)
+	for _, tool := range strings.Split(list, ",") {
+		tool = strings.TrimSpace(tool)
+		if tool != "" {
+			tools[tool] = true
+		}
+	}
+	return tools
+}
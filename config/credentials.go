@@ -0,0 +1,93 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// hasCredentials reports whether any credential source is configured.
+func (a Account) hasCredentials() bool {
+	return a.MiddlewareAPIKey != "" || a.AuthorizationToken != "" ||
+		a.MiddlewareAPIKeyFile != "" || a.AuthorizationFile != "" || a.CredentialCommand != ""
+}
+
+// HasRefreshableCredentials reports whether the credentials come from a file or
+// command, and so may change while the server is running.
+func (a Account) HasRefreshableCredentials() bool {
+	return a.MiddlewareAPIKeyFile != "" || a.AuthorizationFile != "" || a.CredentialCommand != ""
+}
+
+// UsesAuthorization reports whether the account authenticates with an
+// Authorization header rather than an API key.
+func (a Account) UsesAuthorization() bool {
+	return a.AuthorizationToken != "" || a.AuthorizationFile != ""
+}
+
+// ResolveCredentials returns the account's API key and authorization header.
+// Each is taken from its value if set, otherwise from its file; the API key
+// falls back to the output of the credential command.
+func (a Account) ResolveCredentials(ctx context.Context) (apiKey, authorization string, err error) {
+	authorization = a.AuthorizationToken
+	if authorization == "" && a.AuthorizationFile != "" {
+		if authorization, err = readCredentialFile(a.AuthorizationFile); err != nil {
+			return "", "", err
+		}
+	}
+
+	apiKey = a.MiddlewareAPIKey
+	if apiKey == "" && a.MiddlewareAPIKeyFile != "" {
+		if apiKey, err = readCredentialFile(a.MiddlewareAPIKeyFile); err != nil {
+			return "", "", err
+		}
+	}
+	if apiKey == "" && authorization == "" && a.CredentialCommand != "" {
+		if apiKey, err = runCredentialCommand(ctx, a.CredentialCommand); err != nil {
+			return "", "", err
+		}
+	}
+
+	if apiKey == "" && authorization == "" {
+		return "", "", fmt.Errorf("no credentials configured for account %s", a.Name)
+	}
+	return apiKey, authorization, nil
+}
+
+func readCredentialFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read credential file: %w", err)
+	}
+	credential := strings.TrimSpace(string(data))
+	if credential == "" {
+		return "", fmt.Errorf("credential file %s is empty", path)
+	}
+	return credential, nil
+}
+
+// runCredentialCommand runs command through the shell and returns its output.
+func runCredentialCommand(ctx context.Context, command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("credential command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	credential := strings.TrimSpace(string(out))
+	if credential == "" {
+		return "", fmt.Errorf("credential command printed no credential")
+	}
+	return credential, nil
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the environment variable holding the config file path,
+// used when --config isn't given.
+const ConfigFileEnv = "MCP_MIDDLEWARE_CONFIG"
+
+// File is the schema of the YAML or JSON config file. Keys mirror the
+// environment variables they correspond to; unset keys leave the default.
+type File struct {
+	MiddlewareAPIKey         string             `yaml:"middleware_api_key" json:"middleware_api_key"`
+	Authorization            string             `yaml:"authorization" json:"authorization"`
+	MiddlewareBaseURL        string             `yaml:"middleware_base_url" json:"middleware_base_url"`
+	MiddlewareAPIKeyFile     string             `yaml:"middleware_api_key_file" json:"middleware_api_key_file"`
+	AuthorizationFile        string             `yaml:"authorization_file" json:"authorization_file"`
+	CredentialCommand        string             `yaml:"credential_command" json:"credential_command"`
+	Accounts                 map[string]Account `yaml:"accounts" json:"accounts"`
+	DefaultAccount           string             `yaml:"default_account" json:"default_account"`
+	AppMode                  string             `yaml:"app_mode" json:"app_mode"`
+	AppHost                  string             `yaml:"app_host" json:"app_host"`
+	AppPort                  string             `yaml:"app_port" json:"app_port"`
+	HTTPAuthToken            string             `yaml:"http_auth_token" json:"http_auth_token"`
+	RESTGateway              *bool              `yaml:"rest_gateway" json:"rest_gateway"`
+	EnabledToolsets          []string           `yaml:"enabled_toolsets" json:"enabled_toolsets"`
+	IncludedTools            []string           `yaml:"included_tools" json:"included_tools"`
+	ExcludedTools            []string           `yaml:"excluded_tools" json:"excluded_tools"`
+	ConfirmDestructive       string             `yaml:"confirm_destructive" json:"confirm_destructive"`
+	ReadOnly                 *bool              `yaml:"read_only" json:"read_only"`
+	DynamicToolsets          *bool              `yaml:"dynamic_toolsets" json:"dynamic_toolsets"`
+	WatchConfig              *bool              `yaml:"watch_config" json:"watch_config"`
+	DashboardTemplatesDir    string             `yaml:"dashboard_templates_dir" json:"dashboard_templates_dir"`
+	DashboardHistoryDir      string             `yaml:"dashboard_history_dir" json:"dashboard_history_dir"`
+	DashboardHistoryVersions *int               `yaml:"dashboard_history_versions" json:"dashboard_history_versions"`
+	DashboardHistoryMaxAge   string             `yaml:"dashboard_history_max_age" json:"dashboard_history_max_age"`
+}
+
+// LoadFile reads a config file. Files ending in .json are parsed as JSON and
+// everything else as YAML. Unknown keys are an error.
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file File
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&file)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&file)
+	}
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return &file, nil
+}
+
+// apply copies the values set in the file onto cfg.
+func (f *File) apply(cfg *Config) error {
+	setIfNotEmpty(&cfg.MiddlewareAPIKey, f.MiddlewareAPIKey)
+	setIfNotEmpty(&cfg.AuthorizationToken, f.Authorization)
+	setIfNotEmpty(&cfg.MiddlewareBaseURL, f.MiddlewareBaseURL)
+	setIfNotEmpty(&cfg.MiddlewareAPIKeyFile, f.MiddlewareAPIKeyFile)
+	setIfNotEmpty(&cfg.AuthorizationFile, f.AuthorizationFile)
+	setIfNotEmpty(&cfg.CredentialCommand, f.CredentialCommand)
+	setIfNotEmpty(&cfg.DefaultAccount, f.DefaultAccount)
+	if f.Accounts != nil {
+		cfg.Accounts = f.Accounts
+	}
+	setIfNotEmpty(&cfg.AppMode, f.AppMode)
+	setIfNotEmpty(&cfg.AppHost, f.AppHost)
+	setIfNotEmpty(&cfg.AppPort, f.AppPort)
+	setIfNotEmpty(&cfg.HTTPAuthToken, f.HTTPAuthToken)
+	if f.RESTGateway != nil {
+		cfg.RESTGateway = *f.RESTGateway
+	}
+	setIfNotEmpty(&cfg.ConfirmDestructive, f.ConfirmDestructive)
+	setIfNotEmpty(&cfg.DashboardTemplatesDir, f.DashboardTemplatesDir)
+	setIfNotEmpty(&cfg.DashboardHistoryDir, f.DashboardHistoryDir)
+	if f.DashboardHistoryVersions != nil {
+		cfg.DashboardHistoryVersions = *f.DashboardHistoryVersions
+	}
+	if f.DashboardHistoryMaxAge != "" {
+		maxAge, err := time.ParseDuration(f.DashboardHistoryMaxAge)
+		if err != nil {
+			return fmt.Errorf("invalid dashboard_history_max_age: %s (must be a duration such as 720h)", f.DashboardHistoryMaxAge)
+		}
+		cfg.DashboardHistoryMaxAge = maxAge
+	}
+	if f.EnabledToolsets != nil {
+		cfg.EnabledToolsets = parseToolList(strings.Join(f.EnabledToolsets, ","))
+	}
+	if f.IncludedTools != nil {
+		cfg.IncludedTools = parseToolList(strings.Join(f.IncludedTools, ","))
+	}
+	if f.ExcludedTools != nil {
+		cfg.ExcludedTools = parseToolList(strings.Join(f.ExcludedTools, ","))
+	}
+	if f.ReadOnly != nil {
+		cfg.ReadOnly = *f.ReadOnly
+	}
+	if f.DynamicToolsets != nil {
+		cfg.DynamicToolsets = *f.DynamicToolsets
+	}
+	if f.WatchConfig != nil {
+		cfg.WatchConfig = *f.WatchConfig
+	}
+	return nil
+}
+
+func setIfNotEmpty(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"flag"
+	"strconv"
+)
+
+// Flags holds configuration given on the command line. Empty values are unset
+// and leave the value from the environment or config file in place.
+type Flags struct {
+	ConfigFile         string
+	MiddlewareBaseURL  string
+	DefaultAccount     string
+	AppMode            string
+	AppHost            string
+	AppPort            string
+	EnabledToolsets    string
+	IncludedTools      string
+	ExcludedTools      string
+	ConfirmDestructive string
+	ReadOnly           *bool
+	DynamicToolsets    *bool
+	WatchConfig        *bool
+	RESTGateway        *bool
+}
+
+// Register defines the configuration flags on fs.
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.ConfigFile, "config", "", "Path to a YAML or JSON config file (overrides "+ConfigFileEnv+")")
+	fs.StringVar(&f.MiddlewareBaseURL, "base-url", "", "Middleware base URL (overrides MIDDLEWARE_BASE_URL)")
+	fs.StringVar(&f.DefaultAccount, "account", "", "Account used when a tool call doesn't name one (overrides DEFAULT_ACCOUNT)")
+	fs.StringVar(&f.AppMode, "mode", "", "Server mode: stdio, http, or sse (overrides APP_MODE)")
+	fs.StringVar(&f.AppHost, "host", "", "Host for http/sse modes (overrides APP_HOST)")
+	fs.StringVar(&f.AppPort, "port", "", "Port for http/sse modes (overrides APP_PORT)")
+	fs.StringVar(&f.EnabledToolsets, "toolsets", "", "Comma-separated toolsets to enable, or all (overrides ENABLED_TOOLSETS)")
+	fs.StringVar(&f.IncludedTools, "included-tools", "", "Comma-separated tools or patterns to allow (overrides INCLUDED_TOOLS)")
+	fs.StringVar(&f.ExcludedTools, "excluded-tools", "", "Comma-separated tools to exclude (overrides EXCLUDED_TOOLS)")
+	fs.StringVar(&f.ConfirmDestructive, "confirm-destructive", "", "Confirmation for destructive tools: auto, require, or off (overrides CONFIRM_DESTRUCTIVE)")
+	fs.BoolFunc("read-only", "Don't register mutating tools and refuse write requests (overrides READ_ONLY)", boolFlag(&f.ReadOnly))
+	fs.BoolFunc("dynamic-toolsets", "Start with only list_toolsets and enable_toolset (overrides DYNAMIC_TOOLSETS)", boolFlag(&f.DynamicToolsets))
+	fs.BoolFunc("watch-config", "Reload the config file when it changes (overrides WATCH_CONFIG)", boolFlag(&f.WatchConfig))
+	fs.BoolFunc("rest-gateway", "Serve the tools as a REST API in http mode (overrides REST_GATEWAY)", boolFlag(&f.RESTGateway))
+}
+
+// boolFlag returns a flag.BoolFunc callback that records the flag's value in *dst,
+// so that an unset flag can be told apart from false.
+func boolFlag(dst **bool) func(string) error {
+	return func(value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*dst = &parsed
+		return nil
+	}
+}
+
+// apply copies the values set on the command line onto cfg.
+func (f *Flags) apply(cfg *Config) {
+	setIfNotEmpty(&cfg.MiddlewareBaseURL, f.MiddlewareBaseURL)
+	setIfNotEmpty(&cfg.DefaultAccount, f.DefaultAccount)
+	setIfNotEmpty(&cfg.AppMode, f.AppMode)
+	setIfNotEmpty(&cfg.AppHost, f.AppHost)
+	setIfNotEmpty(&cfg.AppPort, f.AppPort)
+	setIfNotEmpty(&cfg.ConfirmDestructive, f.ConfirmDestructive)
+	if f.EnabledToolsets != "" {
+		cfg.EnabledToolsets = parseToolList(f.EnabledToolsets)
+	}
+	if f.IncludedTools != "" {
+		cfg.IncludedTools = parseToolList(f.IncludedTools)
+	}
+	if f.ExcludedTools != "" {
+		cfg.ExcludedTools = parseToolList(f.ExcludedTools)
+	}
+	if f.ReadOnly != nil {
+		cfg.ReadOnly = *f.ReadOnly
+	}
+	if f.DynamicToolsets != nil {
+		cfg.DynamicToolsets = *f.DynamicToolsets
+	}
+	if f.WatchConfig != nil {
+		cfg.WatchConfig = *f.WatchConfig
+	}
+	if f.RESTGateway != nil {
+		cfg.RESTGateway = *f.RESTGateway
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// WatchFile polls path every interval and calls onChange when its size or
+// modification time changes. It returns when ctx is done. A file that is
+// briefly missing, as while an editor replaces it, is not reported until it
+// reappears.
+func WatchFile(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	last, _ := os.Stat(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if last == nil || info.Size() != last.Size() || !info.ModTime().Equal(last.ModTime()) {
+				last = info
+				onChange()
+			}
+		}
+	}
+}
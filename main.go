@@ -2,17 +2,29 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
+	"time"
 
+	"mcp-middleware/cli"
 	"mcp-middleware/config"
 	"mcp-middleware/server"
 )
 
 func main() {
-	cfg, err := config.Load()
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+	}
+
+	var flags config.Flags
+	flags.Register(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := config.LoadWithFlags(flags)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
@@ -30,10 +42,47 @@ func main() {
 		cancel()
 	}()
 
+	// SIGHUP, or a change to the watched config file, reloads the configuration
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	go func() {
+		for range reloadChan {
+			reload(srv, flags)
+		}
+	}()
+	if cfg.WatchConfig {
+		if cfg.ConfigFile == "" {
+			log.Printf("WATCH_CONFIG is set but no config file is configured")
+		} else {
+			go config.WatchFile(ctx, cfg.ConfigFile, configWatchInterval, func() {
+				log.Printf("Config file %s changed", cfg.ConfigFile)
+				reload(srv, flags)
+			})
+		}
+	}
+
 	log.Printf("Middleware MCP Server v1.0.0")
 	log.Printf("Connected to: %s", cfg.MiddlewareBaseURL)
+	if cfg.ConfigFile != "" {
+		log.Printf("Config file: %s", cfg.ConfigFile)
+	}
+	if cfg.ReadOnly {
+		log.Printf("Read-only mode: mutating tools are disabled")
+	}
+	if cfg.WatchConfig && cfg.ConfigFile != "" {
+		log.Printf("Watching config file for changes")
+	}
+	if cfg.DynamicToolsets {
+		log.Printf("Dynamic toolsets: tools are registered when the client enables their toolset")
+	}
+	if len(cfg.EnabledToolsets) > 0 {
+		log.Printf("Enabled toolsets: %v", setToList(cfg.EnabledToolsets))
+	}
+	if len(cfg.IncludedTools) > 0 {
+		log.Printf("Included tools: %v", setToList(cfg.IncludedTools))
+	}
 	if len(cfg.ExcludedTools) > 0 {
-		log.Printf("Excluded tools: %v", getExcludedToolsList(cfg))
+		log.Printf("Excluded tools: %v", setToList(cfg.ExcludedTools))
 	}
 
 	switch cfg.AppMode {
@@ -55,10 +104,26 @@ func main() {
 	}
 }
 
-func getExcludedToolsList(cfg *config.Config) []string {
-	tools := make([]string, 0, len(cfg.ExcludedTools))
-	for tool := range cfg.ExcludedTools {
-		tools = append(tools, tool)
+// configWatchInterval is how often a watched config file is checked for changes.
+const configWatchInterval = 2 * time.Second
+
+// reload loads the configuration again and applies it, keeping the current
+// configuration if the new one is invalid.
+func reload(srv *server.Server, flags config.Flags) {
+	cfg, err := config.LoadWithFlags(flags)
+	if err == nil {
+		err = srv.Reload(cfg)
+	}
+	if err != nil {
+		log.Printf("Configuration reload rejected, keeping the current configuration: %v", err)
+	}
+}
+
+func setToList(set map[string]bool) []string {
+	list := make([]string, 0, len(set))
+	for item := range set {
+		list = append(list, item)
 	}
-	return tools
+	sort.Strings(list)
+	return list
 }
@@ -6,16 +6,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"sync"
 	"time"
 )
 
 type Client struct {
 	baseURL    string
-	apiKey     string
-	authHeader string
 	httpClient *http.Client
+	logFunc    LogFunc
+	readOnly   bool
+
+	credentialsMu sync.RWMutex
+	apiKey        string
+	authHeader    string
+	credentials   CredentialProvider
 }
 
 func NewClient(baseURL, apiKey string) *Client {
@@ -39,60 +44,62 @@ func NewClientWithAuth(baseURL, apiKey, authorization string) *Client {
 }
 
 func (c *Client) doRequest(ctx context.Context, method, path string, body any, result any) error {
+	if c.readOnly && !isReadRequest(method, path) {
+		c.Log(ctx, LogLevelWarning, "refused request in read-only mode", map[string]any{"method": method, "path": path})
+		return fmt.Errorf("%w: %s %s", ErrReadOnly, method, path)
+	}
+
 	url := c.baseURL + "/api/v1" + path
-	log.Printf("Request: Method=%s Path=%s URL=%s", method, path, url)
+	requestFields := map[string]any{"method": method, "path": path, "url": url}
 
-	var reqBody io.Reader
+	var jsonData []byte
 	if body != nil {
-		jsonData, err := json.Marshal(body)
+		var err error
+		jsonData, err = json.Marshal(body)
 		if err != nil {
 			return fmt.Errorf("failed to marshal request body: %w", err)
 		}
-		log.Printf("Request Body: %s\n", string(jsonData))
-		reqBody = bytes.NewBuffer(jsonData)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		requestFields["body"] = truncateString(string(jsonData), 2000)
 	}
+	c.Log(ctx, LogLevelDebug, "upstream request", requestFields)
 
-	if c.authHeader != "" {
-		req.Header.Set("Authorization", c.authHeader)
-	} else if c.apiKey != "" {
-		req.Header.Set("ApiKey", c.apiKey)
+	start := time.Now()
+	resp, respBody, err := c.send(ctx, method, url, jsonData)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && c.refreshCredentials(ctx) {
+		c.Log(ctx, LogLevelInfo, "retrying request with refreshed credentials", map[string]any{"method": method, "path": path})
+		resp, respBody, err = c.send(ctx, method, url, jsonData)
 	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		c.Log(ctx, LogLevelError, "upstream request failed", map[string]any{"method": method, "path": path, "error": err.Error()})
+		return err
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
+	responseFields := map[string]any{
+		"method":      method,
+		"path":        path,
+		"status":      resp.StatusCode,
+		"duration_ms": time.Since(start).Milliseconds(),
 	}
-	// log.Printf("Response Body: %s\n", string(respBody))
-
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		c.Log(ctx, LogLevelWarning, "upstream request returned an error status", responseFields)
 		var errResp ErrorResponse
 		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error != "" {
-			return fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error)
+			return &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("API error (%d): %s", resp.StatusCode, errResp.Error)}
 		}
 		// Check if response is HTML (common for error pages)
 		bodyStr := string(respBody)
 		if len(bodyStr) > 0 && bodyStr[0] == '<' {
-			return fmt.Errorf("API error (%d): received HTML response instead of JSON. This usually indicates the endpoint doesn't exist or there's an authentication issue. Response preview: %s", resp.StatusCode, truncateString(bodyStr, 200))
+			return &APIError{StatusCode: resp.StatusCode, HTML: true, Message: fmt.Sprintf("API error (%d): received HTML response instead of JSON. This usually indicates the endpoint doesn't exist or there's an authentication issue. Response preview: %s", resp.StatusCode, truncateString(bodyStr, 200))}
 		}
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, truncateString(bodyStr, 500))
+		return &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("API error (%d): %s", resp.StatusCode, truncateString(bodyStr, 500))}
 	}
+	c.Log(ctx, LogLevelDebug, "upstream response", responseFields)
 
 	if result != nil && len(respBody) > 0 {
 		// Check if response is HTML before trying to unmarshal
 		if len(respBody) > 0 && respBody[0] == '<' {
-			return fmt.Errorf("received HTML response instead of JSON. This usually indicates the endpoint doesn't exist or there's an authentication issue. Response preview: %s", truncateString(string(respBody), 200))
+			c.Log(ctx, LogLevelWarning, "upstream returned HTML instead of JSON", map[string]any{"method": method, "path": path})
+			return &APIError{StatusCode: resp.StatusCode, HTML: true, Message: fmt.Sprintf("received HTML response instead of JSON. This usually indicates the endpoint doesn't exist or there's an authentication issue. Response preview: %s", truncateString(string(respBody), 200))}
 		}
 		if err := json.Unmarshal(respBody, result); err != nil {
 			return fmt.Errorf("failed to unmarshal response: %w", err)
@@ -102,6 +109,51 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body any, r
 	return nil
 }
 
+// send makes one HTTP request with the current credentials and reads the response.
+func (c *Client) send(ctx context.Context, method, url string, jsonData []byte) (*http.Response, []byte, error) {
+	var reqBody io.Reader
+	if jsonData != nil {
+		reqBody = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	apiKey, authHeader := c.currentCredentials()
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	} else if apiKey != "" {
+		req.Header.Set("ApiKey", apiKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return resp, respBody, nil
+}
+
+// APIError is returned when the upstream answers with an error status, or with
+// an HTML page where JSON was expected.
+type APIError struct {
+	StatusCode int
+	HTML       bool
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Success bool   `json:"success"`
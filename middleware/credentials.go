@@ -0,0 +1,47 @@
+package middleware
+
+import "context"
+
+// CredentialProvider returns the current API key and Authorization header. It is
+// called again when the upstream rejects a request with 401, so rotated
+// credentials are picked up without a restart.
+type CredentialProvider func(ctx context.Context) (apiKey, authorization string, err error)
+
+// SetCredentialProvider sets the function used to reload credentials after a 401.
+func (c *Client) SetCredentialProvider(provider CredentialProvider) {
+	c.credentialsMu.Lock()
+	defer c.credentialsMu.Unlock()
+	c.credentials = provider
+}
+
+func (c *Client) currentCredentials() (apiKey, authorization string) {
+	c.credentialsMu.RLock()
+	defer c.credentialsMu.RUnlock()
+	return c.apiKey, c.authHeader
+}
+
+// refreshCredentials reloads the credentials from the provider and reports
+// whether they changed, in which case the failed request is worth retrying.
+func (c *Client) refreshCredentials(ctx context.Context) bool {
+	c.credentialsMu.RLock()
+	provider := c.credentials
+	c.credentialsMu.RUnlock()
+	if provider == nil {
+		return false
+	}
+
+	apiKey, authorization, err := provider(ctx)
+	if err != nil {
+		c.Log(ctx, LogLevelError, "failed to refresh credentials", map[string]any{"error": err.Error()})
+		return false
+	}
+
+	c.credentialsMu.Lock()
+	defer c.credentialsMu.Unlock()
+	if apiKey == c.apiKey && authorization == c.authHeader {
+		return false
+	}
+	c.apiKey = apiKey
+	c.authHeader = authorization
+	return true
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// LogLevel is the severity of a log event. The values match the MCP logging levels.
+type LogLevel string
+
+const (
+	LogLevelDebug   LogLevel = "debug"
+	LogLevelInfo    LogLevel = "info"
+	LogLevelWarning LogLevel = "warning"
+	LogLevelError   LogLevel = "error"
+)
+
+// LogFunc receives the client's structured log events. ctx is the context of
+// the call that produced the event, so it can be used to route the event back
+// to the caller. Secrets have already been redacted.
+type LogFunc func(ctx context.Context, level LogLevel, message string, fields map[string]any)
+
+const redacted = "[REDACTED]"
+
+// sensitiveFields are field names whose values are never logged.
+var sensitiveFields = []string{"apikey", "api_key", "authorization", "token", "password", "secret"}
+
+// SetLogFunc sets a function that receives every log event in addition to stderr.
+func (c *Client) SetLogFunc(fn LogFunc) {
+	c.logFunc = fn
+}
+
+// Log records a structured log event. The event is written to stderr and passed
+// to the LogFunc, if one is set, with the client's credentials redacted.
+func (c *Client) Log(ctx context.Context, level LogLevel, message string, fields map[string]any) {
+	message = c.redact(message)
+	redactedFields := make(map[string]any, len(fields))
+	for k, v := range fields {
+		redactedFields[k] = c.redactField(k, v)
+	}
+
+	log.Printf("[%s] %s%s", level, message, formatFields(redactedFields))
+
+	if c.logFunc != nil {
+		c.logFunc(ctx, level, message, redactedFields)
+	}
+}
+
+func (c *Client) redactField(key string, value any) any {
+	lower := strings.ToLower(key)
+	for _, name := range sensitiveFields {
+		if strings.Contains(lower, name) {
+			return redacted
+		}
+	}
+	if s, ok := value.(string); ok {
+		return c.redact(s)
+	}
+	return value
+}
+
+// redact replaces any occurrence of the client's credentials in s, including
+// the bare token of a "Bearer <token>" authorization header.
+func (c *Client) redact(s string) string {
+	apiKey, authHeader := c.currentCredentials()
+	secrets := []string{apiKey, authHeader}
+	if fields := strings.Fields(authHeader); len(fields) == 2 {
+		secrets = append(secrets, fields[1])
+	}
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+	return s
+}
+
+func formatFields(fields map[string]any) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrReadOnly is returned for requests that could modify data while the client
+// is in read-only mode.
+var ErrReadOnly = errors.New("request refused in read-only mode")
+
+// readOnlyPOSTPaths are POST endpoints that only read data.
+var readOnlyPOSTPaths = map[string]bool{
+	"/query":                     true,
+	"/builder/widget/data":       true,
+	"/builder/widget/multi-data": true,
+	"/builder/metrics-v2":        true,
+}
+
+// mutatingGETPrefixes are GET endpoints that modify data.
+var mutatingGETPrefixes = []string{
+	"/builder/report/favourite/",
+}
+
+// SetReadOnly makes the client refuse every request that could modify data.
+// Only GET requests and the POST endpoints in readOnlyPOSTPaths are sent.
+func (c *Client) SetReadOnly(readOnly bool) {
+	c.readOnly = readOnly
+}
+
+func isReadRequest(method, path string) bool {
+	path, _, _ = strings.Cut(path, "?")
+	switch method {
+	case "GET":
+		for _, prefix := range mutatingGETPrefixes {
+			if strings.HasPrefix(path, prefix) {
+				return false
+			}
+		}
+		return true
+	case "POST":
+		return readOnlyPOSTPaths[path]
+	default:
+		return false
+	}
+}
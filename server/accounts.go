@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"mcp-middleware/config"
+	"mcp-middleware/middleware"
+	"mcp-middleware/server/tools"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// accountArgument is the optional tool argument that selects the account a call runs against.
+const accountArgument = "account"
+
+// credentialTimeout bounds reading credential files and running credential commands.
+const credentialTimeout = 30 * time.Second
+
+// accountClients creates one Middleware API client per account, on first use.
+type accountClients struct {
+	mu      sync.Mutex
+	clients map[string]*middleware.Client
+}
+
+// ClientFor returns the client for the named account, or for the default account
+// if name is empty.
+func (s *Server) ClientFor(name string) (*middleware.Client, error) {
+	cfg := s.Config()
+	account, err := cfg.GetAccount(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.clients.mu.Lock()
+	client, ok := s.clients.clients[account.Name]
+	s.clients.mu.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	// Credentials may come from a slow command, so resolve them without
+	// holding the lock and keep the first client if another call raced us.
+	// A client built from a configuration Reload has since replaced is not cached.
+	ctx, cancel := context.WithTimeout(context.Background(), credentialTimeout)
+	defer cancel()
+	apiKey, authorization, err := account.ResolveCredentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credentials for account %s: %w", account.Name, err)
+	}
+
+	client = middleware.NewClientWithAuth(account.MiddlewareBaseURL, apiKey, authorization)
+	if account.HasRefreshableCredentials() {
+		client.SetCredentialProvider(refreshCredentials(*account))
+	}
+	client.SetReadOnly(cfg.ReadOnly)
+	client.SetLogFunc(forwardLogs(s.mcpServer))
+
+	s.clients.mu.Lock()
+	defer s.clients.mu.Unlock()
+	if existing, ok := s.clients.clients[account.Name]; ok {
+		return existing, nil
+	}
+	if s.Config() == cfg {
+		s.clients.clients[account.Name] = client
+	}
+	return client, nil
+}
+
+// refreshCredentials re-reads an account's credential files or command.
+func refreshCredentials(account config.Account) middleware.CredentialProvider {
+	return func(ctx context.Context) (string, string, error) {
+		ctx, cancel := context.WithTimeout(ctx, credentialTimeout)
+		defer cancel()
+		return account.ResolveCredentials(ctx)
+	}
+}
+
+// callServer is the tools.ServerInterface a single tool call runs against, bound
+// to the client of the account the call selected. Meta-tools bound with
+// bindMetaHandler get one without a client and never call Client.
+type callServer struct {
+	*Server
+	client *middleware.Client
+}
+
+func (s *callServer) Client() *middleware.Client {
+	return s.client
+}
+
+// forCall returns the server view for a tool call, using its account argument.
+func (s *Server) forCall(req mcp.CallToolRequest) (tools.ServerInterface, error) {
+	client, err := s.ClientFor(req.GetString(accountArgument, ""))
+	if err != nil {
+		return nil, err
+	}
+	return &callServer{Server: s, client: client}, nil
+}
+
+// withAccountArgument adds the optional account argument to a tool's input schema.
+func (s *Server) withAccountArgument(tool mcp.Tool) mcp.Tool {
+	var schema map[string]any
+	if err := json.Unmarshal(tool.RawInputSchema, &schema); err != nil {
+		log.Printf("Failed to add account argument to %s: %v", tool.Name, err)
+		return tool
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	if properties == nil {
+		properties = make(map[string]any)
+		schema["properties"] = properties
+	}
+
+	cfg := s.Config()
+	defaultAccount, _ := cfg.GetAccount("")
+	property := map[string]any{
+		"type":        "string",
+		"description": "Name of the Middleware account to use (see list_accounts). Defaults to the configured default account.",
+	}
+	if names := cfg.AccountNames(); len(names) > 0 {
+		property["enum"] = names
+	}
+	if defaultAccount != nil {
+		property["default"] = defaultAccount.Name
+	}
+	properties[accountArgument] = property
+
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		log.Printf("Failed to add account argument to %s: %v", tool.Name, err)
+		return tool
+	}
+	tool.RawInputSchema = raw
+	return tool
+}
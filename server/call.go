@@ -0,0 +1,34 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Tools returns the registered tools sorted by name.
+func (s *Server) Tools() []mcp.Tool {
+	registered := s.mcpServer.ListTools()
+	list := make([]mcp.Tool, 0, len(registered))
+	for _, tool := range registered {
+		list = append(list, tool.Tool)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// CallTool runs a registered tool with args outside of an MCP session, through
+// the same handler an MCP client's tools/call reaches.
+func (s *Server) CallTool(ctx context.Context, name string, args map[string]any) (*mcp.CallToolResult, error) {
+	tool := s.mcpServer.GetTool(name)
+	if tool == nil {
+		return nil, fmt.Errorf("unknown tool: %s", name)
+	}
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+	return tool.Handler(ctx, req)
+}
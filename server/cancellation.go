@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// requestIDMetaKey is the _meta field used to carry the JSON-RPC request ID from
+// the before-call hook to the tool handler middleware, which doesn't receive it.
+const requestIDMetaKey = "io.middleware/requestId"
+
+// inFlightCalls tracks running tool calls so that notifications/cancelled can
+// abort them, including any upstream Middleware API requests they have open.
+type inFlightCalls struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func newInFlightCalls() *inFlightCalls {
+	return &inFlightCalls{cancels: make(map[string]context.CancelFunc)}
+}
+
+// hooks returns the server hooks that record each tool call's request ID.
+func (c *inFlightCalls) hooks() *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddBeforeCallTool(func(ctx context.Context, id any, req *mcp.CallToolRequest) {
+		if req.Params.Meta == nil {
+			req.Params.Meta = &mcp.Meta{}
+		}
+		if req.Params.Meta.AdditionalFields == nil {
+			req.Params.Meta.AdditionalFields = make(map[string]any)
+		}
+		req.Params.Meta.AdditionalFields[requestIDMetaKey] = mcp.NewRequestId(id).String()
+	})
+	return hooks
+}
+
+// middleware gives every tool call a cancellable context for as long as it runs.
+func (c *inFlightCalls) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if req.Params.Meta == nil {
+			return next(ctx, req)
+		}
+		requestID, ok := req.Params.Meta.AdditionalFields[requestIDMetaKey].(string)
+		if !ok {
+			return next(ctx, req)
+		}
+		delete(req.Params.Meta.AdditionalFields, requestIDMetaKey)
+
+		ctx, cancel := context.WithCancel(ctx)
+		key := callKey(ctx, requestID)
+
+		c.mu.Lock()
+		c.cancels[key] = cancel
+		c.mu.Unlock()
+
+		defer func() {
+			c.mu.Lock()
+			delete(c.cancels, key)
+			c.mu.Unlock()
+			cancel()
+		}()
+
+		return next(ctx, req)
+	}
+}
+
+// handleCancelled handles notifications/cancelled from the client.
+func (c *inFlightCalls) handleCancelled(ctx context.Context, notification mcp.JSONRPCNotification) {
+	data, err := json.Marshal(notification.Params.AdditionalFields)
+	if err != nil {
+		return
+	}
+	var params mcp.CancelledNotificationParams
+	if err := json.Unmarshal(data, &params); err != nil {
+		return
+	}
+
+	key := callKey(ctx, params.RequestId.String())
+
+	c.mu.Lock()
+	cancel, ok := c.cancels[key]
+	c.mu.Unlock()
+
+	if ok {
+		log.Printf("Cancelling tool call %s: %s", params.RequestId.String(), params.Reason)
+		cancel()
+	}
+}
+
+// callKey scopes request IDs to the client session, since IDs are only unique per session.
+func callKey(ctx context.Context, requestID string) string {
+	sessionID := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionID = session.SessionID()
+	}
+	return sessionID + "/" + requestID
+}
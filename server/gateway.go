@@ -0,0 +1,166 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"mcp-middleware/middleware"
+	"mcp-middleware/server/tools"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxGatewayBody limits the size of a REST gateway request body.
+const maxGatewayBody = 1 << 20
+
+// gatewayError is the body of REST gateway error responses.
+type gatewayError struct {
+	Error   string   `json:"error"`
+	Details []string `json:"details,omitempty"`
+}
+
+// Gateway returns the REST gateway, which serves every registered tool at
+// POST /v1/tools/{name}, the tool list at GET /v1/tools and an OpenAPI 3
+// document at GET /v1/openapi.json. It answers 404 unless REST_GATEWAY is set.
+func (s *Server) Gateway() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/openapi.json", s.handleOpenAPI)
+	mux.HandleFunc("GET /v1/tools", s.handleListToolsREST)
+	mux.HandleFunc("POST /v1/tools/{name}", s.handleCallToolREST)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Config().RESTGateway {
+			http.NotFound(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// requireToken rejects requests without the configured HTTP_AUTH_TOKEN as a
+// bearer token. Requests pass unchecked when no token is configured.
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := s.Config().HTTPAuthToken
+		if token != "" {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="middleware-mcp-server"`)
+				writeJSON(w, http.StatusUnauthorized, gatewayError{Error: "missing or invalid bearer token"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleListToolsREST(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, mcp.ListToolsResult{Tools: s.Tools()})
+}
+
+func (s *Server) handleCallToolREST(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	tool := s.mcpServer.GetTool(name)
+	if tool == nil {
+		writeJSON(w, http.StatusNotFound, gatewayError{Error: "unknown tool: " + name})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxGatewayBody+1))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, gatewayError{Error: fmt.Sprintf("failed to read request body: %v", err)})
+		return
+	}
+	if len(body) > maxGatewayBody {
+		writeJSON(w, http.StatusRequestEntityTooLarge, gatewayError{Error: "request body is too large"})
+		return
+	}
+
+	var args any = map[string]any{}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &args); err != nil {
+			writeJSON(w, http.StatusBadRequest, gatewayError{Error: fmt.Sprintf("request body is not valid JSON: %v", err)})
+			return
+		}
+	}
+	arguments, ok := args.(map[string]any)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, gatewayError{Error: "request body must be a JSON object"})
+		return
+	}
+
+	inputSchema, _, err := toolSchemas(tool.Tool)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, gatewayError{Error: err.Error()})
+		return
+	}
+	if problems := validateSchema(inputSchema, arguments); len(problems) > 0 {
+		writeJSON(w, http.StatusBadRequest, gatewayError{Error: "invalid input for " + name, Details: problems})
+		return
+	}
+
+	result, err := s.CallTool(r.Context(), name, arguments)
+	if err != nil {
+		writeJSON(w, gatewayStatus(err), gatewayError{Error: err.Error()})
+		return
+	}
+	if result.IsError {
+		// Tools here return Middleware API failures as errors, so an error
+		// result is the tool rejecting its arguments.
+		writeJSON(w, http.StatusBadRequest, gatewayError{Error: resultText(result)})
+		return
+	}
+
+	if result.StructuredContent != nil {
+		writeJSON(w, http.StatusOK, result.StructuredContent)
+		return
+	}
+	text := resultText(result)
+	if json.Valid([]byte(text)) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(text))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"text": text})
+}
+
+// gatewayStatus maps a tool error to an HTTP status.
+func gatewayStatus(err error) int {
+	var apiErr *middleware.APIError
+	var inputErr *tools.InputError
+	switch {
+	case errors.Is(err, middleware.ErrReadOnly):
+		return http.StatusForbidden
+	case errors.As(err, &apiErr):
+		return http.StatusBadGateway
+	case errors.As(err, &inputErr):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func resultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"context"
+
+	"mcp-middleware/server/tools"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// withSnapshot wraps the handler of a tool in tools.SnapshotTools so that the
+// dashboards the call changes are saved to the local history first. Dry runs
+// change nothing and aren't snapshotted. Tools that ask the user to confirm
+// snapshot in their handlers instead, once confirmed.
+func withSnapshot(handler toolHandler) toolHandler {
+	return func(s tools.ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !req.GetBool("dry_run", false) {
+			if err := tools.SnapshotDashboards(ctx, s, req); err != nil {
+				return nil, err
+			}
+		}
+		return handler(s, ctx, req)
+	}
+}
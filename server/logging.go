@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+
+	"mcp-middleware/middleware"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// loggerName identifies this server's events in notifications/message.
+const loggerName = "middleware-mcp-server"
+
+// forwardLogs returns a LogFunc that sends log events as notifications/message
+// to the client session that made the call. mcp-go drops events below the level
+// the session set with logging/setLevel.
+func forwardLogs(mcpServer *server.MCPServer) middleware.LogFunc {
+	return func(ctx context.Context, level middleware.LogLevel, message string, fields map[string]any) {
+		session := server.ClientSessionFromContext(ctx)
+		if _, ok := session.(server.SessionWithLogging); !ok {
+			return
+		}
+
+		data := make(map[string]any, len(fields)+1)
+		for k, v := range fields {
+			data[k] = v
+		}
+		data["message"] = message
+
+		// Errors only mean the session can't receive notifications right now;
+		// the event has already been written to stderr.
+		_ = mcpServer.SendLogMessageToClient(ctx, mcp.NewLoggingMessageNotification(mcp.LoggingLevel(level), loggerName, data))
+	}
+}
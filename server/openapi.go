@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// openAPIVersion is the OpenAPI version of the generated document. 3.1 uses
+// JSON Schema as is, so the tool schemas need no conversion.
+const openAPIVersion = "3.1.0"
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.OpenAPI())
+}
+
+// OpenAPI builds an OpenAPI document describing the REST gateway, with one
+// operation per registered tool generated from its input and output schemas.
+func (s *Server) OpenAPI() map[string]any {
+	errorResponse := func(description string) map[string]any {
+		return map[string]any{
+			"description": description,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}},
+			},
+		}
+	}
+
+	paths := map[string]any{}
+	for _, tool := range s.Tools() {
+		input, output, err := toolSchemas(tool)
+		if err != nil {
+			continue
+		}
+		if output == nil {
+			output = map[string]any{"type": "object"}
+		}
+
+		summary := tool.Annotations.Title
+		if summary == "" {
+			summary = DescriptionSummary(tool.Description)
+		}
+		operation := map[string]any{
+			"operationId": tool.Name,
+			"summary":     summary,
+			"description": tool.Description,
+			"requestBody": map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": input},
+				},
+			},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "Tool result",
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": output},
+					},
+				},
+				"400": errorResponse("Invalid input"),
+				"401": errorResponse("Missing or invalid bearer token"),
+				"403": errorResponse("Refused in read-only mode"),
+				"502": errorResponse("The Middleware API returned an error"),
+			},
+		}
+		if toolset := toolsetOf(tool.Name); toolset != "" {
+			operation["tags"] = []string{toolset}
+		}
+		paths["/v1/tools/"+tool.Name] = map[string]any{"post": operation}
+	}
+
+	doc := map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":       "Middleware MCP Server REST gateway",
+			"version":     "1.0.0",
+			"description": "The MCP tools of the Middleware MCP server as REST operations. Each operation takes the tool's arguments as a JSON object and returns its result.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": map[string]any{
+				"Error": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"error":   map[string]any{"type": "string"},
+						"details": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					},
+					"required": []string{"error"},
+				},
+			},
+		},
+	}
+	if s.Config().HTTPAuthToken != "" {
+		doc["components"].(map[string]any)["securitySchemes"] = map[string]any{
+			"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+		}
+		doc["security"] = []map[string]any{{"bearerAuth": []string{}}}
+	}
+	return doc
+}
+
+// toolsetOf returns the toolset of a tool in the registry, if any.
+func toolsetOf(name string) string {
+	for _, def := range toolRegistry {
+		if def.tool().Name == name {
+			return def.toolset
+		}
+	}
+	return ""
+}
+
+// DescriptionSummary returns the first line of a tool description, for lists
+// and summaries that have no room for the rest.
+func DescriptionSummary(description string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(description), "\n")
+	return line
+}
@@ -2,131 +2,167 @@ package server
 
 import (
 	"context"
+	"slices"
 
+	"mcp-middleware/config"
 	"mcp-middleware/server/tools"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 )
 
-// See: https://modelcontextprotocol.io/docs/learn/server-concepts#tools
-func (s *Server) registerTools() {
+// toolHandler is the signature shared by all handlers in the tools package.
+type toolHandler func(s tools.ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error)
+
+// toolDefinition is an entry in the tool registry.
+type toolDefinition struct {
+	toolset string
+	tool    func() mcp.Tool
+	handler toolHandler
+}
+
+// toolRegistry lists every tool the server can register.
+var toolRegistry = []toolDefinition{
 	// Dashboard tools
-	if !s.config.IsToolExcluded("list_dashboards") {
-		s.mcpServer.AddTool(tools.NewListDashboardsTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleListDashboards(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("get_dashboard") {
-		s.mcpServer.AddTool(tools.NewGetDashboardTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleGetDashboard(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("create_dashboard") {
-		s.mcpServer.AddTool(tools.NewCreateDashboardTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleCreateDashboard(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("update_dashboard") {
-		s.mcpServer.AddTool(tools.NewUpdateDashboardTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleUpdateDashboard(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("delete_dashboard") {
-		s.mcpServer.AddTool(tools.NewDeleteDashboardTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleDeleteDashboard(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("clone_dashboard") {
-		s.mcpServer.AddTool(tools.NewCloneDashboardTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleCloneDashboard(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("set_dashboard_favorite") {
-		s.mcpServer.AddTool(tools.NewSetDashboardFavoriteTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleSetDashboardFavorite(s, ctx, req)
-		})
-	}
+	{config.ToolsetDashboards, tools.NewListDashboardsTool, tools.HandleListDashboards},
+	{config.ToolsetDashboards, tools.NewGetDashboardTool, tools.HandleGetDashboard},
+	{config.ToolsetDashboards, tools.NewCreateDashboardTool, tools.HandleCreateDashboard},
+	{config.ToolsetDashboards, tools.NewUpdateDashboardTool, tools.HandleUpdateDashboard},
+	{config.ToolsetDashboards, tools.NewDeleteDashboardTool, tools.HandleDeleteDashboard},
+	{config.ToolsetDashboards, tools.NewCloneDashboardTool, tools.HandleCloneDashboard},
+	{config.ToolsetDashboards, tools.NewSetDashboardFavoriteTool, tools.HandleSetDashboardFavorite},
+	{config.ToolsetDashboards, tools.NewExportDashboardTool, tools.HandleExportDashboard},
+	{config.ToolsetDashboards, tools.NewImportDashboardTool, tools.HandleImportDashboard},
+	{config.ToolsetDashboards, tools.NewDiffDashboardsTool, tools.HandleDiffDashboards},
+	{config.ToolsetDashboards, tools.NewListDashboardTemplatesTool, tools.HandleListDashboardTemplates},
+	{config.ToolsetDashboards, tools.NewCreateDashboardFromTemplateTool, tools.HandleCreateDashboardFromTemplate},
+	{config.ToolsetDashboards, tools.NewListDashboardVersionsTool, tools.HandleListDashboardVersions},
+	{config.ToolsetDashboards, tools.NewRestoreDashboardVersionTool, tools.HandleRestoreDashboardVersion},
+	{config.ToolsetDashboards, tools.NewLintDashboardTool, tools.HandleLintDashboard},
+	{config.ToolsetDashboards, tools.NewBulkDashboardsTool, tools.HandleBulkDashboards},
 
 	// Widget tools
-	if !s.config.IsToolExcluded("list_widgets") {
-		s.mcpServer.AddTool(tools.NewListWidgetsTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleListWidgets(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("create_widget") {
-		s.mcpServer.AddTool(tools.NewCreateWidgetTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleCreateWidget(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("update_widget") {
-		s.mcpServer.AddTool(tools.NewUpdateWidgetTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleUpdateWidget(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("delete_widget") {
-		s.mcpServer.AddTool(tools.NewDeleteWidgetTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleDeleteWidget(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("get_widget_data") {
-		s.mcpServer.AddTool(tools.NewGetWidgetDataTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleGetWidgetData(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("get_multi_widget_data") {
-		s.mcpServer.AddTool(tools.NewGetMultiWidgetDataTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleGetMultiWidgetData(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("update_widget_layouts") {
-		s.mcpServer.AddTool(tools.NewUpdateWidgetLayoutsTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleUpdateWidgetLayouts(s, ctx, req)
-		})
-	}
+	{config.ToolsetWidgets, tools.NewListWidgetsTool, tools.HandleListWidgets},
+	{config.ToolsetWidgets, tools.NewCreateWidgetTool, tools.HandleCreateWidget},
+	{config.ToolsetWidgets, tools.NewUpdateWidgetTool, tools.HandleUpdateWidget},
+	{config.ToolsetWidgets, tools.NewDeleteWidgetTool, tools.HandleDeleteWidget},
+	{config.ToolsetWidgets, tools.NewGetWidgetDataTool, tools.HandleGetWidgetData},
+	{config.ToolsetWidgets, tools.NewGetMultiWidgetDataTool, tools.HandleGetMultiWidgetData},
+	{config.ToolsetWidgets, tools.NewUpdateWidgetLayoutsTool, tools.HandleUpdateWidgetLayouts},
 
 	// Metrics tools
-	if !s.config.IsToolExcluded("get_metrics") {
-		s.mcpServer.AddTool(tools.NewGetMetricsTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleGetMetrics(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("get_resources") {
-		s.mcpServer.AddTool(tools.NewGetResourcesTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleGetResources(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("query") {
-		s.mcpServer.AddTool(tools.NewQueryTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleQuery(s, ctx, req)
-		})
-	}
+	{config.ToolsetMetrics, tools.NewGetMetricsTool, tools.HandleGetMetrics},
+	{config.ToolsetMetrics, tools.NewGetResourcesTool, tools.HandleGetResources},
+	{config.ToolsetMetrics, tools.NewQueryTool, tools.HandleQuery},
 
 	// Alert tools
-	if !s.config.IsToolExcluded("list_alerts") {
-		s.mcpServer.AddTool(tools.NewListAlertsTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleListAlerts(s, ctx, req)
-		})
-	}
-	if !s.config.IsToolExcluded("create_alert") {
-		s.mcpServer.AddTool(tools.NewCreateAlertTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleCreateAlert(s, ctx, req)
-		})
+	{config.ToolsetAlerts, tools.NewListAlertsTool, tools.HandleListAlerts},
+	{config.ToolsetAlerts, tools.NewCreateAlertTool, tools.HandleCreateAlert},
+	{config.ToolsetAlerts, tools.NewGetAlertStatsTool, tools.HandleGetAlertStats},
+
+	// Error/Incident tools
+	{config.ToolsetErrors, tools.NewListErrorsTool, tools.HandleListErrors},
+	{config.ToolsetErrors, tools.NewGetErrorDetailsTool, tools.HandleGetErrorDetails},
+
+	// Account tools
+	{config.ToolsetAccounts, tools.NewListAccountsTool, tools.HandleListAccounts},
+}
+
+// See: https://modelcontextprotocol.io/docs/learn/server-concepts#tools
+func (s *Server) registerTools() {
+	s.toolsetsMu.Lock()
+	defer s.toolsetsMu.Unlock()
+	s.mcpServer.SetTools(s.activeTools()...)
+}
+
+// activeTools works out which toolsets are enabled under the current
+// configuration and returns their tools. In dynamic mode only the meta-tools
+// and the toolsets the client already enabled are active; otherwise every
+// toolset the configuration allows is. The caller holds toolsetsMu.
+func (s *Server) activeTools() []server.ServerTool {
+	cfg := s.Config()
+
+	var serverTools []server.ServerTool
+	if cfg.DynamicToolsets {
+		serverTools = append(serverTools,
+			server.ServerTool{Tool: tools.NewListToolsetsTool(), Handler: s.bindMetaHandler(tools.HandleListToolsets)},
+			server.ServerTool{Tool: tools.NewEnableToolsetTool(), Handler: s.bindMetaHandler(tools.HandleEnableToolset)},
+		)
 	}
-	if !s.config.IsToolExcluded("get_alert_stats") {
-		s.mcpServer.AddTool(tools.NewGetAlertStatsTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleGetAlertStats(s, ctx, req)
-		})
+
+	for _, toolset := range config.Toolsets {
+		enabled := cfg.IsToolsetEnabled(toolset) && (!cfg.DynamicToolsets || s.enabledToolsets[toolset])
+		if !enabled {
+			delete(s.enabledToolsets, toolset)
+			continue
+		}
+		s.enabledToolsets[toolset] = true
+		serverTools = append(serverTools, s.toolsetTools(toolset)...)
+	}
+	return serverTools
+}
+
+// toolsetTools returns the tools of toolset that the configuration allows.
+func (s *Server) toolsetTools(toolset string) []server.ServerTool {
+	cfg := s.Config()
+	var serverTools []server.ServerTool
+	for _, def := range toolRegistry {
+		if def.toolset != toolset {
+			continue
+		}
+		tool := def.tool()
+		if !cfg.IsToolEnabled(def.toolset, tool.Name) {
+			continue
+		}
+		if cfg.ReadOnly && !isReadOnlyTool(tool) {
+			continue
+		}
+		// Account tools describe the accounts rather than run against one
+		if def.toolset == config.ToolsetAccounts {
+			serverTools = append(serverTools, server.ServerTool{Tool: tool, Handler: s.bindMetaHandler(def.handler)})
+			continue
+		}
+		tool = s.withAccountArgument(tool)
+		handler := def.handler
+		if slices.Contains(tools.SnapshotTools, tool.Name) && !slices.Contains(tools.ConfirmedSnapshotTools, tool.Name) {
+			handler = withSnapshot(handler)
+		}
+		serverTools = append(serverTools, server.ServerTool{Tool: tool, Handler: s.bindHandler(handler)})
+	}
+	return serverTools
+}
+
+// bindHandler adapts a tools package handler to the mcp-go handler signature,
+// running it against the account selected by the call's account argument.
+func (s *Server) bindHandler(handler toolHandler) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		callSrv, err := s.forCall(req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(callSrv, ctx, req)
 	}
+}
 
-	// Error/Incident tools
-	if !s.config.IsToolExcluded("list_errors") {
-		s.mcpServer.AddTool(tools.NewListErrorsTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleListErrors(s, ctx, req)
-		})
+// bindMetaHandler adapts the handler of a tool that only describes the server,
+// such as list_accounts or list_toolsets. It runs without an account client, so
+// these tools keep working when an account's credentials can't be loaded.
+func (s *Server) bindMetaHandler(handler toolHandler) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handler(&callServer{Server: s}, ctx, req)
 	}
-	if !s.config.IsToolExcluded("get_error_details") {
-		s.mcpServer.AddTool(tools.NewGetErrorDetailsTool(), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return tools.HandleGetErrorDetails(s, ctx, req)
-		})
+}
+
+// isReadOnlyTool reports whether a tool is annotated as not modifying anything.
+func isReadOnlyTool(tool mcp.Tool) bool {
+	return tool.Annotations.ReadOnlyHint != nil && *tool.Annotations.ReadOnlyHint
+}
+
+func isToolset(name string) bool {
+	for _, toolset := range config.Toolsets {
+		if toolset == name {
+			return true
+		}
 	}
+	return false
 }
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+	"log"
+
+	"mcp-middleware/config"
+	"mcp-middleware/middleware"
+)
+
+// Reload validates cfg and makes it the active configuration. The registered
+// tools are rebuilt for the new configuration and connected clients are sent
+// notifications/tools/list_changed. If cfg is invalid it is rejected and the
+// current configuration stays active.
+//
+// The server mode, host and port can't change while the server is running;
+// new values for them take effect after a restart.
+func (s *Server) Reload(cfg *config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	s.toolsetsMu.Lock()
+	defer s.toolsetsMu.Unlock()
+
+	s.configMu.Lock()
+	old := s.config
+	s.config = cfg
+	s.configMu.Unlock()
+
+	if cfg.AppMode != old.AppMode || cfg.AppHost != old.AppHost || cfg.AppPort != old.AppPort {
+		log.Printf("APP_MODE, APP_HOST and APP_PORT changes take effect after a restart")
+	}
+
+	// Accounts and credentials may have changed, so clients are recreated on next use
+	s.clients.mu.Lock()
+	s.clients.clients = make(map[string]*middleware.Client)
+	s.clients.mu.Unlock()
+
+	serverTools := s.activeTools()
+	s.mcpServer.SetTools(serverTools...)
+	log.Printf("Configuration reloaded: %d tools registered", len(serverTools))
+	return nil
+}
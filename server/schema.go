@@ -0,0 +1,199 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolSchemas returns a tool's input and output JSON schemas, whichever of the
+// typed or raw forms the tool was defined with. output is nil if the tool has
+// no output schema.
+func toolSchemas(tool mcp.Tool) (input, output map[string]any, err error) {
+	data, err := json.Marshal(tool)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal tool %s: %w", tool.Name, err)
+	}
+	var schemas struct {
+		InputSchema  map[string]any `json:"inputSchema"`
+		OutputSchema map[string]any `json:"outputSchema"`
+	}
+	if err := json.Unmarshal(data, &schemas); err != nil {
+		return nil, nil, fmt.Errorf("failed to read schemas of tool %s: %w", tool.Name, err)
+	}
+	return schemas.InputSchema, schemas.OutputSchema, nil
+}
+
+// validateSchema checks value, as decoded by encoding/json, against schema and
+// returns one message per problem. It supports the keywords the tool schemas
+// use: type, properties, required, additionalProperties, items, enum and
+// minLength. Other keywords are ignored.
+func validateSchema(schema map[string]any, value any) []string {
+	var problems []string
+	validateValue(schema, value, "", &problems)
+	return problems
+}
+
+func validateValue(schema map[string]any, value any, path string, problems *[]string) {
+	report := func(format string, args ...any) {
+		where := path
+		if where == "" {
+			where = "input"
+		}
+		*problems = append(*problems, where+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if hasType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			report("must be of type %s, got %s", joinTypes(types), typeOf(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !inEnum(enum, value) {
+		report("must be one of %v", enum)
+	}
+
+	switch v := value.(type) {
+	case string:
+		if minLength, ok := schema["minLength"].(float64); ok && float64(utf8.RuneCountInString(v)) < minLength {
+			report("must be at least %d characters long", int(minLength))
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case map[string]any:
+		validateObject(schema, v, path, report, problems)
+	}
+}
+
+func validateObject(schema map[string]any, object map[string]any, path string, report func(string, ...any), problems *[]string) {
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, present := object[key]; !present {
+					report("missing required property %q", key)
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		propertyPath := key
+		if path != "" {
+			propertyPath = path + "." + key
+		}
+		if property, ok := properties[key].(map[string]any); ok {
+			validateValue(property, object[key], propertyPath, problems)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				report("unknown property %q", key)
+			}
+		case map[string]any:
+			validateValue(additional, object[key], propertyPath, problems)
+		}
+	}
+}
+
+func schemaTypes(t any) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func hasType(value any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func typeOf(value any) string {
+	switch v := value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func joinTypes(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return fmt.Sprintf("%v", types)
+}
+
+func inEnum(enum []any, value any) bool {
+	for _, allowed := range enum {
+		if allowed == value {
+			return true
+		}
+	}
+	return false
+}
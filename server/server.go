@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"mcp-middleware/config"
@@ -16,19 +17,31 @@ import (
 
 type Server struct {
 	mcpServer *server.MCPServer
-	client    *middleware.Client
-	config    *config.Config
+	clients   accountClients
+
+	configMu sync.RWMutex
+	config   *config.Config
+
+	toolsetsMu      sync.Mutex
+	enabledToolsets map[string]bool
 }
 
 func New(cfg *config.Config) *Server {
-	client := middleware.NewClientWithAuth(cfg.MiddlewareBaseURL, cfg.MiddlewareAPIKey, cfg.AuthorizationToken)
-
-	mcpServer := server.NewMCPServer("middleware-mcp-server", "1.0.0")
+	calls := newInFlightCalls()
+	mcpServer := server.NewMCPServer("middleware-mcp-server", "1.0.0",
+		server.WithHooks(calls.hooks()),
+		server.WithToolHandlerMiddleware(calls.middleware),
+		server.WithElicitation(),
+		server.WithLogging(),
+	)
+	mcpServer.AddNotificationHandler("notifications/cancelled", calls.handleCancelled)
 
 	s := &Server{
 		mcpServer: mcpServer,
-		client:    client,
+		clients:   accountClients{clients: make(map[string]*middleware.Client)},
 		config:    cfg,
+
+		enabledToolsets: make(map[string]bool),
 	}
 
 	// Register all MCP features
@@ -39,23 +52,32 @@ func New(cfg *config.Config) *Server {
 	return s
 }
 
-func (s *Server) Client() *middleware.Client {
-	return s.client
+// Config returns the active configuration, which Reload may replace.
+func (s *Server) Config() *config.Config {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.config
 }
 
 func (s *Server) GetMCPServer() *server.MCPServer {
 	return s.mcpServer
 }
 
-func (s *Server) RunHTTPMode(ctx context.Context, cfg *config.Config) error {
-	// Create streamable HTTP server
-	httpServer := server.NewStreamableHTTPServer(s.mcpServer)
+// HTTPHandler returns the handler of http mode: the streamable HTTP MCP
+// endpoint and the REST gateway, behind the HTTP_AUTH_TOKEN check.
+func (s *Server) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", s.Gateway())
+	mux.Handle("/", server.NewStreamableHTTPServer(s.mcpServer))
+	return s.requireToken(mux)
+}
 
+func (s *Server) RunHTTPMode(ctx context.Context, cfg *config.Config) error {
 	// Create HTTP server
 	addr := fmt.Sprintf("%s:%s", cfg.AppHost, cfg.AppPort)
 	httpSrv := &http.Server{
 		Addr:         addr,
-		Handler:      httpServer,
+		Handler:      s.HTTPHandler(),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
@@ -66,6 +88,9 @@ func (s *Server) RunHTTPMode(ctx context.Context, cfg *config.Config) error {
 	go func() {
 		log.Printf("Starting MCP server in HTTP mode on %s", addr)
 		log.Printf("Server ready. Connect to: http://%s", addr)
+		if cfg.RESTGateway {
+			log.Printf("REST gateway: http://%s/v1/tools (OpenAPI document at /v1/openapi.json)", addr)
+		}
 		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			serverErr <- err
 		}
@@ -95,7 +120,7 @@ func (s *Server) RunSSEMode(ctx context.Context, cfg *config.Config) error {
 	addr := fmt.Sprintf("%s:%s", cfg.AppHost, cfg.AppPort)
 	httpSrv := &http.Server{
 		Addr:         addr,
-		Handler:      sseServer,
+		Handler:      s.requireToken(sseServer),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 0, // No timeout for SSE (long-lived connection)
 		IdleTimeout:  120 * time.Second,
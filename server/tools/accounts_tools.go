@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func NewListAccountsTool() mcp.Tool {
+	return mcp.NewTool(
+		"list_accounts",
+		mcp.WithDescription(`List the Middleware accounts this server is configured for.
+
+Each account is a separate Middleware project with its own base URL and credentials. Pass an account's name as the 'account' argument of any other tool to run it against that project; tools use the default account when 'account' is omitted.
+
+Credentials are never returned, only which kind of credential the account uses.`),
+		mcp.WithInputSchema[ListAccountsInput](),
+		mcp.WithOutputSchema[ListAccountsResult](),
+		mcp.WithTitleAnnotation("List Accounts"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(false),
+	)
+}
+
+type ListAccountsInput struct{}
+
+type AccountInfo struct {
+	Name     string `json:"name"`
+	BaseURL  string `json:"base_url"`
+	AuthType string `json:"auth_type" jsonschema:"enum=api_key,enum=authorization"`
+	Default  bool   `json:"default"`
+}
+
+type ListAccountsResult struct {
+	Accounts []AccountInfo `json:"accounts"`
+}
+
+func HandleListAccounts(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := s.Config()
+
+	defaultName := ""
+	if account, err := cfg.GetAccount(""); err == nil {
+		defaultName = account.Name
+	}
+
+	result := ListAccountsResult{Accounts: []AccountInfo{}}
+	for _, name := range cfg.AccountNames() {
+		account, err := cfg.GetAccount(name)
+		if err != nil {
+			return nil, err
+		}
+
+		authType := "api_key"
+		if account.UsesAuthorization() {
+			authType = "authorization"
+		}
+		result.Accounts = append(result.Accounts, AccountInfo{
+			Name:     name,
+			BaseURL:  account.MiddlewareBaseURL,
+			AuthType: authType,
+			Default:  name == defaultName,
+		})
+	}
+
+	return ToStructuredResult(result)
+}
@@ -15,6 +15,12 @@ func NewListAlertsTool() mcp.Tool {
 		mcp.WithDescription(`Get a list of triggered alerts for a specific alert rule with pagination and sorting.	
 This tool retrieves all alert instances that have been triggered for a specific alert rule. Each alert instance represents a time when the alert condition was met. Use this to review alert history, analyze alert patterns, or investigate recent incidents. Results can be paginated and ordered by various fields.`),
 		mcp.WithInputSchema[ListAlertsInput](),
+		mcp.WithOutputSchema[middleware.AlertsResponse](),
+		mcp.WithTitleAnnotation("List Alerts"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -40,7 +46,7 @@ func HandleListAlerts(s ServerInterface, ctx context.Context, req mcp.CallToolRe
 		return nil, fmt.Errorf("failed to get alerts: %w", err)
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
 
 func NewCreateAlertTool() mcp.Tool {
@@ -52,6 +58,12 @@ This tool allows you to programmatically create alert instances, typically used
 
 Note: In most cases, alerts are automatically created when rule conditions are met. Use this tool for custom alerting workflows or manual alert creation.`),
 		mcp.WithInputSchema[CreateAlertInput](),
+		mcp.WithOutputSchema[middleware.Alert](),
+		mcp.WithTitleAnnotation("Create Alert"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -96,7 +108,7 @@ func HandleCreateAlert(s ServerInterface, ctx context.Context, req mcp.CallToolR
 		return nil, fmt.Errorf("failed to create alert: %w", err)
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
 
 func NewGetAlertStatsTool() mcp.Tool {
@@ -111,6 +123,12 @@ Returns:
 - Count by title: Distribution of alerts by their titles
 - Timeseries by title: Historical alert counts over time grouped by title`),
 		mcp.WithInputSchema[GetAlertStatsInput](),
+		mcp.WithOutputSchema[middleware.StatsResponse](),
+		mcp.WithTitleAnnotation("Get Alert Stats"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -129,5 +147,5 @@ func HandleGetAlertStats(s ServerInterface, ctx context.Context, req mcp.CallToo
 		return nil, fmt.Errorf("failed to get alert stats: %w", err)
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
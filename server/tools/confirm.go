@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"mcp-middleware/middleware"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// shouldConfirm reports whether a destructive tool call has to be confirmed by
+// the user through MCP elicitation before it runs. With CONFIRM_DESTRUCTIVE=auto
+// clients without elicitation support skip confirmation; with require they are refused.
+func shouldConfirm(ctx context.Context, s ServerInterface) (bool, error) {
+	mode := s.Config().ConfirmDestructive
+	if mode == "off" {
+		return false, nil
+	}
+
+	if clientSupportsElicitation(ctx) {
+		return true, nil
+	}
+	if mode == "require" {
+		return false, fmt.Errorf("this operation requires user confirmation, but the client does not support elicitation (set CONFIRM_DESTRUCTIVE=auto or off to allow it)")
+	}
+	return false, nil
+}
+
+func clientSupportsElicitation(ctx context.Context) bool {
+	session := server.ClientSessionFromContext(ctx)
+	if _, ok := session.(server.SessionWithElicitation); !ok {
+		return false
+	}
+	clientInfo, ok := session.(server.SessionWithClientInfo)
+	if !ok {
+		return false
+	}
+	return clientInfo.GetClientCapabilities().Elicitation != nil
+}
+
+// requestConfirmation asks the user to confirm message and reports whether they accepted.
+func requestConfirmation(ctx context.Context, message string) (bool, error) {
+	mcpServer := server.ServerFromContext(ctx)
+	if mcpServer == nil {
+		return false, fmt.Errorf("failed to request confirmation: no MCP server in context")
+	}
+
+	result, err := mcpServer.RequestElicitation(ctx, mcp.ElicitationRequest{
+		Params: mcp.ElicitationParams{
+			Message: message,
+			RequestedSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"confirm": map[string]any{
+						"type":        "boolean",
+						"title":       "Confirm",
+						"description": "Set to true to proceed",
+					},
+				},
+				"required": []string{"confirm"},
+			},
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to request confirmation: %w", err)
+	}
+
+	if result.Action != mcp.ElicitationResponseActionAccept {
+		return false, nil
+	}
+	content, ok := result.Content.(map[string]any)
+	if !ok {
+		return false, nil
+	}
+	confirmed, _ := content["confirm"].(bool)
+	return confirmed, nil
+}
+
+// findDashboardByID pages through the dashboard list looking for id, since the
+// API only looks dashboards up by key.
+func findDashboardByID(ctx context.Context, client *middleware.Client, id int) (*middleware.Report, error) {
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		page, err := client.GetDashboards(ctx, &middleware.GetDashboardsParams{Limit: pageSize, Offset: offset})
+		if err != nil {
+			return nil, err
+		}
+		for i := range page.Reports {
+			if page.Reports[i].ID == id {
+				return &page.Reports[i], nil
+			}
+		}
+		if len(page.Reports) < pageSize || (page.Total > 0 && offset+len(page.Reports) >= page.Total) {
+			return nil, fmt.Errorf("dashboard %d not found", id)
+		}
+	}
+}
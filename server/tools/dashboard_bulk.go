@@ -0,0 +1,417 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"mcp-middleware/middleware"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Actions of bulk_dashboards.
+const (
+	BulkActionDelete        = "delete"
+	BulkActionSetVisibility = "set_visibility"
+	BulkActionSetFavorite   = "set_favorite"
+	BulkActionClone         = "clone"
+)
+
+// Statuses of the dashboards in a bulk_dashboards result.
+const (
+	BulkStatusPlanned   = "planned"
+	BulkStatusDone      = "done"
+	BulkStatusUnchanged = "unchanged"
+	BulkStatusFailed    = "failed"
+	BulkStatusSkipped   = "skipped"
+)
+
+const (
+	defaultBulkMaxDashboards = 50
+	bulkPageSize             = 100
+	defaultCloneLabelSuffix  = " (copy)"
+)
+
+// dashboardTimeLayouts are the formats of dashboard timestamps.
+var dashboardTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05"}
+
+func NewBulkDashboardsTool() mcp.Tool {
+	return mcp.NewTool(
+		"bulk_dashboards",
+		mcp.WithDescription(`Apply one action to every dashboard matching a selection.
+
+This tool selects dashboards by search text, list filters, visibility, owner, favorite status and how long ago they were created or last updated, and applies one action to all of them:
+- delete: permanently delete the dashboards and their widgets
+- set_visibility: make the dashboards public or private
+- set_favorite: add the dashboards to favorites or remove them
+- clone: create a copy of each dashboard, with a suffix added to its name
+
+At least one selector is required. The call is a dry run unless dry_run is set to false: it lists the matching dashboards and what would happen to each without changing anything. Always run it as a dry run first and check the list. Applying the action refuses to change more than max_dashboards dashboards (default: 50). A dashboard that fails doesn't stop the others; the result reports each dashboard's outcome.`),
+		mcp.WithInputSchema[BulkDashboardsInput](),
+		mcp.WithOutputSchema[BulkDashboardsResult](),
+		mcp.WithTitleAnnotation("Bulk Dashboard Operations"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithOpenWorldHintAnnotation(true),
+	)
+}
+
+type BulkDashboardsInput struct {
+	Action string `json:"action" jsonschema:"Action to apply to the selected dashboards,required,enum=delete,enum=set_visibility,enum=set_favorite,enum=clone"`
+
+	Search           string `json:"search,omitempty" jsonschema:"Select dashboards whose name or description matches this search text"`
+	FilterBy         string `json:"filter_by,omitempty" jsonschema:"Comma-separated list_dashboards filters, such as custom or created_by_you"`
+	MatchVisibility  string `json:"match_visibility,omitempty" jsonschema:"Select only public or private dashboards,enum=public,enum=private"`
+	Owner            string `json:"owner,omitempty" jsonschema:"Select dashboards created by the user with this name (case-insensitive)"`
+	MatchFavorite    *bool  `json:"match_favorite,omitempty" jsonschema:"Select only favorite (true) or non-favorite (false) dashboards"`
+	CreatedOlderThan int    `json:"created_older_than_days,omitempty" jsonschema:"Select dashboards created more than this many days ago,minimum=0"`
+	UpdatedOlderThan int    `json:"updated_older_than_days,omitempty" jsonschema:"Select dashboards last updated more than this many days ago,minimum=0"`
+
+	Visibility  string `json:"visibility,omitempty" jsonschema:"Visibility to set with set_visibility, or of the copies with clone (default: that of each dashboard),enum=public,enum=private"`
+	Favorite    *bool  `json:"favorite,omitempty" jsonschema:"With set_favorite: true to add the dashboards to favorites, false to remove them"`
+	LabelSuffix string `json:"label_suffix,omitempty" jsonschema:"With clone: suffix added to the name of each copy (default: ' (copy)')"`
+
+	DryRun        *bool `json:"dry_run,omitempty" jsonschema:"Only report what would be done (default: true). Set to false to apply the action"`
+	MaxDashboards int   `json:"max_dashboards,omitempty" jsonschema:"Refuse to apply the action to more dashboards than this (default: 50),minimum=1"`
+}
+
+// BulkDashboardsResult is the result of bulk_dashboards.
+type BulkDashboardsResult struct {
+	Action    string              `json:"action" jsonschema:"The action applied"`
+	DryRun    bool                `json:"dry_run" jsonschema:"Whether this was a dry run that changed nothing"`
+	Matched   int                 `json:"matched" jsonschema:"Number of dashboards selected"`
+	Succeeded int                 `json:"succeeded" jsonschema:"Number of dashboards the action was applied to"`
+	Unchanged int                 `json:"unchanged" jsonschema:"Number of dashboards already in the requested state"`
+	Failed    int                 `json:"failed" jsonschema:"Number of dashboards the action failed for"`
+	Items     []BulkDashboardItem `json:"items" jsonschema:"The outcome for each selected dashboard"`
+	Message   string              `json:"message" jsonschema:"Summary of the operation"`
+}
+
+// BulkDashboardItem is the outcome of a bulk action for one dashboard.
+type BulkDashboardItem struct {
+	ID         int    `json:"id" jsonschema:"ID of the dashboard"`
+	Key        string `json:"key" jsonschema:"Key of the dashboard"`
+	Label      string `json:"label" jsonschema:"Name of the dashboard"`
+	Visibility string `json:"visibility" jsonschema:"Visibility of the dashboard"`
+	Owner      string `json:"owner,omitempty" jsonschema:"Name of the user who created the dashboard"`
+	UpdatedAt  string `json:"updated_at,omitempty" jsonschema:"When the dashboard was last updated"`
+	Status     string `json:"status" jsonschema:"planned, done, unchanged, failed or skipped"`
+	Detail     string `json:"detail,omitempty" jsonschema:"What was or would be done, or why it failed"`
+	CloneID    int    `json:"clone_id,omitempty" jsonschema:"ID of the copy, with clone"`
+	CloneKey   string `json:"clone_key,omitempty" jsonschema:"Key of the copy, with clone"`
+}
+
+func HandleBulkDashboards(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := ParseInput[BulkDashboardsInput](req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+	dryRun := input.DryRun == nil || *input.DryRun
+	maxDashboards := input.MaxDashboards
+	if maxDashboards <= 0 {
+		maxDashboards = defaultBulkMaxDashboards
+	}
+
+	client := s.Client()
+	selected, err := selectDashboards(ctx, client, &input, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkDashboardsResult{Action: input.Action, DryRun: dryRun, Matched: len(selected), Items: []BulkDashboardItem{}}
+	for _, report := range selected {
+		item := newBulkDashboardItem(report)
+		item.Status, item.Detail = input.plan(report)
+		result.Items = append(result.Items, item)
+	}
+
+	if !dryRun && len(selected) > maxDashboards {
+		return nil, invalidInput("%d dashboards match, more than max_dashboards (%d); narrow the selection or raise max_dashboards", len(selected), maxDashboards)
+	}
+	if !dryRun && input.Action == BulkActionDelete && len(selected) > 0 {
+		confirm, err := shouldConfirm(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		if confirm {
+			message := fmt.Sprintf("Permanently delete %d dashboards and their widgets? This cannot be undone.", len(selected))
+			confirmed, err := requestConfirmation(ctx, message)
+			if err != nil {
+				return nil, err
+			}
+			if !confirmed {
+				for i := range result.Items {
+					result.Items[i].Status = BulkStatusSkipped
+				}
+				result.Message = "Bulk deletion cancelled by user"
+				return ToStructuredResult(result)
+			}
+		}
+	}
+
+	if !dryRun {
+		var history *DashboardHistory
+		if input.Action == BulkActionDelete || input.Action == BulkActionSetVisibility {
+			history, err = dashboardHistory(s, req)
+			if err != nil {
+				client.Log(ctx, middleware.LogLevelWarning, "failed to snapshot dashboards before tool call", map[string]any{"tool": req.Params.Name, "error": err.Error()})
+			}
+		}
+
+		progress := NewProgress(ctx, req, len(selected))
+		for i := range selected {
+			item := &result.Items[i]
+			if item.Status == BulkStatusUnchanged {
+				progress.Step(fmt.Sprintf("Dashboard %s unchanged", item.Key))
+				continue
+			}
+			if ctx.Err() != nil {
+				item.Status, item.Detail = BulkStatusSkipped, "cancelled"
+				continue
+			}
+			if history != nil {
+				snapshotDashboard(ctx, client, history, &selected[i], req.Params.Name)
+			}
+			if err := input.apply(ctx, client, &selected[i], item); err != nil {
+				item.Status, item.Detail = BulkStatusFailed, err.Error()
+			} else {
+				item.Status = BulkStatusDone
+			}
+			progress.Step(fmt.Sprintf("Dashboard %s %s", item.Key, item.Status))
+		}
+	}
+
+	for _, item := range result.Items {
+		switch item.Status {
+		case BulkStatusDone:
+			result.Succeeded++
+		case BulkStatusUnchanged:
+			result.Unchanged++
+		case BulkStatusFailed:
+			result.Failed++
+		}
+	}
+	result.Message = bulkSummary(result)
+	return ToStructuredResult(result)
+}
+
+// validate checks that the input selects something and has what its action
+// needs.
+func (in *BulkDashboardsInput) validate() error {
+	if in.Search == "" && in.FilterBy == "" && in.MatchVisibility == "" && in.Owner == "" && in.MatchFavorite == nil &&
+		in.CreatedOlderThan <= 0 && in.UpdatedOlderThan <= 0 {
+		return invalidInput("at least one selector is required: search, filter_by, match_visibility, owner, match_favorite, created_older_than_days or updated_older_than_days")
+	}
+	switch in.Action {
+	case BulkActionDelete, BulkActionClone:
+	case BulkActionSetVisibility:
+		if in.Visibility != "public" && in.Visibility != "private" {
+			return invalidInput("set_visibility requires visibility to be public or private")
+		}
+	case BulkActionSetFavorite:
+		if in.Favorite == nil {
+			return invalidInput("set_favorite requires favorite")
+		}
+	default:
+		return invalidInput("unknown action %q (must be delete, set_visibility, set_favorite or clone)", in.Action)
+	}
+	return nil
+}
+
+// selectDashboards pages through the dashboards matching the search and list
+// filters, and keeps those matching the other selectors.
+func selectDashboards(ctx context.Context, client *middleware.Client, in *BulkDashboardsInput, now time.Time) ([]middleware.Report, error) {
+	var selected []middleware.Report
+	for offset := 0; ; offset += bulkPageSize {
+		page, err := client.GetDashboards(ctx, &middleware.GetDashboardsParams{
+			Limit:    bulkPageSize,
+			Offset:   offset,
+			Search:   in.Search,
+			FilterBy: in.FilterBy,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list dashboards: %w", err)
+		}
+		for _, report := range page.Reports {
+			if in.matches(&report, now) {
+				selected = append(selected, report)
+			}
+		}
+		if len(page.Reports) < bulkPageSize || (page.Total > 0 && offset+len(page.Reports) >= page.Total) {
+			return selected, nil
+		}
+	}
+}
+
+// matches reports whether report matches the selectors the API doesn't
+// filter on. A dashboard whose timestamp can't be read doesn't match an age
+// selector.
+func (in *BulkDashboardsInput) matches(report *middleware.Report, now time.Time) bool {
+	if in.MatchVisibility != "" && report.Visibility != in.MatchVisibility {
+		return false
+	}
+	if in.Owner != "" && (report.User == nil || !strings.EqualFold(strings.TrimSpace(report.User.Name), strings.TrimSpace(in.Owner))) {
+		return false
+	}
+	if in.MatchFavorite != nil && report.Favorite != *in.MatchFavorite {
+		return false
+	}
+	if in.CreatedOlderThan > 0 && !olderThan(report.CreatedAt, in.CreatedOlderThan, now) {
+		return false
+	}
+	if in.UpdatedOlderThan > 0 && !olderThan(report.UpdatedAt, in.UpdatedOlderThan, now) {
+		return false
+	}
+	return true
+}
+
+// olderThan reports whether timestamp is more than days before now.
+func olderThan(timestamp string, days int, now time.Time) bool {
+	for _, layout := range dashboardTimeLayouts {
+		if t, err := time.Parse(layout, timestamp); err == nil {
+			return now.Sub(t) > time.Duration(days)*24*time.Hour
+		}
+	}
+	return false
+}
+
+func newBulkDashboardItem(report middleware.Report) BulkDashboardItem {
+	item := BulkDashboardItem{
+		ID:         report.ID,
+		Key:        report.Key,
+		Label:      report.Label,
+		Visibility: report.Visibility,
+		UpdatedAt:  report.UpdatedAt,
+	}
+	if report.User != nil {
+		item.Owner = report.User.Name
+	}
+	return item
+}
+
+// plan describes what the action does to report, or reports it unchanged if
+// it is already in the requested state.
+func (in *BulkDashboardsInput) plan(report middleware.Report) (status, detail string) {
+	switch in.Action {
+	case BulkActionDelete:
+		return BulkStatusPlanned, "delete the dashboard and its widgets"
+	case BulkActionSetVisibility:
+		if report.Visibility == in.Visibility {
+			return BulkStatusUnchanged, "already " + in.Visibility
+		}
+		return BulkStatusPlanned, fmt.Sprintf("change visibility from %s to %s", report.Visibility, in.Visibility)
+	case BulkActionSetFavorite:
+		if report.Favorite == *in.Favorite {
+			return BulkStatusUnchanged, fmt.Sprintf("favorite already %t", report.Favorite)
+		}
+		if *in.Favorite {
+			return BulkStatusPlanned, "add to favorites"
+		}
+		return BulkStatusPlanned, "remove from favorites"
+	default:
+		return BulkStatusPlanned, fmt.Sprintf("clone as %q (%s)", in.cloneLabel(report), in.cloneVisibility(report))
+	}
+}
+
+// apply applies the action to report and records the outcome in item.
+func (in *BulkDashboardsInput) apply(ctx context.Context, client *middleware.Client, report *middleware.Report, item *BulkDashboardItem) error {
+	switch in.Action {
+	case BulkActionDelete:
+		if err := client.DeleteDashboard(ctx, report.ID); err != nil {
+			return fmt.Errorf("failed to delete dashboard: %w", err)
+		}
+		item.Detail = "deleted"
+	case BulkActionSetVisibility:
+		_, err := client.UpdateDashboard(ctx, report.ID, &middleware.UpsertReportRequest{
+			ID:           report.ID,
+			Key:          report.Key,
+			Label:        report.Label,
+			Description:  report.Description,
+			DisplayScope: report.DisplayScope,
+			Visibility:   in.Visibility,
+			MetaData:     report.MetaData,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update dashboard: %w", err)
+		}
+		item.Visibility = in.Visibility
+		item.Detail = "visibility set to " + in.Visibility
+	case BulkActionSetFavorite:
+		if err := client.SetDashboardFavorite(ctx, report.ID, *in.Favorite); err != nil {
+			return fmt.Errorf("failed to set favorite: %w", err)
+		}
+		item.Detail = fmt.Sprintf("favorite set to %t", *in.Favorite)
+	case BulkActionClone:
+		clone, err := client.CloneDashboard(ctx, &middleware.UpsertReportRequest{
+			Key:         report.Key,
+			Label:       in.cloneLabel(*report),
+			Description: report.Description,
+			Visibility:  in.cloneVisibility(*report),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to clone dashboard: %w", err)
+		}
+		item.CloneID, item.CloneKey = clone.ID, clone.Key
+		item.Detail = fmt.Sprintf("cloned as %q", clone.Label)
+	}
+	return nil
+}
+
+func (in *BulkDashboardsInput) cloneLabel(report middleware.Report) string {
+	suffix := in.LabelSuffix
+	if suffix == "" {
+		suffix = defaultCloneLabelSuffix
+	}
+	return report.Label + suffix
+}
+
+func (in *BulkDashboardsInput) cloneVisibility(report middleware.Report) string {
+	if in.Visibility != "" {
+		return in.Visibility
+	}
+	return report.Visibility
+}
+
+// snapshotDashboard saves report and its widgets to the history before a bulk
+// action changes it. A snapshot that fails is logged and doesn't stop the
+// action.
+func snapshotDashboard(ctx context.Context, client *middleware.Client, history *DashboardHistory, report *middleware.Report, tool string) {
+	widgets, err := client.GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: report.ID})
+	if err == nil {
+		_, err = history.Save(report, widgets, tool)
+	}
+	if err != nil {
+		client.Log(ctx, middleware.LogLevelWarning, "failed to snapshot dashboard before tool call", map[string]any{"tool": tool, "dashboard": report.Key, "error": err.Error()})
+	}
+}
+
+func bulkSummary(result *BulkDashboardsResult) string {
+	if result.Matched == 0 {
+		return "No dashboards match the selection"
+	}
+	if result.DryRun {
+		planned := result.Matched - result.Unchanged
+		message := fmt.Sprintf("Dry run: %s would apply to %d of %d matching dashboards", result.Action, planned, result.Matched)
+		if result.Unchanged > 0 {
+			message += fmt.Sprintf(" (%d already in that state)", result.Unchanged)
+		}
+		return message + "; set dry_run to false to apply it"
+	}
+	message := fmt.Sprintf("%s applied to %d of %d matching dashboards", result.Action, result.Succeeded, result.Matched)
+	if result.Unchanged > 0 {
+		message += fmt.Sprintf(", %d unchanged", result.Unchanged)
+	}
+	if result.Failed > 0 {
+		message += fmt.Sprintf(", %d failed", result.Failed)
+	}
+	if skipped := result.Matched - result.Succeeded - result.Unchanged - result.Failed; skipped > 0 {
+		message += fmt.Sprintf(", %d skipped", skipped)
+	}
+	return message
+}
@@ -0,0 +1,411 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"mcp-middleware/middleware"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func NewDiffDashboardsTool() mcp.Tool {
+	return mcp.NewTool(
+		"diff_dashboards",
+		mcp.WithDescription(`Compare two dashboards, or a dashboard and a document from export_dashboard, and describe the differences.
+
+The base is always a dashboard in the account. The target is either another dashboard (target_key) or a dashboard document (target_document), for example one kept in version control. Widgets are matched by key, then by label. The result lists dashboard setting changes, widgets added and removed, and for each changed widget its chart type, columns, filters, group by, other config changes and layout moves, with a human-readable summary in text.`),
+		mcp.WithInputSchema[DiffDashboardsInput](),
+		mcp.WithOutputSchema[DashboardDiff](),
+		mcp.WithTitleAnnotation("Diff Dashboards"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
+	)
+}
+
+type DiffDashboardsInput struct {
+	BaseKey        string             `json:"base_key" jsonschema:"The key of the dashboard to compare from,required"`
+	TargetKey      string             `json:"target_key,omitempty" jsonschema:"The key of the dashboard to compare to. Set either this or target_document"`
+	TargetDocument *DashboardDocument `json:"target_document,omitempty" jsonschema:"A dashboard document to compare to, as returned by export_dashboard. Set either this or target_key"`
+}
+
+// DashboardDiff is the difference between two versions of a dashboard.
+type DashboardDiff struct {
+	Base      string          `json:"base" jsonschema:"What the diff compares from"`
+	Target    string          `json:"target" jsonschema:"What the diff compares to"`
+	Identical bool            `json:"identical" jsonschema:"Whether the dashboards have no differences"`
+	Settings  []SettingChange `json:"settings,omitempty" jsonschema:"Changed dashboard settings"`
+	Added     []WidgetDiff    `json:"added,omitempty" jsonschema:"Widgets only in the target"`
+	Removed   []WidgetDiff    `json:"removed,omitempty" jsonschema:"Widgets only in the base"`
+	Changed   []WidgetDiff    `json:"changed,omitempty" jsonschema:"Widgets in both that differ"`
+	Text      string          `json:"text" jsonschema:"Human-readable summary of the diff"`
+}
+
+// WidgetDiff describes a widget that was added, removed or changed.
+type WidgetDiff struct {
+	Key       string        `json:"key,omitempty" jsonschema:"Key of the widget"`
+	Label     string        `json:"label" jsonschema:"Title of the widget"`
+	LabelFrom string        `json:"label_from,omitempty" jsonschema:"Previous title, if it changed"`
+	ChartType *ValueChange  `json:"chart_type,omitempty" jsonschema:"Change of the widget type"`
+	Columns   *ListChange   `json:"columns,omitempty" jsonschema:"Columns added and removed across the widget's queries"`
+	Filters   []ValueChange `json:"filters,omitempty" jsonschema:"Changed filters, one per query"`
+	GroupBy   *ListChange   `json:"group_by,omitempty" jsonschema:"Group by attributes added and removed across the widget's queries"`
+	Config    []string      `json:"config,omitempty" jsonschema:"Paths of other changed config and metadata fields"`
+	Layout    *LayoutChange `json:"layout,omitempty" jsonschema:"Change of position or size"`
+}
+
+// ValueChange is a value before and after a change.
+type ValueChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ListChange is the items added to and removed from a list.
+type ListChange struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// LayoutChange is a widget's layout before and after a change.
+type LayoutChange struct {
+	From *WidgetLayout `json:"from"`
+	To   *WidgetLayout `json:"to"`
+}
+
+// String formats a layout as "x,y wxh".
+func (l *WidgetLayout) String() string {
+	if l == nil {
+		return "no position"
+	}
+	return fmt.Sprintf("%d,%d %dx%d", l.X, l.Y, l.W, l.H)
+}
+
+func HandleDiffDashboards(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := ParseInput[DiffDashboardsInput](req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+	if (input.TargetKey == "") == (input.TargetDocument == nil) {
+		return nil, invalidInput("set exactly one of target_key and target_document")
+	}
+
+	base, err := exportDashboard(ctx, s.Client(), input.BaseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	target := input.TargetDocument
+	targetName := "the document"
+	if target != nil {
+		if err := validateDocument(target); err != nil {
+			return nil, err
+		}
+		if target.Key != "" {
+			targetName = fmt.Sprintf("the document of %s", target.Key)
+		}
+	} else {
+		if target, err = exportDashboard(ctx, s.Client(), input.TargetKey); err != nil {
+			return nil, err
+		}
+		targetName = input.TargetKey
+	}
+
+	diff := diffDocuments(base, target)
+	diff.Base = input.BaseKey
+	diff.Target = targetName
+	diff.Text = formatDiff(diff)
+	return ToStructuredResult(diff)
+}
+
+// diffDocuments compares two dashboard documents.
+func diffDocuments(base, target *DashboardDocument) *DashboardDiff {
+	diff := &DashboardDiff{}
+	setting := func(field, from, to string) {
+		if from != to {
+			diff.Settings = append(diff.Settings, SettingChange{Field: field, From: from, To: to})
+		}
+	}
+	setting("label", base.Label, target.Label)
+	setting("description", base.Description, target.Description)
+	setting("visibility", base.Visibility, target.Visibility)
+	setting("display_scope", base.DisplayScope, target.DisplayScope)
+	for _, path := range diffBoth("meta_data", base.MetaData, target.MetaData) {
+		diff.Settings = append(diff.Settings, SettingChange{Field: path})
+	}
+
+	pairs, added, removed := matchWidgets(base.Widgets, target.Widgets)
+	for _, j := range added {
+		diff.Added = append(diff.Added, WidgetDiff{Key: target.Widgets[j].Key, Label: target.Widgets[j].Label})
+	}
+	for _, i := range removed {
+		diff.Removed = append(diff.Removed, WidgetDiff{Key: base.Widgets[i].Key, Label: base.Widgets[i].Label})
+	}
+	for _, pair := range pairs {
+		if widgetDiff, changed := diffWidgets(base.Widgets[pair[0]], target.Widgets[pair[1]]); changed {
+			diff.Changed = append(diff.Changed, widgetDiff)
+		}
+	}
+
+	diff.Identical = len(diff.Settings)+len(diff.Added)+len(diff.Removed)+len(diff.Changed) == 0
+	return diff
+}
+
+func diffWidgets(base, target DashboardDocWidget) (WidgetDiff, bool) {
+	diff := WidgetDiff{Key: target.Key, Label: target.Label}
+	if diff.Key == "" {
+		diff.Key = base.Key
+	}
+	changed := false
+
+	if base.Label != target.Label {
+		diff.LabelFrom = base.Label
+		changed = true
+	}
+	if baseType, targetType := widgetTypeOf(base), widgetTypeOf(target); baseType != targetType {
+		diff.ChartType = &ValueChange{From: baseType, To: targetType}
+		changed = true
+	}
+
+	baseQueries, targetQueries := builderQueries(base.Config), builderQueries(target.Config)
+	if columns := diffLists(queryColumns(baseQueries), queryColumns(targetQueries)); columns != nil {
+		diff.Columns = columns
+		changed = true
+	}
+	if groupBy := diffLists(queryGroupBy(baseQueries), queryGroupBy(targetQueries)); groupBy != nil {
+		diff.GroupBy = groupBy
+		changed = true
+	}
+	for i := 0; i < len(baseQueries) || i < len(targetQueries); i++ {
+		from, to := queryFilter(baseQueries, i), queryFilter(targetQueries, i)
+		if from != to {
+			diff.Filters = append(diff.Filters, ValueChange{From: from, To: to})
+			changed = true
+		}
+	}
+
+	// Columns and the with items are reported above.
+	for _, path := range append(diffBoth("config", base.Config, target.Config), diffBoth("meta_data", base.MetaData, target.MetaData)...) {
+		if isQueryPath(path) {
+			continue
+		}
+		diff.Config = append(diff.Config, path)
+		changed = true
+	}
+
+	if base.Layout.String() != target.Layout.String() {
+		diff.Layout = &LayoutChange{From: base.Layout, To: target.Layout}
+		changed = true
+	}
+	return diff, changed
+}
+
+func widgetTypeOf(widget DashboardDocWidget) string {
+	if widget.WidgetType != "" {
+		return widget.WidgetType
+	}
+	if widget.WidgetAppID != 0 {
+		return fmt.Sprintf("widget app %d", widget.WidgetAppID)
+	}
+	return ""
+}
+
+// diffBoth returns the paths at which a and b differ, in either direction.
+func diffBoth(path string, a, b any) []string {
+	var paths []string
+	diffValues(path, a, b, &paths)
+	diffValues(path, b, a, &paths)
+	if a != nil && b == nil {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+	unique := paths[:0]
+	for i, p := range paths {
+		if i == 0 || p != paths[i-1] {
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
+
+func isQueryPath(path string) bool {
+	if !strings.HasPrefix(path, "config.builderConfig[") {
+		return false
+	}
+	_, rest, _ := strings.Cut(path, "]")
+	return rest == "" || strings.HasPrefix(rest, ".columns") || strings.HasPrefix(rest, ".with")
+}
+
+// builderQueries returns the builderConfig items of a widget config.
+func builderQueries(config any) []map[string]any {
+	fields, _ := config.(map[string]any)
+	items, _ := fields["builderConfig"].([]any)
+	queries := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		query, _ := item.(map[string]any)
+		queries = append(queries, query)
+	}
+	return queries
+}
+
+func queryColumns(queries []map[string]any) []string {
+	var columns []string
+	for _, query := range queries {
+		items, _ := query["columns"].([]any)
+		for _, item := range items {
+			columns = append(columns, compactValue(item))
+		}
+	}
+	return columns
+}
+
+func queryGroupBy(queries []map[string]any) []string {
+	var groupBy []string
+	for _, query := range queries {
+		values, _ := queryWith(query, middleware.BuilderConfigWithKeySelectDataBy).([]any)
+		for _, value := range values {
+			groupBy = append(groupBy, compactValue(value))
+		}
+	}
+	return groupBy
+}
+
+// queryFilter returns the filter of the i-th query as compact JSON, or "" if
+// it has none.
+func queryFilter(queries []map[string]any, i int) string {
+	if i >= len(queries) {
+		return ""
+	}
+	filter := queryWith(queries[i], middleware.BuilderConfigWithKeyAttributeFilter)
+	if filter == nil {
+		return ""
+	}
+	return compactValue(filter)
+}
+
+// queryWith returns the value of the with item with the given key.
+func queryWith(query map[string]any, key middleware.BuilderConfigWithKey) any {
+	items, _ := query["with"].([]any)
+	for _, item := range items {
+		with, _ := item.(map[string]any)
+		if with["key"] == string(key) {
+			return with["value"]
+		}
+	}
+	return nil
+}
+
+// diffLists returns the items only in to as added and those only in from as
+// removed, or nil if there are none.
+func diffLists(from, to []string) *ListChange {
+	count := func(items []string) map[string]int {
+		counts := make(map[string]int, len(items))
+		for _, item := range items {
+			counts[item]++
+		}
+		return counts
+	}
+	fromCounts, toCounts := count(from), count(to)
+
+	change := &ListChange{}
+	for item, n := range toCounts {
+		if n > fromCounts[item] {
+			change.Added = append(change.Added, item)
+		}
+	}
+	for item, n := range fromCounts {
+		if n > toCounts[item] {
+			change.Removed = append(change.Removed, item)
+		}
+	}
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return nil
+	}
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+	return change
+}
+
+// compactValue formats strings as is and other values as compact JSON.
+func compactValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func formatDiff(diff *DashboardDiff) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Comparing %s with %s\n", diff.Base, diff.Target)
+	if diff.Identical {
+		b.WriteString("No differences.\n")
+		return b.String()
+	}
+
+	for _, setting := range diff.Settings {
+		if setting.From == "" && setting.To == "" {
+			fmt.Fprintf(&b, "~ %s\n", setting.Field)
+		} else {
+			fmt.Fprintf(&b, "~ %s: %q -> %q\n", setting.Field, setting.From, setting.To)
+		}
+	}
+	for _, widget := range diff.Added {
+		fmt.Fprintf(&b, "+ widget %q\n", widget.Label)
+	}
+	for _, widget := range diff.Removed {
+		fmt.Fprintf(&b, "- widget %q\n", widget.Label)
+	}
+	listChange := func(change *ListChange) string {
+		var parts []string
+		for _, item := range change.Added {
+			parts = append(parts, "+"+item)
+		}
+		for _, item := range change.Removed {
+			parts = append(parts, "-"+item)
+		}
+		return strings.Join(parts, " ")
+	}
+	for _, widget := range diff.Changed {
+		fmt.Fprintf(&b, "~ widget %q\n", widget.Label)
+		if widget.LabelFrom != "" {
+			fmt.Fprintf(&b, "    renamed from %q\n", widget.LabelFrom)
+		}
+		if widget.ChartType != nil {
+			fmt.Fprintf(&b, "    chart type: %s -> %s\n", widget.ChartType.From, widget.ChartType.To)
+		}
+		if widget.Columns != nil {
+			fmt.Fprintf(&b, "    columns: %s\n", listChange(widget.Columns))
+		}
+		for _, filter := range widget.Filters {
+			fmt.Fprintf(&b, "    filter: %s -> %s\n", orNone(filter.From), orNone(filter.To))
+		}
+		if widget.GroupBy != nil {
+			fmt.Fprintf(&b, "    group by: %s\n", listChange(widget.GroupBy))
+		}
+		for _, path := range widget.Config {
+			fmt.Fprintf(&b, "    changed %s\n", path)
+		}
+		if widget.Layout != nil {
+			fmt.Fprintf(&b, "    moved from %s to %s\n", widget.Layout.From, widget.Layout.To)
+		}
+	}
+	fmt.Fprintf(&b, "%d settings changed, %d widgets added, %d removed, %d changed.\n",
+		len(diff.Settings), len(diff.Added), len(diff.Removed), len(diff.Changed))
+	return b.String()
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
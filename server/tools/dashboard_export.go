@@ -0,0 +1,264 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"mcp-middleware/middleware"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DashboardDocumentVersion is the version of the dashboard document format.
+// It changes only when a document written by an older version can no longer
+// be read as is.
+const DashboardDocumentVersion = 1
+
+// DashboardDocument is a dashboard and its widgets in a portable form, with
+// the IDs that only make sense in one account removed.
+type DashboardDocument struct {
+	Version      int                  `json:"version" jsonschema:"Version of the dashboard document format"`
+	Key          string               `json:"key,omitempty" jsonschema:"Key of the exported dashboard"`
+	Label        string               `json:"label" jsonschema:"Name of the dashboard"`
+	Description  string               `json:"description,omitempty" jsonschema:"Description of the dashboard"`
+	Visibility   string               `json:"visibility,omitempty" jsonschema:"Visibility of the dashboard: public or private"`
+	DisplayScope string               `json:"display_scope,omitempty" jsonschema:"Display scope of the dashboard"`
+	MetaData     any                  `json:"meta_data,omitempty" jsonschema:"Dashboard metadata"`
+	Widgets      []DashboardDocWidget `json:"widgets" jsonschema:"Widgets of the dashboard, ordered by position"`
+}
+
+// DashboardDocWidget is a widget in a DashboardDocument.
+type DashboardDocWidget struct {
+	Key         string        `json:"key,omitempty" jsonschema:"Key of the widget"`
+	Label       string        `json:"label" jsonschema:"Title of the widget"`
+	WidgetType  string        `json:"widget_type,omitempty" jsonschema:"Type of visualization, as accepted by create_widget"`
+	WidgetAppID int           `json:"widget_app_id,omitempty" jsonschema:"Widget app ID, set only when it maps to no known widget type"`
+	Config      any           `json:"config,omitempty" jsonschema:"Builder configuration of the widget"`
+	MetaData    any           `json:"meta_data,omitempty" jsonschema:"Widget metadata"`
+	Layout      *WidgetLayout `json:"layout,omitempty" jsonschema:"Position and size of the widget in the dashboard grid"`
+}
+
+// WidgetLayout is the position and size of a widget in the dashboard grid.
+type WidgetLayout struct {
+	X int `json:"x" jsonschema:"Horizontal position in the grid"`
+	Y int `json:"y" jsonschema:"Vertical position in the grid"`
+	W int `json:"w" jsonschema:"Width in grid units"`
+	H int `json:"h" jsonschema:"Height in grid units"`
+}
+
+// accountSpecificKeys are the fields stripped from exported widget configs and
+// metadata. They identify objects in the account the dashboard came from.
+var accountSpecificKeys = map[string]bool{
+	"account_id": true, "accountId": true,
+	"project_id": true, "projectId": true,
+	"user_id": true, "userId": true,
+	"report_id": true, "reportId": true,
+	"builder_id": true, "builderId": true,
+	"scope_id": true, "scopeId": true, "_scope_id": true,
+	"dataset_id": true, "datasetId": true,
+	"widget_app_id": true, "widgetAppId": true,
+	"created_at": true, "createdAt": true,
+	"updated_at": true, "updatedAt": true,
+}
+
+func NewExportDashboardTool() mcp.Tool {
+	return mcp.NewTool(
+		"export_dashboard",
+		mcp.WithDescription(`Export a dashboard and all its widgets as one portable JSON document.
+
+The document holds the dashboard's label, description and visibility, and every widget's type, builder configuration, metadata and layout. IDs that only exist in the source account (dashboard, widget, scope, dataset, account, project and user IDs) and timestamps are removed, so the document can be committed to version control or imported into another account.`),
+		mcp.WithInputSchema[ExportDashboardInput](),
+		mcp.WithOutputSchema[DashboardDocument](),
+		mcp.WithTitleAnnotation("Export Dashboard"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
+	)
+}
+
+type ExportDashboardInput struct {
+	ReportKey string `json:"report_key" jsonschema:"The unique key identifier of the dashboard to export,required"`
+}
+
+func HandleExportDashboard(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := ParseInput[ExportDashboardInput](req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	doc, err := exportDashboard(ctx, s.Client(), input.ReportKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToStructuredResult(doc)
+}
+
+// exportDashboard fetches the dashboard with the given key and its widgets and
+// builds their document.
+func exportDashboard(ctx context.Context, client *middleware.Client, key string) (*DashboardDocument, error) {
+	report, err := getDashboardByKey(ctx, client, key)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, fmt.Errorf("dashboard %s not found", key)
+	}
+
+	widgets, err := client.GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: report.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get widgets: %w", err)
+	}
+
+	return newDashboardDocument(report, widgets), nil
+}
+
+// getDashboardByKey returns the dashboard with the given key, or nil if there
+// is none.
+func getDashboardByKey(ctx context.Context, client *middleware.Client, key string) (*middleware.Report, error) {
+	result, err := client.GetDashboardByKey(ctx, key)
+	var apiErr *middleware.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Reports) == 0 {
+		return nil, nil
+	}
+	return &result.Reports[0], nil
+}
+
+func newDashboardDocument(report *middleware.Report, widgets []middleware.Widget) *DashboardDocument {
+	doc := &DashboardDocument{
+		Version:      DashboardDocumentVersion,
+		Key:          report.Key,
+		Label:        report.Label,
+		Description:  report.Description,
+		Visibility:   report.Visibility,
+		DisplayScope: report.DisplayScope,
+		MetaData:     stripAccountIDs(report.MetaData),
+		Widgets:      make([]DashboardDocWidget, 0, len(widgets)),
+	}
+	for _, widget := range widgets {
+		doc.Widgets = append(doc.Widgets, newDashboardDocWidget(widget))
+	}
+	sortDocWidgets(doc.Widgets)
+	return doc
+}
+
+func newDashboardDocWidget(widget middleware.Widget) DashboardDocWidget {
+	docWidget := DashboardDocWidget{
+		Key:        widget.Key,
+		Label:      widget.Label,
+		WidgetType: widgetTypeName(widget.WidgetAppID),
+	}
+	if docWidget.WidgetType == "" {
+		docWidget.WidgetAppID = widget.WidgetAppID
+	}
+
+	config := stripAccountIDs(widget.Config)
+	if configMap, ok := config.(map[string]any); ok {
+		// The dashboard a widget belongs to is implied by the document.
+		if viewOptions, ok := configMap["builderViewOptions"].(map[string]any); ok {
+			delete(viewOptions, "report")
+		}
+		docWidget.Layout = readLayout(configMap["layout"])
+		delete(configMap, "layout")
+	}
+	docWidget.Config = config
+	docWidget.MetaData = stripAccountIDs(widget.MetaData)
+
+	// The layout is kept on the widget's scope in the dashboard, so prefer it
+	// over the one in the widget's own config.
+	if widget.Scope != nil {
+		if scopeMeta, ok := widget.Scope.MetaData.(map[string]any); ok {
+			if layout := readLayout(scopeMeta["layout"]); layout != nil {
+				docWidget.Layout = layout
+			}
+		}
+	}
+	return docWidget
+}
+
+// widgetTypeName returns the widget type with the given WidgetAppID, or "" if
+// there is none.
+func widgetTypeName(appID int) string {
+	for name, id := range widgetTypeIDs {
+		if id == appID {
+			return name
+		}
+	}
+	return ""
+}
+
+// readLayout reads a layout object as returned by the API. It returns nil if
+// value isn't one.
+func readLayout(value any) *WidgetLayout {
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	number := func(key string) int {
+		n, _ := fields[key].(float64)
+		return int(n)
+	}
+	layout := &WidgetLayout{X: number("x"), Y: number("y"), W: number("w"), H: number("h")}
+	if layout.W == 0 && layout.H == 0 {
+		return nil
+	}
+	return layout
+}
+
+// sortDocWidgets orders widgets top to bottom and left to right, with the
+// widgets without a layout last, so that the same dashboard always exports to
+// the same document.
+func sortDocWidgets(widgets []DashboardDocWidget) {
+	sort.SliceStable(widgets, func(i, j int) bool {
+		a, b := widgets[i], widgets[j]
+		if (a.Layout == nil) != (b.Layout == nil) {
+			return b.Layout == nil
+		}
+		if a.Layout != nil {
+			if a.Layout.Y != b.Layout.Y {
+				return a.Layout.Y < b.Layout.Y
+			}
+			if a.Layout.X != b.Layout.X {
+				return a.Layout.X < b.Layout.X
+			}
+		}
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+		return a.Key < b.Key
+	})
+}
+
+// stripAccountIDs returns a copy of value, as decoded by encoding/json, with
+// the accountSpecificKeys removed from every object in it.
+func stripAccountIDs(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		stripped := make(map[string]any, len(v))
+		for key, item := range v {
+			if accountSpecificKeys[key] {
+				continue
+			}
+			stripped[key] = stripAccountIDs(item)
+		}
+		return stripped
+	case []any:
+		stripped := make([]any, len(v))
+		for i, item := range v {
+			stripped[i] = stripAccountIDs(item)
+		}
+		return stripped
+	default:
+		return value
+	}
+}
@@ -0,0 +1,704 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcp-middleware/middleware"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var (
+	versionIDPattern   = regexp.MustCompile(`^(\d+)-(\d+)$`)
+	accountDirReplacer = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// DashboardVersion is a snapshot of a dashboard and its widgets, taken before
+// a tool call changed them.
+type DashboardVersion struct {
+	ID        string              `json:"id"`
+	CreatedAt time.Time           `json:"created_at"`
+	Tool      string              `json:"tool"`
+	Dashboard middleware.Report   `json:"dashboard"`
+	Widgets   []middleware.Widget `json:"widgets"`
+}
+
+// DashboardHistory is the local store of the dashboard versions of one
+// account: a directory with one JSON file per version.
+type DashboardHistory struct {
+	dir      string
+	versions int
+	maxAge   time.Duration
+}
+
+// dashboardHistory returns the history of the account a tool call runs
+// against, or nil if the history is disabled.
+func dashboardHistory(s ServerInterface, req mcp.CallToolRequest) (*DashboardHistory, error) {
+	cfg := s.Config()
+	if cfg.DashboardHistoryDir == "" || cfg.DashboardHistoryVersions <= 0 {
+		return nil, nil
+	}
+	account, err := cfg.GetAccount(req.GetString("account", ""))
+	if err != nil {
+		return nil, err
+	}
+	return &DashboardHistory{
+		dir:      filepath.Join(cfg.DashboardHistoryDir, accountDirReplacer.ReplaceAllString(account.Name, "_")),
+		versions: cfg.DashboardHistoryVersions,
+		maxAge:   cfg.DashboardHistoryMaxAge,
+	}, nil
+}
+
+// Save stores a snapshot of report and its widgets, taken before tool, and
+// drops the versions of the dashboard the retention settings no longer keep.
+func (h *DashboardHistory) Save(report *middleware.Report, widgets []middleware.Widget, tool string) (*DashboardVersion, error) {
+	now := time.Now().UTC()
+	version := &DashboardVersion{
+		ID:        fmt.Sprintf("%d-%d", report.ID, now.UnixNano()),
+		CreatedAt: now,
+		Tool:      tool,
+		Dashboard: *report,
+		Widgets:   widgets,
+	}
+	if version.Widgets == nil {
+		version.Widgets = []middleware.Widget{}
+	}
+
+	data, err := json.MarshalIndent(version, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dashboard version: %w", err)
+	}
+	if err := os.MkdirAll(h.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create dashboard history directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(h.dir, version.ID+".json"), data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to save dashboard version: %w", err)
+	}
+	return version, h.prune(report.ID, now)
+}
+
+// prune deletes the versions of a dashboard beyond the newest h.versions and
+// those older than h.maxAge.
+func (h *DashboardHistory) prune(dashboardID int, now time.Time) error {
+	versions, err := h.List(func(v *DashboardVersion) bool { return v.Dashboard.ID == dashboardID })
+	if err != nil {
+		return err
+	}
+	for i, version := range versions {
+		if i < h.versions && (h.maxAge == 0 || now.Sub(version.CreatedAt) <= h.maxAge) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(h.dir, version.ID+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete dashboard version %s: %w", version.ID, err)
+		}
+	}
+	return nil
+}
+
+// List returns the stored versions for which keep returns true, newest first.
+func (h *DashboardHistory) List(keep func(*DashboardVersion) bool) ([]*DashboardVersion, error) {
+	entries, err := os.ReadDir(h.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dashboard history: %w", err)
+	}
+
+	var versions []*DashboardVersion
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !versionIDPattern.MatchString(id) {
+			continue
+		}
+		version, err := h.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		if keep == nil || keep(version) {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].CreatedAt.After(versions[j].CreatedAt) })
+	return versions, nil
+}
+
+// Load returns the version with the given ID.
+func (h *DashboardHistory) Load(id string) (*DashboardVersion, error) {
+	if !versionIDPattern.MatchString(id) {
+		return nil, invalidInput("invalid version ID %q", id)
+	}
+	data, err := os.ReadFile(filepath.Join(h.dir, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("dashboard version %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dashboard version %s: %w", id, err)
+	}
+	var version DashboardVersion
+	if err := json.Unmarshal(data, &version); err != nil {
+		return nil, fmt.Errorf("failed to parse dashboard version %s: %w", id, err)
+	}
+	return &version, nil
+}
+
+// SnapshotTools lists the tools whose calls save the dashboards they change to
+// the history first. bulk_dashboards only knows its selection once it runs, so
+// it snapshots the dashboards it changes itself.
+var SnapshotTools = []string{
+	"update_dashboard", "delete_dashboard", "create_widget", "update_widget", "delete_widget",
+	"update_widget_layouts", "import_dashboard", "restore_dashboard_version",
+}
+
+// ConfirmedSnapshotTools lists the SnapshotTools that may ask the user to
+// confirm. Their handlers call SnapshotDashboards once the call is confirmed,
+// so a declined call leaves the history alone.
+var ConfirmedSnapshotTools = []string{"delete_dashboard", "delete_widget", "import_dashboard"}
+
+// SnapshotDashboards saves the dashboards a mutating tool call is about to
+// change to the local history. It returns an error, and the call must not go
+// ahead, if the history is enabled and the dashboards can't be found. A
+// snapshot that fails to save is logged and doesn't stop the call.
+func SnapshotDashboards(ctx context.Context, s ServerInterface, req mcp.CallToolRequest) error {
+	client := s.Client()
+	warn := func(err error) {
+		client.Log(ctx, middleware.LogLevelWarning, "failed to snapshot dashboard before tool call", map[string]any{"tool": req.Params.Name, "error": err.Error()})
+	}
+	history, err := dashboardHistory(s, req)
+	if err != nil {
+		warn(err)
+		return nil
+	}
+	if history == nil {
+		return nil
+	}
+
+	reports, err := affectedDashboards(ctx, client, history, req)
+	if err != nil {
+		return fmt.Errorf("failed to find the dashboard to save to the history: %w", err)
+	}
+	for _, report := range reports {
+		widgets, err := client.GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: report.ID})
+		if err != nil {
+			warn(fmt.Errorf("failed to get widgets of dashboard %d: %w", report.ID, err))
+			continue
+		}
+		if _, err := history.Save(report, widgets, req.Params.Name); err != nil {
+			warn(err)
+		}
+	}
+	return nil
+}
+
+// affectedDashboards returns the dashboards a tool call changes, as far as its
+// arguments tell.
+func affectedDashboards(ctx context.Context, client *middleware.Client, history *DashboardHistory, req mcp.CallToolRequest) ([]*middleware.Report, error) {
+	var ids []int
+	var keys []string
+	switch req.Params.Name {
+	case "update_dashboard", "delete_dashboard":
+		ids = append(ids, req.GetInt("id", 0))
+	case "create_widget", "update_widget", "delete_widget":
+		if id := req.GetInt("report_id", 0); id > 0 {
+			ids = append(ids, id)
+		} else if key := req.GetString("report_key", ""); key != "" {
+			keys = append(keys, key)
+		} else if builderID := req.GetInt("builder_id", 0); builderID > 0 {
+			found, err := widgetDashboards(ctx, client, func(w middleware.Widget) bool { return w.ID == builderID })
+			if err != nil {
+				return nil, err
+			}
+			if len(found) == 0 {
+				return nil, fmt.Errorf("widget %d is not on any dashboard", builderID)
+			}
+			ids = append(ids, found...)
+		}
+		// A new widget without a dashboard ID or key goes on a new dashboard.
+	case "update_widget_layouts":
+		scopes := make(map[int]bool)
+		layouts, _ := req.GetArguments()["layouts"].([]any)
+		for _, item := range layouts {
+			layout, _ := item.(map[string]any)
+			if scopeID, ok := layout["scope_id"].(float64); ok && scopeID > 0 {
+				scopes[int(scopeID)] = true
+			}
+		}
+		if len(scopes) == 0 {
+			break
+		}
+		found, err := widgetDashboards(ctx, client, func(w middleware.Widget) bool { return w.Scope != nil && scopes[w.Scope.ID] })
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("no dashboard has the widgets of the layouts")
+		}
+		ids = append(ids, found...)
+	case "import_dashboard":
+		if doc, ok := req.GetArguments()["document"].(map[string]any); ok {
+			if key, ok := doc["key"].(string); ok && key != "" {
+				keys = append(keys, key)
+			}
+		}
+	case "restore_dashboard_version":
+		if version, err := history.Load(req.GetString("version_id", "")); err == nil && version.Dashboard.Key != "" {
+			keys = append(keys, version.Dashboard.Key)
+		}
+	}
+
+	var reports []*middleware.Report
+	for _, id := range ids {
+		if id <= 0 {
+			continue
+		}
+		report, err := findDashboardByID(ctx, client, id)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	for _, key := range keys {
+		report, err := getDashboardByKey(ctx, client, key)
+		if err != nil {
+			return nil, err
+		}
+		// Nothing to snapshot for a dashboard that doesn't exist yet.
+		if report != nil {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+// widgetDashboards returns the IDs of the dashboards holding the widgets for
+// which match returns true. The API doesn't look widgets up by ID, so this lists
+// every widget, once per call.
+func widgetDashboards(ctx context.Context, client *middleware.Client, match func(middleware.Widget) bool) ([]int, error) {
+	widgets, err := client.GetWidgets(ctx, &middleware.GetWidgetsParams{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get widgets: %w", err)
+	}
+	seen := make(map[int]bool)
+	var ids []int
+	for _, widget := range widgets {
+		if widget.Scope == nil || widget.Scope.ReportID == 0 || seen[widget.Scope.ReportID] || !match(widget) {
+			continue
+		}
+		seen[widget.Scope.ReportID] = true
+		ids = append(ids, widget.Scope.ReportID)
+	}
+	return ids, nil
+}
+
+func NewListDashboardVersionsTool() mcp.Tool {
+	return mcp.NewTool(
+		"list_dashboard_versions",
+		mcp.WithDescription(`List the versions of dashboards kept in the local history, newest first.
+
+Before every tool call that changes a dashboard or its widgets (`+strings.Join(SnapshotTools, ", ")+`, and bulk_dashboards deletes and visibility changes), the server saves the dashboard and all its widgets as a version. Versions of deleted dashboards are kept too. Pass a version's ID to restore_dashboard_version to go back to it.`),
+		mcp.WithInputSchema[ListDashboardVersionsInput](),
+		mcp.WithOutputSchema[ListDashboardVersionsResult](),
+		mcp.WithTitleAnnotation("List Dashboard Versions"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(false),
+	)
+}
+
+type ListDashboardVersionsInput struct {
+	ReportKey   string `json:"report_key,omitempty" jsonschema:"Only list versions of the dashboard with this key"`
+	DashboardID int    `json:"dashboard_id,omitempty" jsonschema:"Only list versions of the dashboard with this ID"`
+	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of versions to return (default 20)"`
+}
+
+type ListDashboardVersionsResult struct {
+	Versions []DashboardVersionSummary `json:"versions" jsonschema:"The versions, newest first"`
+	Total    int                       `json:"total" jsonschema:"Number of matching versions in the history"`
+}
+
+// DashboardVersionSummary describes a version in list_dashboard_versions.
+type DashboardVersionSummary struct {
+	ID          string `json:"id" jsonschema:"ID of the version, for restore_dashboard_version"`
+	CreatedAt   string `json:"created_at" jsonschema:"When the version was saved"`
+	Tool        string `json:"tool" jsonschema:"The tool call the version was saved before"`
+	DashboardID int    `json:"dashboard_id" jsonschema:"ID of the dashboard"`
+	Key         string `json:"key" jsonschema:"Key of the dashboard"`
+	Label       string `json:"label" jsonschema:"Name of the dashboard"`
+	Widgets     int    `json:"widgets" jsonschema:"Number of widgets in the version"`
+}
+
+func HandleListDashboardVersions(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := ParseInput[ListDashboardVersionsInput](req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+	if input.Limit <= 0 {
+		input.Limit = 20
+	}
+
+	history, err := requireDashboardHistory(s, req)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := history.List(func(v *DashboardVersion) bool {
+		return (input.ReportKey == "" || v.Dashboard.Key == input.ReportKey) &&
+			(input.DashboardID == 0 || v.Dashboard.ID == input.DashboardID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ListDashboardVersionsResult{Versions: []DashboardVersionSummary{}, Total: len(versions)}
+	for i, version := range versions {
+		if i == input.Limit {
+			break
+		}
+		result.Versions = append(result.Versions, DashboardVersionSummary{
+			ID:          version.ID,
+			CreatedAt:   version.CreatedAt.Format(time.RFC3339),
+			Tool:        version.Tool,
+			DashboardID: version.Dashboard.ID,
+			Key:         version.Dashboard.Key,
+			Label:       version.Dashboard.Label,
+			Widgets:     len(version.Widgets),
+		})
+	}
+	return ToStructuredResult(result)
+}
+
+func requireDashboardHistory(s ServerInterface, req mcp.CallToolRequest) (*DashboardHistory, error) {
+	history, err := dashboardHistory(s, req)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		return nil, fmt.Errorf("dashboard history is disabled (set DASHBOARD_HISTORY_VERSIONS and DASHBOARD_HISTORY_DIR to enable it)")
+	}
+	return history, nil
+}
+
+func NewRestoreDashboardVersionTool() mcp.Tool {
+	return mcp.NewTool(
+		"restore_dashboard_version",
+		mcp.WithDescription(`Restore a dashboard to a version from list_dashboard_versions.
+
+If the dashboard was deleted, it is recreated with all the widgets of the version. Otherwise its settings are rolled back, deleted widgets are recreated, and widgets whose config, type, title or layout changed are reverted. Widgets added since the version are kept unless remove_new_widgets is set. The current state is saved as a version first, so a restore can be undone.
+
+Use dry_run to see what the restore would change without changing anything.`),
+		mcp.WithInputSchema[RestoreDashboardVersionInput](),
+		mcp.WithOutputSchema[RestoreDashboardVersionResult](),
+		mcp.WithTitleAnnotation("Restore Dashboard Version"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
+	)
+}
+
+type RestoreDashboardVersionInput struct {
+	VersionID        string `json:"version_id" jsonschema:"ID of the version to restore, as returned by list_dashboard_versions,required"`
+	RemoveNewWidgets bool   `json:"remove_new_widgets,omitempty" jsonschema:"Delete the widgets added to the dashboard since the version"`
+	DryRun           bool   `json:"dry_run,omitempty" jsonschema:"Report what the restore would change without changing anything"`
+}
+
+// RestoreDashboardVersionResult describes a restore, or with dry_run what it
+// would do.
+type RestoreDashboardVersionResult struct {
+	DryRun      bool     `json:"dry_run" jsonschema:"Whether nothing was changed"`
+	VersionID   string   `json:"version_id" jsonschema:"ID of the restored version"`
+	Action      string   `json:"action" jsonschema:"recreate for a deleted dashboard, restore for an existing one, none if it already matches the version"`
+	DashboardID int      `json:"dashboard_id,omitempty" jsonschema:"ID of the restored dashboard"`
+	Key         string   `json:"key" jsonschema:"Key of the restored dashboard"`
+	Label       string   `json:"label" jsonschema:"Name of the restored dashboard"`
+	Settings    []string `json:"settings,omitempty" jsonschema:"Dashboard settings rolled back"`
+	Recreated   []string `json:"recreated,omitempty" jsonschema:"Titles of the widgets recreated"`
+	Reverted    []string `json:"reverted,omitempty" jsonschema:"Titles of the widgets whose config was rolled back"`
+	Moved       []string `json:"moved,omitempty" jsonschema:"Titles of the widgets moved back to their position"`
+	Removed     []string `json:"removed,omitempty" jsonschema:"Titles of the widgets added since the version and deleted"`
+	Kept        []string `json:"kept,omitempty" jsonschema:"Titles of the widgets added since the version and kept"`
+	Message     string   `json:"message" jsonschema:"Summary of the restore"`
+}
+
+func HandleRestoreDashboardVersion(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := ParseInput[RestoreDashboardVersionInput](req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+	history, err := requireDashboardHistory(s, req)
+	if err != nil {
+		return nil, err
+	}
+	version, err := history.Load(input.VersionID)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s.Client()
+	result := &RestoreDashboardVersionResult{
+		DryRun:    input.DryRun,
+		VersionID: version.ID,
+		Key:       version.Dashboard.Key,
+		Label:     version.Dashboard.Label,
+	}
+	current, err := getDashboardByKey(ctx, client, version.Dashboard.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up dashboard %s: %w", version.Dashboard.Key, err)
+	}
+
+	if current == nil {
+		if err := recreateDashboard(ctx, client, req, version, result); err != nil {
+			return nil, err
+		}
+	} else {
+		widgets, err := client.GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: current.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get widgets: %w", err)
+		}
+		restore := &dashboardRestore{client: client, version: version, current: current, widgets: widgets, result: result}
+		restore.plan(input.RemoveNewWidgets)
+		if !input.DryRun {
+			if err := restore.apply(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result.Message = restoreSummary(result)
+	return ToStructuredResult(result)
+}
+
+// recreateDashboard creates a deleted dashboard and the widgets of a version,
+// rolling back if that fails.
+func recreateDashboard(ctx context.Context, client *middleware.Client, req mcp.CallToolRequest, version *DashboardVersion, result *RestoreDashboardVersionResult) error {
+	result.Action = "recreate"
+	doc := newDashboardDocument(&version.Dashboard, version.Widgets)
+	// Restore the metadata as it was, account-specific fields included.
+	doc.MetaData = version.Dashboard.MetaData
+	for _, widget := range doc.Widgets {
+		result.Recreated = append(result.Recreated, widget.Label)
+	}
+	if result.DryRun {
+		return nil
+	}
+
+	widgets, imported, err := importWidgets(doc, true)
+	if err != nil {
+		return err
+	}
+	imp := &dashboardImport{
+		client: client,
+		result: &ImportDashboardResult{Action: "create", Key: doc.Key, Label: doc.Label, Widgets: imported},
+	}
+	if err := imp.run(ctx, req, doc, nil, widgets, nil); err != nil {
+		return err
+	}
+	result.DashboardID = imp.result.DashboardID
+	result.Key = imp.result.Key
+	return nil
+}
+
+// dashboardRestore rolls an existing dashboard back to a version.
+type dashboardRestore struct {
+	client  *middleware.Client
+	version *DashboardVersion
+	current *middleware.Report
+	widgets []middleware.Widget
+	result  *RestoreDashboardVersionResult
+
+	settings  bool
+	recreate  []DashboardDocWidget
+	revert    []restoredWidget
+	layouts   []middleware.LayoutItem
+	deletions []middleware.Widget
+}
+
+// restoredWidget is a widget whose config is rolled back.
+type restoredWidget struct {
+	current *middleware.Widget
+	desired DashboardDocWidget
+}
+
+// plan works out what the restore changes and records it in the result.
+func (r *dashboardRestore) plan(removeNew bool) {
+	result := r.result
+	result.Action = "restore"
+	result.DashboardID = r.current.ID
+	result.Key = r.current.Key
+
+	before := newDashboardDocument(r.current, nil)
+	after := newDashboardDocument(&r.version.Dashboard, nil)
+	for _, change := range diffDocuments(before, after).Settings {
+		result.Settings = append(result.Settings, change.Field)
+	}
+	r.settings = len(result.Settings) > 0
+
+	current := make([]DashboardDocWidget, len(r.widgets))
+	for i := range r.widgets {
+		current[i] = newDashboardDocWidget(r.widgets[i])
+	}
+	desired := make([]DashboardDocWidget, len(r.version.Widgets))
+	for i := range r.version.Widgets {
+		desired[i] = newDashboardDocWidget(r.version.Widgets[i])
+	}
+
+	pairs, added, removed := matchWidgets(current, desired)
+	for _, j := range added {
+		r.recreate = append(r.recreate, desired[j])
+		result.Recreated = append(result.Recreated, desired[j].Label)
+	}
+	for _, pair := range pairs {
+		widget := &r.widgets[pair[0]]
+		diff, changed := diffWidgets(current[pair[0]], desired[pair[1]])
+		if !changed {
+			continue
+		}
+		if diff.Layout != nil && desired[pair[1]].Layout != nil && widget.Scope != nil {
+			layout := documentLayout(desired[pair[1]].Layout)
+			layout.ScopeID = widget.Scope.ID
+			r.layouts = append(r.layouts, layout)
+			result.Moved = append(result.Moved, desired[pair[1]].Label)
+		}
+		diff.Layout = nil
+		if diff.LabelFrom != "" || diff.ChartType != nil || diff.Columns != nil || diff.GroupBy != nil || len(diff.Filters) > 0 || len(diff.Config) > 0 {
+			r.revert = append(r.revert, restoredWidget{current: widget, desired: desired[pair[1]]})
+			result.Reverted = append(result.Reverted, desired[pair[1]].Label)
+		}
+	}
+	for _, i := range removed {
+		if removeNew {
+			r.deletions = append(r.deletions, r.widgets[i])
+			result.Removed = append(result.Removed, r.widgets[i].Label)
+		} else {
+			result.Kept = append(result.Kept, r.widgets[i].Label)
+		}
+	}
+
+	if !r.settings && len(r.recreate)+len(r.revert)+len(r.layouts)+len(r.deletions) == 0 {
+		result.Action = "none"
+	}
+}
+
+// apply makes the changes plan worked out.
+func (r *dashboardRestore) apply(ctx context.Context) error {
+	report := r.version.Dashboard
+	if r.settings {
+		upsert := &middleware.UpsertReportRequest{
+			ID:           r.current.ID,
+			Key:          r.current.Key,
+			Label:        report.Label,
+			Description:  report.Description,
+			DisplayScope: report.DisplayScope,
+			Visibility:   report.Visibility,
+			MetaData:     report.MetaData,
+		}
+		if _, err := r.client.UpdateDashboard(ctx, r.current.ID, upsert); err != nil {
+			return fmt.Errorf("failed to update dashboard: %w", err)
+		}
+	}
+
+	reportView := &middleware.ReportView{ReportID: r.current.ID, ReportKey: r.current.Key, ReportName: report.Label}
+	var unscoped []int
+	for _, desired := range r.recreate {
+		widget, err := customWidget(desired)
+		if err != nil {
+			return err
+		}
+		if widget.Key == "" {
+			widget.Key = generateWidgetKey(widget.Label)
+		}
+		widget.BuilderViewOptions.Report = reportView
+		created, err := r.client.CreateWidget(ctx, widget)
+		if err != nil {
+			return fmt.Errorf("failed to recreate widget %q: %w", widget.Label, err)
+		}
+		if desired.Layout == nil {
+			continue
+		}
+		layout := documentLayout(desired.Layout)
+		if created.Scope != nil && created.Scope.ID != 0 {
+			layout.ScopeID = created.Scope.ID
+		} else {
+			unscoped = append(unscoped, created.ID)
+		}
+		r.layouts = append(r.layouts, layout)
+	}
+
+	for _, restored := range r.revert {
+		desired := restored.desired
+		if desired.Key == "" {
+			desired.Key = restored.current.Key
+		}
+		widget, err := customWidget(desired)
+		if err != nil {
+			return err
+		}
+		widget.BuilderID = restored.current.ID
+		widget.ScopeID = 0
+		widget.Layout = nil
+		widget.BuilderViewOptions.Report = reportView
+		if _, err := r.client.UpdateWidget(ctx, widget); err != nil {
+			return fmt.Errorf("failed to revert widget %q: %w", widget.Label, err)
+		}
+	}
+
+	for _, widget := range r.deletions {
+		if err := r.client.DeleteWidget(ctx, widget.ID); err != nil {
+			return fmt.Errorf("failed to delete widget %q: %w", widget.Label, err)
+		}
+	}
+
+	if len(unscoped) > 0 {
+		if err := fillScopeIDs(ctx, r.client, r.current.ID, unscoped, r.layouts); err != nil {
+			return err
+		}
+	}
+	if len(r.layouts) > 0 {
+		if err := r.client.UpdateWidgetLayouts(ctx, &middleware.LayoutRequest{Layouts: r.layouts}); err != nil {
+			return fmt.Errorf("failed to update widget layouts: %w", err)
+		}
+	}
+	return nil
+}
+
+func restoreSummary(result *RestoreDashboardVersionResult) string {
+	switch result.Action {
+	case "recreate":
+		verb := "Recreated"
+		if result.DryRun {
+			verb = "Would recreate"
+		}
+		return fmt.Sprintf("%s dashboard %s (%q) with %d widgets from version %s", verb, result.Key, result.Label, len(result.Recreated), result.VersionID)
+	case "none":
+		return fmt.Sprintf("Dashboard %s already matches version %s", result.Key, result.VersionID)
+	}
+
+	var parts []string
+	count := func(n int, what string) {
+		if n > 0 {
+			parts = append(parts, strconv.Itoa(n)+" "+what)
+		}
+	}
+	count(len(result.Settings), "settings rolled back")
+	count(len(result.Recreated), "widgets recreated")
+	count(len(result.Reverted), "widgets reverted")
+	count(len(result.Moved), "widgets moved")
+	count(len(result.Removed), "new widgets deleted")
+	count(len(result.Kept), "new widgets kept")
+
+	verb := "Restored"
+	if result.DryRun {
+		verb = "Would restore"
+	}
+	return fmt.Sprintf("%s dashboard %s to version %s: %s", verb, result.Key, result.VersionID, strings.Join(parts, ", "))
+}
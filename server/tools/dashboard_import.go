@@ -0,0 +1,442 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"mcp-middleware/middleware"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Import conflict policies, applied when a dashboard with the document's key
+// already exists.
+const (
+	ConflictSkip      = "skip"
+	ConflictRename    = "rename"
+	ConflictOverwrite = "overwrite"
+)
+
+// maxRenameAttempts bounds the search for a free key with on_conflict=rename.
+const maxRenameAttempts = 100
+
+func NewImportDashboardTool() mcp.Tool {
+	return mcp.NewTool(
+		"import_dashboard",
+		mcp.WithDescription(`Import a dashboard document written by export_dashboard, recreating the dashboard, its widgets and their layout.
+
+The dashboard is created first, then each widget on it, and finally the widgets are moved to their positions in the document. If any step fails, the widgets and the dashboard created so far are deleted again.
+
+When a dashboard with the document's key already exists, on_conflict decides what happens:
+- skip (default): leave the existing dashboard alone and import nothing
+- rename: import the document as a new dashboard under the first free key of the form <key>-2, <key>-3, ...
+- overwrite: replace the existing dashboard's settings and widgets with the document's. The new widgets are created before the old ones are deleted
+
+Use dry_run to see what the import would do without changing anything.`),
+		mcp.WithInputSchema[ImportDashboardInput](),
+		mcp.WithOutputSchema[ImportDashboardResult](),
+		mcp.WithTitleAnnotation("Import Dashboard"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithOpenWorldHintAnnotation(true),
+	)
+}
+
+type ImportDashboardInput struct {
+	Document   DashboardDocument `json:"document" jsonschema:"The dashboard document, as returned by export_dashboard,required"`
+	OnConflict string            `json:"on_conflict,omitempty" jsonschema:"What to do when a dashboard with the document's key exists: skip (default), rename or overwrite,enum=skip,enum=rename,enum=overwrite"`
+	DryRun     bool              `json:"dry_run,omitempty" jsonschema:"Report what the import would do without changing anything"`
+}
+
+// ImportDashboardResult describes an import, or with dry_run what it would do.
+type ImportDashboardResult struct {
+	DryRun          bool             `json:"dry_run" jsonschema:"Whether nothing was changed"`
+	Action          string           `json:"action" jsonschema:"What the import did: create, skip, rename or overwrite"`
+	DashboardID     int              `json:"dashboard_id,omitempty" jsonschema:"ID of the imported dashboard"`
+	Key             string           `json:"key,omitempty" jsonschema:"Key of the imported dashboard"`
+	Label           string           `json:"label" jsonschema:"Name of the imported dashboard"`
+	Widgets         []ImportedWidget `json:"widgets" jsonschema:"The widgets created by the import"`
+	ReplacedWidgets int              `json:"replaced_widgets,omitempty" jsonschema:"Number of existing widgets deleted by on_conflict=overwrite"`
+	Message         string           `json:"message" jsonschema:"Summary of the import"`
+}
+
+// ImportedWidget is a widget created by an import.
+type ImportedWidget struct {
+	Key        string `json:"key" jsonschema:"Key of the widget"`
+	Label      string `json:"label" jsonschema:"Title of the widget"`
+	WidgetType string `json:"widget_type,omitempty" jsonschema:"Type of visualization"`
+	BuilderID  int    `json:"builder_id,omitempty" jsonschema:"ID of the created widget"`
+	ScopeID    int    `json:"scope_id,omitempty" jsonschema:"Scope ID of the widget on the dashboard"`
+}
+
+func HandleImportDashboard(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := ParseInput[ImportDashboardInput](req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+	if input.OnConflict == "" {
+		input.OnConflict = ConflictSkip
+	}
+	if input.OnConflict != ConflictSkip && input.OnConflict != ConflictRename && input.OnConflict != ConflictOverwrite {
+		return nil, invalidInput("invalid on_conflict %q (must be skip, rename or overwrite)", input.OnConflict)
+	}
+
+	doc := &input.Document
+	if err := validateDocument(doc); err != nil {
+		return nil, err
+	}
+	if doc.Visibility == "" {
+		doc.Visibility = "private"
+	}
+
+	client := s.Client()
+	result := &ImportDashboardResult{DryRun: input.DryRun, Action: "create", Key: doc.Key, Label: doc.Label}
+
+	var existing *middleware.Report
+	if doc.Key != "" {
+		if existing, err = getDashboardByKey(ctx, client, doc.Key); err != nil {
+			return nil, fmt.Errorf("failed to look up dashboard %s: %w", doc.Key, err)
+		}
+	}
+
+	var replaced []middleware.Widget
+	if existing != nil {
+		result.Action = input.OnConflict
+		switch input.OnConflict {
+		case ConflictSkip:
+			result.DashboardID = existing.ID
+			result.Widgets = []ImportedWidget{}
+			result.Message = fmt.Sprintf("Dashboard %s already exists (ID %d), skipped", doc.Key, existing.ID)
+			return ToStructuredResult(result)
+		case ConflictRename:
+			key, suffix, err := freeDashboardKey(ctx, client, doc.Key)
+			if err != nil {
+				return nil, err
+			}
+			result.Key = key
+			result.Label = fmt.Sprintf("%s (%d)", doc.Label, suffix)
+			// The renamed copy is a new dashboard; the existing one is left alone.
+			existing = nil
+		case ConflictOverwrite:
+			result.DashboardID = existing.ID
+			if replaced, err = client.GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: existing.ID}); err != nil {
+				return nil, fmt.Errorf("failed to get widgets: %w", err)
+			}
+			result.ReplacedWidgets = len(replaced)
+		}
+	}
+
+	// Widget keys are kept when the dashboard is new to the account. Renamed
+	// and overwritten dashboards get new ones, since the originals may still
+	// be in use.
+	widgets, imported, err := importWidgets(doc, result.Action == "create")
+	if err != nil {
+		return nil, err
+	}
+	result.Widgets = imported
+
+	if input.DryRun {
+		result.Message = importSummary(result, true)
+		return ToStructuredResult(result)
+	}
+
+	if result.Action == ConflictOverwrite {
+		confirm, err := shouldConfirm(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		if confirm {
+			message := fmt.Sprintf("Replace dashboard %q (ID %d) and its %d widgets with the imported document? This cannot be undone.", existing.Label, existing.ID, len(replaced))
+			confirmed, err := requestConfirmation(ctx, message)
+			if err != nil {
+				return nil, err
+			}
+			if !confirmed {
+				result.Widgets = []ImportedWidget{}
+				result.ReplacedWidgets = 0
+				result.Message = "Dashboard import cancelled by user"
+				return ToStructuredResult(result)
+			}
+		}
+	}
+
+	if err := SnapshotDashboards(ctx, s, req); err != nil {
+		return nil, err
+	}
+
+	imp := &dashboardImport{client: client, result: result}
+	if err := imp.run(ctx, req, doc, existing, widgets, replaced); err != nil {
+		return nil, err
+	}
+	result.Message = importSummary(result, false)
+	return ToStructuredResult(result)
+}
+
+// dashboardImport creates the objects of an import and keeps track of them to
+// roll the import back.
+type dashboardImport struct {
+	client *middleware.Client
+	result *ImportDashboardResult
+
+	createdDashboard bool
+	createdWidgets   []int
+}
+
+func (imp *dashboardImport) run(ctx context.Context, req mcp.CallToolRequest, doc *DashboardDocument, existing *middleware.Report, widgets []*middleware.CustomWidget, replaced []middleware.Widget) error {
+	result := imp.result
+	upsert := &middleware.UpsertReportRequest{
+		Key:          result.Key,
+		Label:        result.Label,
+		Description:  doc.Description,
+		DisplayScope: doc.DisplayScope,
+		Visibility:   doc.Visibility,
+		MetaData:     doc.MetaData,
+	}
+
+	report := existing
+	if report == nil {
+		created, err := imp.client.CreateDashboard(ctx, upsert)
+		if err != nil {
+			return fmt.Errorf("failed to create dashboard: %w", err)
+		}
+		report = created
+		imp.createdDashboard = true
+		result.DashboardID = report.ID
+		if report.Key != "" {
+			result.Key = report.Key
+		}
+	}
+
+	progress := NewProgress(ctx, req, len(widgets))
+	for i, widget := range widgets {
+		widget.BuilderViewOptions.Report = &middleware.ReportView{
+			ReportID:   report.ID,
+			ReportKey:  result.Key,
+			ReportName: result.Label,
+		}
+		created, err := imp.client.CreateWidget(ctx, widget)
+		if err != nil {
+			return imp.rollback(ctx, fmt.Errorf("failed to create widget %q: %w", widget.Label, err))
+		}
+		imp.createdWidgets = append(imp.createdWidgets, created.ID)
+		result.Widgets[i].BuilderID = created.ID
+		if created.Scope != nil {
+			result.Widgets[i].ScopeID = created.Scope.ID
+		}
+		progress.Step(fmt.Sprintf("created widget %d/%d", i+1, len(widgets)))
+	}
+
+	if err := imp.restoreLayouts(ctx, report.ID, doc, widgets); err != nil {
+		return imp.rollback(ctx, err)
+	}
+
+	if existing == nil {
+		return nil
+	}
+	upsert.ID = existing.ID
+	upsert.Key = existing.Key
+	if _, err := imp.client.UpdateDashboard(ctx, existing.ID, upsert); err != nil {
+		return imp.rollback(ctx, fmt.Errorf("failed to update dashboard: %w", err))
+	}
+	for _, widget := range replaced {
+		if err := imp.client.DeleteWidget(ctx, widget.ID); err != nil {
+			return fmt.Errorf("imported the new widgets but failed to delete replaced widget %d: %w", widget.ID, err)
+		}
+	}
+	return nil
+}
+
+// restoreLayouts moves the created widgets to their positions in the document.
+// Scope IDs the create responses didn't include are looked up on the dashboard.
+func (imp *dashboardImport) restoreLayouts(ctx context.Context, reportID int, doc *DashboardDocument, widgets []*middleware.CustomWidget) error {
+	imported := imp.result.Widgets
+	for i := range imported {
+		if imported[i].ScopeID == 0 {
+			if err := imp.lookUpScopeIDs(ctx, reportID); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	var layouts []middleware.LayoutItem
+	for i, docWidget := range doc.Widgets {
+		if docWidget.Layout == nil || imported[i].ScopeID == 0 {
+			continue
+		}
+		layouts = append(layouts, middleware.LayoutItem{
+			X:       docWidget.Layout.X,
+			Y:       docWidget.Layout.Y,
+			W:       docWidget.Layout.W,
+			H:       docWidget.Layout.H,
+			ScopeID: imported[i].ScopeID,
+		})
+	}
+	if len(layouts) == 0 {
+		return nil
+	}
+	if err := imp.client.UpdateWidgetLayouts(ctx, &middleware.LayoutRequest{Layouts: layouts}); err != nil {
+		return fmt.Errorf("failed to update widget layouts: %w", err)
+	}
+	return nil
+}
+
+func (imp *dashboardImport) lookUpScopeIDs(ctx context.Context, reportID int) error {
+	onDashboard, err := imp.client.GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: reportID})
+	if err != nil {
+		return fmt.Errorf("failed to get widgets: %w", err)
+	}
+	scopes := make(map[int]int, len(onDashboard))
+	for _, widget := range onDashboard {
+		if widget.Scope != nil {
+			scopes[widget.ID] = widget.Scope.ID
+		}
+	}
+	for i := range imp.result.Widgets {
+		if imp.result.Widgets[i].ScopeID == 0 {
+			imp.result.Widgets[i].ScopeID = scopes[imp.result.Widgets[i].BuilderID]
+		}
+	}
+	return nil
+}
+
+// rollback deletes the widgets and the dashboard created so far and returns
+// cause, noting anything that couldn't be deleted.
+func (imp *dashboardImport) rollback(ctx context.Context, cause error) error {
+	var leftover []string
+	for _, id := range imp.createdWidgets {
+		if err := imp.client.DeleteWidget(ctx, id); err != nil {
+			leftover = append(leftover, fmt.Sprintf("widget %d", id))
+		}
+	}
+	if imp.createdDashboard {
+		if err := imp.client.DeleteDashboard(ctx, imp.result.DashboardID); err != nil {
+			leftover = append(leftover, fmt.Sprintf("dashboard %d", imp.result.DashboardID))
+		}
+	}
+
+	if len(leftover) > 0 {
+		return fmt.Errorf("%w (rollback failed, delete %s manually)", cause, strings.Join(leftover, ", "))
+	}
+	return fmt.Errorf("%w (import rolled back)", cause)
+}
+
+func validateDocument(doc *DashboardDocument) error {
+	if doc.Version < 1 || doc.Version > DashboardDocumentVersion {
+		return invalidInput("unsupported dashboard document version %d (must be between 1 and %d)", doc.Version, DashboardDocumentVersion)
+	}
+	if doc.Label == "" {
+		return invalidInput("invalid dashboard document: label is required")
+	}
+	for i, widget := range doc.Widgets {
+		if widget.Label == "" {
+			return invalidInput("invalid dashboard document: widget %d has no label", i)
+		}
+		if widget.WidgetType != "" {
+			if _, ok := widgetTypeIDs[widget.WidgetType]; !ok {
+				return invalidInput("invalid dashboard document: widget %q has unknown type %q", widget.Label, widget.WidgetType)
+			}
+		}
+	}
+	return nil
+}
+
+// importWidgets converts the widgets of doc into the requests that create
+// them. Widgets without a key, and all widgets unless keepKeys is set, get a
+// new key.
+func importWidgets(doc *DashboardDocument, keepKeys bool) ([]*middleware.CustomWidget, []ImportedWidget, error) {
+	widgets := make([]*middleware.CustomWidget, len(doc.Widgets))
+	imported := make([]ImportedWidget, len(doc.Widgets))
+	for i, docWidget := range doc.Widgets {
+		widget, err := customWidget(docWidget)
+		if err != nil {
+			return nil, nil, err
+		}
+		if widget.Key == "" || !keepKeys {
+			widget.Key = generateWidgetKey(widget.Label)
+		}
+		widgets[i] = widget
+		imported[i] = ImportedWidget{Key: widget.Key, Label: widget.Label, WidgetType: docWidget.WidgetType}
+	}
+	return widgets, imported, nil
+}
+
+// customWidget converts a document widget into the request that creates it.
+func customWidget(docWidget DashboardDocWidget) (*middleware.CustomWidget, error) {
+	widget := &middleware.CustomWidget{}
+	if docWidget.Config != nil {
+		data, err := json.Marshal(docWidget.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal config of widget %q: %w", docWidget.Label, err)
+		}
+		if err := json.Unmarshal(data, widget); err != nil {
+			return nil, fmt.Errorf("invalid config of widget %q: %w", docWidget.Label, err)
+		}
+	}
+
+	widget.Key = docWidget.Key
+	widget.Label = docWidget.Label
+	widget.WidgetAppID = docWidget.WidgetAppID
+	if docWidget.WidgetType != "" {
+		widget.WidgetAppID = getWidgetAppID(docWidget.WidgetType)
+	}
+	if widget.WidgetAppID == 0 {
+		widget.WidgetAppID = getWidgetAppID("time_series_chart")
+	}
+	if widget.BuilderViewOptions == nil {
+		widget.BuilderViewOptions = &middleware.BuilderViewOptions{}
+	}
+	if docWidget.Layout != nil {
+		widget.Layout = &middleware.LayoutItem{X: docWidget.Layout.X, Y: docWidget.Layout.Y, W: docWidget.Layout.W, H: docWidget.Layout.H}
+	}
+
+	// New widgets, as create_widget sends them.
+	widget.BuilderID = -1
+	widget.ScopeID = -1
+	widget.IsClone = false
+	if widget.Category == "" {
+		widget.Category = "Metrics"
+	}
+	if widget.Formulas == nil {
+		widget.Formulas = []any{}
+	}
+	return widget, nil
+}
+
+// freeDashboardKey returns the first of key-2, key-3, ... no dashboard uses,
+// and its suffix.
+func freeDashboardKey(ctx context.Context, client *middleware.Client, key string) (string, int, error) {
+	for suffix := 2; suffix < maxRenameAttempts+2; suffix++ {
+		candidate := fmt.Sprintf("%s-%d", key, suffix)
+		existing, err := getDashboardByKey(ctx, client, candidate)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to look up dashboard %s: %w", candidate, err)
+		}
+		if existing == nil {
+			return candidate, suffix, nil
+		}
+	}
+	return "", 0, fmt.Errorf("failed to find a free key for dashboard %s after %d attempts", key, maxRenameAttempts)
+}
+
+func importSummary(result *ImportDashboardResult, dryRun bool) string {
+	verb := "Created"
+	if result.Action == ConflictOverwrite {
+		verb = "Replaced"
+	}
+	if dryRun {
+		verb = "Would " + strings.ToLower(strings.TrimSuffix(verb, "d"))
+	}
+
+	summary := fmt.Sprintf("%s dashboard %s (%q) with %d widgets", verb, result.Key, result.Label, len(result.Widgets))
+	switch result.Action {
+	case ConflictOverwrite:
+		summary += fmt.Sprintf(", replacing its %d existing widgets", result.ReplacedWidgets)
+	case ConflictRename:
+		summary += ", since the document's key is taken"
+	}
+	return summary
+}
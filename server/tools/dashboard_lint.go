@@ -0,0 +1,281 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"mcp-middleware/middleware"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Severities of lint findings, from most to least severe.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+var severityOrder = map[string]int{SeverityError: 0, SeverityWarning: 1, SeverityInfo: 2}
+
+// alwaysKnownResources are sources that are valid without being listed by
+// get_resources, as the query tool treats logs.
+var alwaysKnownResources = map[string]bool{"log": true}
+
+func NewLintDashboardTool() mcp.Tool {
+	return mcp.NewTool(
+		"lint_dashboard",
+		mcp.WithDescription(`Check a dashboard for problems and suggest fixes.
+
+This tool loads every widget of the dashboard, fetches their data in one request, and reports:
+- Widgets whose query returns an error, or uses metrics with no data (not_available_metrics)
+- Widgets whose query returns no data
+- Widgets whose source is not a resource returned by get_resources
+- Widgets that overlap, are smaller than the 4x6 minimum, extend past the 12-column grid or have no position
+- Widgets with the same title
+
+Each finding has a severity (error, warning or info) and a suggested fix, usually a call to update_widget or update_widget_layouts. Queries referring to dashboard variables use their defaults unless variables are given. Set skip_data to check only layouts, titles and resources without fetching data.`),
+		mcp.WithInputSchema[LintDashboardInput](),
+		mcp.WithOutputSchema[LintDashboardResult](),
+		mcp.WithTitleAnnotation("Lint Dashboard"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
+	)
+}
+
+type LintDashboardInput struct {
+	ReportKey string            `json:"report_key" jsonschema:"The unique key of the dashboard to check,required"`
+	Variables map[string]string `json:"variables,omitempty" jsonschema:"Values of the dashboard's variables to check the queries with, instead of their defaults"`
+	SkipData  bool              `json:"skip_data,omitempty" jsonschema:"Don't fetch widget data; only check layouts, titles and resources"`
+}
+
+// LintDashboardResult is the result of lint_dashboard.
+type LintDashboardResult struct {
+	Key      string        `json:"key" jsonschema:"Key of the dashboard"`
+	Label    string        `json:"label" jsonschema:"Name of the dashboard"`
+	Widgets  int           `json:"widgets" jsonschema:"Number of widgets checked"`
+	Healthy  bool          `json:"healthy" jsonschema:"Whether there are no errors or warnings"`
+	Errors   int           `json:"errors" jsonschema:"Number of error findings"`
+	Warnings int           `json:"warnings" jsonschema:"Number of warning findings"`
+	Infos    int           `json:"infos" jsonschema:"Number of info findings"`
+	Findings []LintFinding `json:"findings" jsonschema:"The problems found, most severe first"`
+	Message  string        `json:"message" jsonschema:"Summary of the check"`
+}
+
+// LintFinding is a problem lint_dashboard found.
+type LintFinding struct {
+	Severity string `json:"severity" jsonschema:"error, warning or info"`
+	Check    string `json:"check" jsonschema:"The check that found the problem, such as empty_result or layout_overlap"`
+	WidgetID int    `json:"widget_id,omitempty" jsonschema:"Builder ID of the widget, if the finding is about one"`
+	Widget   string `json:"widget,omitempty" jsonschema:"Title of the widget"`
+	Message  string `json:"message" jsonschema:"What is wrong"`
+	Fix      string `json:"fix" jsonschema:"Suggested fix"`
+}
+
+func HandleLintDashboard(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := ParseInput[LintDashboardInput](req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	client := s.Client()
+	report, err := getDashboardByKey(ctx, client, input.ReportKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up dashboard %s: %w", input.ReportKey, err)
+	}
+	if report == nil {
+		return nil, fmt.Errorf("dashboard %s not found", input.ReportKey)
+	}
+	widgets, err := client.GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: report.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get widgets: %w", err)
+	}
+
+	lint := &dashboardLint{}
+	docs := make([]DashboardDocWidget, len(widgets))
+	for i := range widgets {
+		docs[i] = newDashboardDocWidget(widgets[i])
+	}
+
+	if !input.SkipData && len(widgets) > 0 {
+		vars, err := newWidgetVariables(report, widgets, input.Variables)
+		if err != nil {
+			return nil, err
+		}
+		data, err := fetchWidgetData(ctx, client, widgets, vars)
+		if err != nil {
+			return nil, err
+		}
+		for i, response := range data {
+			lint.checkData(widgets[i], response)
+		}
+	}
+
+	resources, err := client.GetResources(ctx)
+	if err != nil {
+		client.Log(ctx, middleware.LogLevelWarning, "failed to get resources, skipping the resource check", map[string]any{"error": err.Error()})
+		lint.add(SeverityInfo, "resources_unchecked", nil, "Could not get the resources to check widget sources against: "+err.Error(),
+			"Run lint_dashboard again, or check the sources against get_resources")
+	} else {
+		lint.checkResources(widgets, docs, resources)
+	}
+	lint.checkLayouts(widgets, docs)
+	lint.checkLabels(widgets)
+
+	result := lint.result(report, len(widgets))
+	return ToStructuredResult(result)
+}
+
+// dashboardLint collects the findings of lint_dashboard.
+type dashboardLint struct {
+	findings []LintFinding
+}
+
+func (l *dashboardLint) add(severity, check string, widget *middleware.Widget, message, fix string) {
+	finding := LintFinding{Severity: severity, Check: check, Message: message, Fix: fix}
+	if widget != nil {
+		finding.WidgetID = widget.ID
+		finding.Widget = widget.Label
+	}
+	l.findings = append(l.findings, finding)
+}
+
+// checkData reports errors, metrics with no data and empty results.
+func (l *dashboardLint) checkData(widget middleware.Widget, response middleware.BuilderDataResponse) {
+	summary := summarizeWidgetData(response)
+	if summary.Error != "" {
+		l.add(SeverityError, "data_error", &widget, "The query fails: "+summary.Error,
+			"Check the query with get_widget_data and correct its columns, source or filters with update_widget")
+	}
+	for _, metric := range summary.NotAvailableMetrics {
+		l.add(SeverityError, "metric_not_available", &widget, fmt.Sprintf("Metric %s has no data", metric),
+			fmt.Sprintf("Find the current name of %s with get_metrics and replace it with update_widget, or delete the widget", metric))
+	}
+	if summary.Empty && len(summary.NotAvailableMetrics) == 0 {
+		l.add(SeverityWarning, "empty_result", &widget, "The query returns no data",
+			"Check that the filters match existing data with get_widget_data, or that the source still reports data")
+	}
+}
+
+// checkResources reports widget sources get_resources doesn't list.
+func (l *dashboardLint) checkResources(widgets []middleware.Widget, docs []DashboardDocWidget, resources []string) {
+	known := make(map[string]bool, len(resources))
+	for _, resource := range resources {
+		known[resource] = true
+	}
+	for i, doc := range docs {
+		reported := make(map[string]bool)
+		for _, query := range builderQueries(doc.Config) {
+			source, _ := query["source"].(map[string]any)
+			name, _ := source["name"].(string)
+			if name == "" || known[name] || alwaysKnownResources[name] || reported[name] {
+				continue
+			}
+			reported[name] = true
+			fix := "Change the source with update_widget to a resource returned by get_resources"
+			if len(resources) > 0 {
+				fix += " (" + strings.Join(resources[:min(len(resources), 8)], ", ")
+				if len(resources) > 8 {
+					fix += ", ..."
+				}
+				fix += ")"
+			}
+			l.add(SeverityError, "unknown_resource", &widgets[i], fmt.Sprintf("Source %s is not an available resource", name), fix)
+		}
+	}
+}
+
+// checkLayouts reports widgets without a position, smaller than the minimum
+// size, past the edge of the grid, or overlapping another.
+func (l *dashboardLint) checkLayouts(widgets []middleware.Widget, docs []DashboardDocWidget) {
+	bottom := 0
+	for _, doc := range docs {
+		if doc.Layout != nil {
+			bottom = max(bottom, doc.Layout.Y+doc.Layout.H)
+		}
+	}
+
+	for i, doc := range docs {
+		layout := doc.Layout
+		if layout == nil {
+			l.add(SeverityInfo, "layout_missing", &widgets[i], "The widget has no position on the dashboard",
+				fmt.Sprintf("Place it with update_widget_layouts, for example at x=0, y=%d, w=%d, h=%d", bottom, gridColumns/2, minWidgetHeight))
+			continue
+		}
+		if layout.W < minWidgetWidth || layout.H < minWidgetHeight {
+			l.add(SeverityWarning, "layout_too_small", &widgets[i], fmt.Sprintf("The widget is %dx%d, below the %dx%d minimum", layout.W, layout.H, minWidgetWidth, minWidgetHeight),
+				fmt.Sprintf("Resize it with update_widget_layouts to w=%d, h=%d", max(layout.W, minWidgetWidth), max(layout.H, minWidgetHeight)))
+		}
+		if layout.X < 0 || layout.X+layout.W > gridColumns {
+			l.add(SeverityWarning, "layout_out_of_grid", &widgets[i], fmt.Sprintf("The widget at x=%d with w=%d extends past the %d-column grid", layout.X, layout.W, gridColumns),
+				fmt.Sprintf("Move or resize it with update_widget_layouts so that x+w is at most %d", gridColumns))
+		}
+	}
+
+	for i := range docs {
+		for j := i + 1; j < len(docs); j++ {
+			a, b := docs[i].Layout, docs[j].Layout
+			if a == nil || b == nil || !layoutsOverlap(a, b) {
+				continue
+			}
+			l.add(SeverityWarning, "layout_overlap", &widgets[j], fmt.Sprintf("The widget at %s overlaps %q at %s", b, widgets[i].Label, a),
+				fmt.Sprintf("Move it with update_widget_layouts, for example to x=%d, y=%d", b.X, bottom))
+			bottom += b.H
+		}
+	}
+}
+
+func layoutsOverlap(a, b *WidgetLayout) bool {
+	return a.X < b.X+b.W && b.X < a.X+a.W && a.Y < b.Y+b.H && b.Y < a.Y+a.H
+}
+
+// checkLabels reports widgets with the same title.
+func (l *dashboardLint) checkLabels(widgets []middleware.Widget) {
+	first := make(map[string]int)
+	for i, widget := range widgets {
+		label := strings.ToLower(strings.TrimSpace(widget.Label))
+		j, seen := first[label]
+		if !seen {
+			first[label] = i
+			continue
+		}
+		l.add(SeverityWarning, "duplicate_label", &widgets[i], fmt.Sprintf("Another widget (ID %d) has the title %q", widgets[j].ID, widgets[j].Label),
+			"Rename one of them with update_widget so that each title says what it shows, or delete the duplicate with delete_widget")
+	}
+}
+
+// result sorts the findings by severity, keeping the order they were found
+// in otherwise, and counts them.
+func (l *dashboardLint) result(report *middleware.Report, widgets int) *LintDashboardResult {
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		return severityOrder[l.findings[i].Severity] < severityOrder[l.findings[j].Severity]
+	})
+	result := &LintDashboardResult{Key: report.Key, Label: report.Label, Widgets: widgets, Findings: l.findings}
+	if result.Findings == nil {
+		result.Findings = []LintFinding{}
+	}
+	for _, finding := range l.findings {
+		switch finding.Severity {
+		case SeverityError:
+			result.Errors++
+		case SeverityWarning:
+			result.Warnings++
+		default:
+			result.Infos++
+		}
+	}
+	result.Healthy = result.Errors == 0 && result.Warnings == 0
+	if result.Healthy {
+		result.Message = fmt.Sprintf("Dashboard %s: %d widgets, no problems found", report.Key, widgets)
+	} else {
+		result.Message = fmt.Sprintf("Dashboard %s: %d widgets, %d errors, %d warnings", report.Key, widgets, result.Errors, result.Warnings)
+	}
+	if result.Infos > 0 {
+		result.Message += fmt.Sprintf(", %d notes", result.Infos)
+	}
+	return result
+}
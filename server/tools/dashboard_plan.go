@@ -0,0 +1,601 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"mcp-middleware/middleware"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// Dashboards applied from a directory are tagged with managedByValue under
+// managedByKey in their metadata.
+const (
+	managedByKey   = "managed_by"
+	managedByValue = "mcp-middleware"
+)
+
+// Actions of a DashboardPlanItem.
+const (
+	PlanCreate = "create"
+	PlanUpdate = "update"
+	PlanNone   = "none"
+	// PlanSkip is planned for an existing dashboard that isn't managed, unless
+	// the plan adopts unmanaged dashboards.
+	PlanSkip = "skip"
+)
+
+// DashboardPlan is the set of changes that brings the dashboards of an account
+// in line with a directory of dashboard documents.
+type DashboardPlan struct {
+	Dashboards []DashboardPlanItem `json:"dashboards"`
+	// Unlisted holds the keys of managed dashboards that have no document.
+	// They are reported but never deleted.
+	Unlisted []string `json:"unlisted,omitempty"`
+}
+
+// DashboardPlanItem is the change planned for one dashboard.
+type DashboardPlanItem struct {
+	Key         string          `json:"key"`
+	Label       string          `json:"label"`
+	File        string          `json:"file,omitempty"`
+	Action      string          `json:"action"`
+	DashboardID int             `json:"dashboard_id,omitempty"`
+	Settings    []SettingChange `json:"settings,omitempty"`
+	Added       []PlannedWidget `json:"added,omitempty"`
+	Changed     []PlannedWidget `json:"changed,omitempty"`
+	Removed     []PlannedWidget `json:"removed,omitempty"`
+	Moved       []PlannedWidget `json:"moved,omitempty"`
+
+	document *DashboardDocument
+	report   *middleware.Report
+}
+
+// SettingChange is a changed dashboard setting. From and To are empty for
+// metadata fields.
+type SettingChange struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// PlannedWidget is a widget a plan adds, changes, removes or moves.
+type PlannedWidget struct {
+	Key       string        `json:"key,omitempty"`
+	Label     string        `json:"label"`
+	BuilderID int           `json:"builder_id,omitempty"`
+	Fields    []string      `json:"fields,omitempty"`
+	From      *WidgetLayout `json:"from,omitempty"`
+	To        *WidgetLayout `json:"to,omitempty"`
+
+	scopeID int
+	current *middleware.Widget
+	desired *DashboardDocWidget
+}
+
+// LoadDashboardDir reads the dashboard documents in the .yaml, .yml and .json
+// files of dir. Every document needs a key, and keys must be unique.
+func LoadDashboardDir(dir string) ([]*DashboardDocument, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read dashboard directory: %w", err)
+	}
+
+	var docs []*DashboardDocument
+	var files []string
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		doc, err := loadDashboardFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if other, ok := seen[doc.Key]; ok {
+			return nil, nil, fmt.Errorf("%s: dashboard key %s is also used by %s", path, doc.Key, other)
+		}
+		seen[doc.Key] = path
+		docs = append(docs, doc)
+		files = append(files, path)
+	}
+	return docs, files, nil
+}
+
+func loadDashboardFile(path string) (*DashboardDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var doc DashboardDocument
+	if err := decodeYAML(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if doc.Version == 0 {
+		doc.Version = DashboardDocumentVersion
+	}
+	if doc.Key == "" {
+		return nil, fmt.Errorf("%s: key is required", path)
+	}
+	if err := validateDocument(&doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &doc, nil
+}
+
+// decodeYAML decodes a YAML or JSON document into v. It decodes through JSON
+// so that YAML documents use the JSON field names and end up with the same
+// value types as documents read from the API.
+func decodeYAML(data []byte, v any) error {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	jsonData, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, v)
+}
+
+// PlanDashboards compares docs with the dashboards of the account and returns
+// the changes that apply them. files names the file of each document. Existing
+// dashboards without the managed tag are skipped unless adopt is set, so a file
+// can't take over a dashboard someone built by hand by accident.
+func PlanDashboards(ctx context.Context, client *middleware.Client, docs []*DashboardDocument, files []string, adopt bool) (*DashboardPlan, error) {
+	reports, err := listAllDashboards(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dashboards: %w", err)
+	}
+	byKey := make(map[string]*middleware.Report, len(reports))
+	for i := range reports {
+		byKey[reports[i].Key] = &reports[i]
+	}
+
+	plan := &DashboardPlan{Dashboards: []DashboardPlanItem{}}
+	listed := make(map[string]bool, len(docs))
+	for i, doc := range docs {
+		listed[doc.Key] = true
+		item := DashboardPlanItem{Key: doc.Key, Label: doc.Label, document: doc}
+		if i < len(files) {
+			item.File = files[i]
+		}
+
+		report := byKey[doc.Key]
+		if report == nil {
+			item.Action = PlanCreate
+			for j := range doc.Widgets {
+				item.Added = append(item.Added, PlannedWidget{Key: doc.Widgets[j].Key, Label: doc.Widgets[j].Label, To: doc.Widgets[j].Layout, desired: &doc.Widgets[j]})
+			}
+			plan.Dashboards = append(plan.Dashboards, item)
+			continue
+		}
+
+		item.DashboardID = report.ID
+		item.report = report
+		if !adopt && !isManaged(report.MetaData) {
+			item.Action = PlanSkip
+			plan.Dashboards = append(plan.Dashboards, item)
+			continue
+		}
+		widgets, err := client.GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: report.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get widgets of dashboard %s: %w", doc.Key, err)
+		}
+		planDashboardUpdate(&item, report, widgets)
+		plan.Dashboards = append(plan.Dashboards, item)
+	}
+
+	for _, report := range reports {
+		if isManaged(report.MetaData) && !listed[report.Key] {
+			plan.Unlisted = append(plan.Unlisted, report.Key)
+		}
+	}
+	sort.Strings(plan.Unlisted)
+	return plan, nil
+}
+
+func planDashboardUpdate(item *DashboardPlanItem, report *middleware.Report, widgets []middleware.Widget) {
+	doc := item.document
+	setting := func(field, from, to string) {
+		if to != "" && from != to {
+			item.Settings = append(item.Settings, SettingChange{Field: field, From: from, To: to})
+		}
+	}
+	setting("label", report.Label, doc.Label)
+	setting("description", report.Description, doc.Description)
+	setting("visibility", report.Visibility, doc.Visibility)
+	setting("display_scope", report.DisplayScope, doc.DisplayScope)
+	var metaChanges []string
+	diffValues("meta_data", stripAccountIDs(report.MetaData), doc.MetaData, &metaChanges)
+	for _, path := range metaChanges {
+		item.Settings = append(item.Settings, SettingChange{Field: path})
+	}
+	if !isManaged(report.MetaData) {
+		setting("meta_data."+managedByKey, "", managedByValue)
+	}
+
+	current := make([]DashboardDocWidget, len(widgets))
+	for i := range widgets {
+		current[i] = newDashboardDocWidget(widgets[i])
+	}
+	pairs, added, removed := matchWidgets(current, doc.Widgets)
+
+	for _, i := range added {
+		desired := &doc.Widgets[i]
+		item.Added = append(item.Added, PlannedWidget{Key: desired.Key, Label: desired.Label, To: desired.Layout, desired: desired})
+	}
+	for _, i := range removed {
+		item.Removed = append(item.Removed, PlannedWidget{Key: widgets[i].Key, Label: widgets[i].Label, BuilderID: widgets[i].ID, current: &widgets[i]})
+	}
+	for _, pair := range pairs {
+		widget := &widgets[pair[0]]
+		desired := &doc.Widgets[pair[1]]
+		planned := PlannedWidget{Key: widget.Key, Label: desired.Label, BuilderID: widget.ID, current: widget, desired: desired}
+		if widget.Scope != nil {
+			planned.scopeID = widget.Scope.ID
+		}
+
+		if fields := widgetChanges(current[pair[0]], *desired); len(fields) > 0 {
+			changed := planned
+			changed.Fields = fields
+			item.Changed = append(item.Changed, changed)
+		}
+		if from := current[pair[0]].Layout; desired.Layout != nil && (from == nil || *from != *desired.Layout) {
+			moved := planned
+			moved.From, moved.To = from, desired.Layout
+			item.Moved = append(item.Moved, moved)
+		}
+	}
+
+	item.Action = PlanNone
+	if len(item.Settings)+len(item.Added)+len(item.Changed)+len(item.Removed)+len(item.Moved) > 0 {
+		item.Action = PlanUpdate
+	}
+}
+
+// matchWidgets pairs the widgets of two versions of a dashboard, first by key
+// and then by label. It returns the index pairs, the indexes of the desired
+// widgets without a match and those of the current widgets without one.
+func matchWidgets(current, desired []DashboardDocWidget) (pairs [][2]int, added, removed []int) {
+	matchedCurrent := make([]bool, len(current))
+	matchedDesired := make([]bool, len(desired))
+	match := func(same func(a, b DashboardDocWidget) bool) {
+		for j := range desired {
+			if matchedDesired[j] {
+				continue
+			}
+			for i := range current {
+				if !matchedCurrent[i] && same(current[i], desired[j]) {
+					matchedCurrent[i], matchedDesired[j] = true, true
+					pairs = append(pairs, [2]int{i, j})
+					break
+				}
+			}
+		}
+	}
+	match(func(a, b DashboardDocWidget) bool { return a.Key != "" && a.Key == b.Key })
+	match(func(a, b DashboardDocWidget) bool { return a.Label == b.Label })
+
+	sort.Slice(pairs, func(a, b int) bool { return pairs[a][1] < pairs[b][1] })
+	for j, ok := range matchedDesired {
+		if !ok {
+			added = append(added, j)
+		}
+	}
+	for i, ok := range matchedCurrent {
+		if !ok {
+			removed = append(removed, i)
+		}
+	}
+	return pairs, added, removed
+}
+
+// widgetChanges lists the fields of desired that differ from current. Fields
+// desired leaves out are not compared, so documents only need to hold the
+// parts of a widget they manage.
+func widgetChanges(current, desired DashboardDocWidget) []string {
+	var fields []string
+	if desired.Label != current.Label {
+		fields = append(fields, "label")
+	}
+	if desired.WidgetType != "" && desired.WidgetType != current.WidgetType {
+		fields = append(fields, "widget_type")
+	}
+	diffValues("config", current.Config, desired.Config, &fields)
+	diffValues("meta_data", current.MetaData, desired.MetaData, &fields)
+	return fields
+}
+
+// diffValues appends to paths the path of every value in desired that differs
+// from current. Objects are compared by the keys of desired, arrays element by
+// element.
+func diffValues(path string, current, desired any, paths *[]string) {
+	switch d := desired.(type) {
+	case nil:
+		return
+	case map[string]any:
+		c, ok := current.(map[string]any)
+		if !ok {
+			*paths = append(*paths, path)
+			return
+		}
+		keys := make([]string, 0, len(d))
+		for key := range d {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			diffValues(path+"."+key, c[key], d[key], paths)
+		}
+	case []any:
+		c, ok := current.([]any)
+		if !ok || len(c) != len(d) {
+			*paths = append(*paths, path)
+			return
+		}
+		for i := range d {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), c[i], d[i], paths)
+		}
+	default:
+		if !reflect.DeepEqual(current, desired) {
+			*paths = append(*paths, path)
+		}
+	}
+}
+
+// mergeValues returns current with the values of desired laid over it,
+// merging objects key by key.
+func mergeValues(current, desired any) any {
+	d, ok := desired.(map[string]any)
+	if !ok {
+		if desired == nil {
+			return current
+		}
+		return desired
+	}
+	c, ok := current.(map[string]any)
+	if !ok {
+		return desired
+	}
+	merged := make(map[string]any, len(c)+len(d))
+	for key, value := range c {
+		merged[key] = value
+	}
+	for key, value := range d {
+		merged[key] = mergeValues(c[key], value)
+	}
+	return merged
+}
+
+// managedMetaData returns metaData with the managed tag added.
+func managedMetaData(metaData any) map[string]any {
+	merged, _ := mergeValues(metaData, map[string]any{managedByKey: managedByValue}).(map[string]any)
+	return merged
+}
+
+func isManaged(metaData any) bool {
+	fields, ok := metaData.(map[string]any)
+	return ok && fields[managedByKey] == managedByValue
+}
+
+// listAllDashboards pages through the dashboard list.
+func listAllDashboards(ctx context.Context, client *middleware.Client) ([]middleware.Report, error) {
+	const pageSize = 100
+	var reports []middleware.Report
+	for offset := 0; ; offset += pageSize {
+		page, err := client.GetDashboards(ctx, &middleware.GetDashboardsParams{Limit: pageSize, Offset: offset})
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, page.Reports...)
+		if len(page.Reports) < pageSize || (page.Total > 0 && offset+len(page.Reports) >= page.Total) {
+			return reports, nil
+		}
+	}
+}
+
+// ApplyDashboardPlan carries out plan, calling logf after each change. New
+// dashboards are created like import_dashboard creates them, and are deleted
+// again if creating them fails. Changes to existing dashboards stop at the
+// first error.
+func ApplyDashboardPlan(ctx context.Context, client *middleware.Client, plan *DashboardPlan, logf func(format string, args ...any)) error {
+	for i := range plan.Dashboards {
+		item := &plan.Dashboards[i]
+		switch item.Action {
+		case PlanCreate:
+			if err := applyCreate(ctx, client, item, logf); err != nil {
+				return fmt.Errorf("dashboard %s: %w", item.Key, err)
+			}
+		case PlanUpdate:
+			if err := applyUpdate(ctx, client, item, logf); err != nil {
+				return fmt.Errorf("dashboard %s: %w", item.Key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func applyCreate(ctx context.Context, client *middleware.Client, item *DashboardPlanItem, logf func(string, ...any)) error {
+	doc := *item.document
+	doc.MetaData = managedMetaData(doc.MetaData)
+	if doc.Visibility == "" {
+		doc.Visibility = "private"
+	}
+
+	widgets, imported, err := importWidgets(&doc, true)
+	if err != nil {
+		return err
+	}
+	result := &ImportDashboardResult{Action: "create", Key: doc.Key, Label: doc.Label, Widgets: imported}
+	imp := &dashboardImport{client: client, result: result}
+	if err := imp.run(ctx, mcp.CallToolRequest{}, &doc, nil, widgets, nil); err != nil {
+		return err
+	}
+	item.DashboardID = result.DashboardID
+	logf("%s: created dashboard (ID %d) with %d widgets", item.Key, result.DashboardID, len(widgets))
+	return nil
+}
+
+func applyUpdate(ctx context.Context, client *middleware.Client, item *DashboardPlanItem, logf func(string, ...any)) error {
+	doc := item.document
+	report := item.report
+
+	if len(item.Settings) > 0 {
+		// Settings the document leaves out keep their current values.
+		orCurrent := func(desired, current string) string {
+			if desired == "" {
+				return current
+			}
+			return desired
+		}
+		upsert := &middleware.UpsertReportRequest{
+			ID:           report.ID,
+			Key:          report.Key,
+			Label:        doc.Label,
+			Description:  orCurrent(doc.Description, report.Description),
+			DisplayScope: orCurrent(doc.DisplayScope, report.DisplayScope),
+			Visibility:   orCurrent(doc.Visibility, report.Visibility),
+			MetaData:     managedMetaData(mergeValues(report.MetaData, doc.MetaData)),
+		}
+		if _, err := client.UpdateDashboard(ctx, report.ID, upsert); err != nil {
+			return fmt.Errorf("failed to update dashboard: %w", err)
+		}
+		logf("%s: updated settings", item.Key)
+	}
+
+	reportView := &middleware.ReportView{ReportID: report.ID, ReportKey: report.Key, ReportName: doc.Label}
+	var layouts []middleware.LayoutItem
+	var unscoped []int
+
+	for _, planned := range item.Added {
+		widget, err := customWidget(*planned.desired)
+		if err != nil {
+			return err
+		}
+		if widget.Key == "" {
+			widget.Key = generateWidgetKey(widget.Label)
+		}
+		widget.BuilderViewOptions.Report = reportView
+		created, err := client.CreateWidget(ctx, widget)
+		if err != nil {
+			return fmt.Errorf("failed to create widget %q: %w", widget.Label, err)
+		}
+		logf("%s: created widget %q", item.Key, widget.Label)
+		if planned.desired.Layout == nil {
+			continue
+		}
+		layout := documentLayout(planned.desired.Layout)
+		if created.Scope != nil && created.Scope.ID != 0 {
+			layout.ScopeID = created.Scope.ID
+		} else {
+			unscoped = append(unscoped, created.ID)
+		}
+		layouts = append(layouts, layout)
+	}
+
+	for _, planned := range item.Changed {
+		current := newDashboardDocWidget(*planned.current)
+		desired := *planned.desired
+		desired.Config = mergeValues(current.Config, desired.Config)
+		desired.MetaData = mergeValues(current.MetaData, desired.MetaData)
+		if desired.WidgetType == "" {
+			desired.WidgetType, desired.WidgetAppID = current.WidgetType, current.WidgetAppID
+		}
+		if desired.Key == "" {
+			desired.Key = planned.current.Key
+		}
+		widget, err := customWidget(desired)
+		if err != nil {
+			return err
+		}
+		widget.BuilderID = planned.BuilderID
+		widget.ScopeID = 0
+		widget.Layout = nil
+		widget.BuilderViewOptions.Report = reportView
+		if _, err := client.UpdateWidget(ctx, widget); err != nil {
+			return fmt.Errorf("failed to update widget %q: %w", widget.Label, err)
+		}
+		logf("%s: updated widget %q (%s)", item.Key, widget.Label, strings.Join(planned.Fields, ", "))
+	}
+
+	for _, planned := range item.Removed {
+		if err := client.DeleteWidget(ctx, planned.BuilderID); err != nil {
+			return fmt.Errorf("failed to delete widget %q: %w", planned.Label, err)
+		}
+		logf("%s: deleted widget %q", item.Key, planned.Label)
+	}
+
+	for _, planned := range item.Moved {
+		layout := documentLayout(planned.To)
+		layout.ScopeID = planned.scopeID
+		if layout.ScopeID == 0 {
+			unscoped = append(unscoped, planned.BuilderID)
+		}
+		layouts = append(layouts, layout)
+	}
+
+	if len(unscoped) > 0 {
+		if err := fillScopeIDs(ctx, client, report.ID, unscoped, layouts); err != nil {
+			return err
+		}
+		// A layout sent with scope ID 0 would not place the widget.
+		scoped := layouts[:0]
+		for _, layout := range layouts {
+			if layout.ScopeID != 0 {
+				scoped = append(scoped, layout)
+			}
+		}
+		if skipped := len(layouts) - len(scoped); skipped > 0 {
+			logf("%s: skipped the layouts of %d widgets with no scope on the dashboard", item.Key, skipped)
+		}
+		layouts = scoped
+	}
+	if len(layouts) > 0 {
+		if err := client.UpdateWidgetLayouts(ctx, &middleware.LayoutRequest{Layouts: layouts}); err != nil {
+			return fmt.Errorf("failed to update widget layouts: %w", err)
+		}
+		logf("%s: updated %d widget layouts", item.Key, len(layouts))
+	}
+	return nil
+}
+
+// fillScopeIDs sets the scope IDs of the layouts of widgets whose scope isn't
+// known yet, such as new widgets whose create response didn't include one. The
+// layouts without a scope ID belong to the widgets in unscoped, in order.
+func fillScopeIDs(ctx context.Context, client *middleware.Client, reportID int, unscoped []int, layouts []middleware.LayoutItem) error {
+	onDashboard, err := client.GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: reportID})
+	if err != nil {
+		return fmt.Errorf("failed to get widgets: %w", err)
+	}
+	scopes := make(map[int]int, len(onDashboard))
+	for _, widget := range onDashboard {
+		if widget.Scope != nil {
+			scopes[widget.ID] = widget.Scope.ID
+		}
+	}
+	next := 0
+	for i := range layouts {
+		if layouts[i].ScopeID == 0 && next < len(unscoped) {
+			layouts[i].ScopeID = scopes[unscoped[next]]
+			next++
+		}
+	}
+	return nil
+}
+
+func documentLayout(layout *WidgetLayout) middleware.LayoutItem {
+	return middleware.LayoutItem{X: layout.X, Y: layout.Y, W: layout.W, H: layout.H}
+}
@@ -0,0 +1,468 @@
+package tools
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"mcp-middleware/middleware"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+//go:embed templates/*.yaml
+var builtinTemplates embed.FS
+
+// BuiltinTemplateSource is the source of the templates embedded in the server.
+const BuiltinTemplateSource = "built-in"
+
+// The dashboard grid and the smallest widget it accepts.
+const (
+	gridColumns     = 12
+	minWidgetWidth  = 4
+	minWidgetHeight = 6
+)
+
+var (
+	templateVariableName    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	templateVariablePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	dashboardKeyInvalid     = regexp.MustCompile(`[^a-z0-9_]+`)
+)
+
+// DashboardTemplate is a dashboard document with variables, from which
+// dashboards are created by create_dashboard_from_template.
+type DashboardTemplate struct {
+	Name        string             `json:"name" jsonschema:"Name of the template"`
+	Description string             `json:"description,omitempty" jsonschema:"What the dashboard shows"`
+	Variables   []TemplateVariable `json:"variables,omitempty" jsonschema:"Variables the template takes"`
+	Dashboard   DashboardDocument  `json:"dashboard" jsonschema:"The dashboard, with ${variable} placeholders"`
+
+	source string
+}
+
+// TemplateVariable is a variable of a DashboardTemplate. Its value replaces
+// ${name} in the template, and if Attribute is set, a non-empty value also
+// filters every query of the dashboard by Attribute = value.
+type TemplateVariable struct {
+	Name        string `json:"name" jsonschema:"Name of the variable, used as ${name} in the template"`
+	Description string `json:"description,omitempty" jsonschema:"What the variable selects"`
+	Default     string `json:"default,omitempty" jsonschema:"Value used when none is given"`
+	Required    bool   `json:"required,omitempty" jsonschema:"Whether a value must be given"`
+	Attribute   string `json:"attribute,omitempty" jsonschema:"Attribute every query is filtered by when the variable is set"`
+}
+
+func NewListDashboardTemplatesTool() mcp.Tool {
+	return mcp.NewTool(
+		"list_dashboard_templates",
+		mcp.WithDescription(`List the dashboard templates create_dashboard_from_template can instantiate.
+
+Each template is described with its variables (such as a host filter, namespace or service name) and the widgets it creates. Built-in templates cover host overview, Kubernetes cluster, container fleet, service RED metrics and log volume; custom templates are loaded from the configured templates directory.`),
+		mcp.WithInputSchema[ListDashboardTemplatesInput](),
+		mcp.WithOutputSchema[ListDashboardTemplatesResult](),
+		mcp.WithTitleAnnotation("List Dashboard Templates"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(false),
+	)
+}
+
+type ListDashboardTemplatesInput struct{}
+
+type ListDashboardTemplatesResult struct {
+	Templates []TemplateSummary `json:"templates" jsonschema:"The available templates"`
+}
+
+// TemplateSummary describes a template in list_dashboard_templates.
+type TemplateSummary struct {
+	Name        string             `json:"name" jsonschema:"Name of the template"`
+	Description string             `json:"description,omitempty" jsonschema:"What the dashboard shows"`
+	Source      string             `json:"source" jsonschema:"built-in, or the file of a custom template"`
+	Label       string             `json:"label" jsonschema:"Name of the dashboard the template creates"`
+	Variables   []TemplateVariable `json:"variables" jsonschema:"Variables the template takes"`
+	Widgets     []string           `json:"widgets" jsonschema:"Titles of the widgets the template creates"`
+}
+
+func HandleListDashboardTemplates(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	templates, err := LoadDashboardTemplates(s.Config().DashboardTemplatesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ListDashboardTemplatesResult{Templates: make([]TemplateSummary, 0, len(templates))}
+	for _, tmpl := range templates {
+		summary := TemplateSummary{
+			Name:        tmpl.Name,
+			Description: tmpl.Description,
+			Source:      tmpl.source,
+			Label:       tmpl.Dashboard.Label,
+			Variables:   tmpl.Variables,
+			Widgets:     make([]string, 0, len(tmpl.Dashboard.Widgets)),
+		}
+		if summary.Variables == nil {
+			summary.Variables = []TemplateVariable{}
+		}
+		for _, widget := range tmpl.Dashboard.Widgets {
+			summary.Widgets = append(summary.Widgets, widget.Label)
+		}
+		result.Templates = append(result.Templates, summary)
+	}
+	return ToStructuredResult(result)
+}
+
+func NewCreateDashboardFromTemplateTool() mcp.Tool {
+	return mcp.NewTool(
+		"create_dashboard_from_template",
+		mcp.WithDescription(`Create a dashboard and all its widgets from a template listed by list_dashboard_templates.
+
+Templates take variables such as a host filter, namespace or service name, which are substituted into the dashboard's key, labels and queries. Variables that select an attribute add a filter on it to every query when they are set. The widgets are packed into the dashboard grid in template order. If creating any widget fails, everything created so far is deleted again.
+
+Use dry_run to see the dashboard and widgets a template would create without changing anything.`),
+		mcp.WithInputSchema[CreateDashboardFromTemplateInput](),
+		mcp.WithOutputSchema[ImportDashboardResult](),
+		mcp.WithTitleAnnotation("Create Dashboard From Template"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithOpenWorldHintAnnotation(true),
+	)
+}
+
+type CreateDashboardFromTemplateInput struct {
+	Template   string            `json:"template" jsonschema:"Name of the template, as listed by list_dashboard_templates,required"`
+	Variables  map[string]string `json:"variables,omitempty" jsonschema:"Values of the template's variables, by name"`
+	Key        string            `json:"key,omitempty" jsonschema:"Key of the new dashboard, instead of the template's"`
+	Label      string            `json:"label,omitempty" jsonschema:"Name of the new dashboard, instead of the template's"`
+	Visibility string            `json:"visibility,omitempty" jsonschema:"Visibility of the new dashboard: public or private (default),enum=public,enum=private"`
+	DryRun     bool              `json:"dry_run,omitempty" jsonschema:"Report what would be created without changing anything"`
+}
+
+func HandleCreateDashboardFromTemplate(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := ParseInput[CreateDashboardFromTemplateInput](req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	templates, err := LoadDashboardTemplates(s.Config().DashboardTemplatesDir)
+	if err != nil {
+		return nil, err
+	}
+	var tmpl *DashboardTemplate
+	names := make([]string, 0, len(templates))
+	for _, t := range templates {
+		names = append(names, t.Name)
+		if t.Name == input.Template {
+			tmpl = t
+		}
+	}
+	if tmpl == nil {
+		return nil, invalidInput("unknown template %q (available: %s)", input.Template, strings.Join(names, ", "))
+	}
+
+	doc, err := tmpl.Instantiate(input.Variables)
+	if err != nil {
+		return nil, err
+	}
+	if input.Key != "" {
+		doc.Key = input.Key
+	}
+	if input.Label != "" {
+		doc.Label = input.Label
+	}
+	if input.Visibility != "" {
+		doc.Visibility = input.Visibility
+	}
+	if doc.Visibility == "" {
+		doc.Visibility = "private"
+	}
+
+	client := s.Client()
+	existing, err := getDashboardByKey(ctx, client, doc.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up dashboard %s: %w", doc.Key, err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("dashboard %s already exists (ID %d); set key to create the dashboard under another key", doc.Key, existing.ID)
+	}
+
+	// Templates are instantiated many times, so every widget gets a new key.
+	widgets, imported, err := importWidgets(doc, false)
+	if err != nil {
+		return nil, err
+	}
+	result := &ImportDashboardResult{DryRun: input.DryRun, Action: "create", Key: doc.Key, Label: doc.Label, Widgets: imported}
+	if input.DryRun {
+		result.Message = importSummary(result, true)
+		return ToStructuredResult(result)
+	}
+
+	imp := &dashboardImport{client: client, result: result}
+	if err := imp.run(ctx, req, doc, nil, widgets, nil); err != nil {
+		return nil, err
+	}
+	result.Message = importSummary(result, false)
+	return ToStructuredResult(result)
+}
+
+// LoadDashboardTemplates returns the built-in templates and those in dir, if
+// set, ordered by name. A template in dir replaces the built-in one with the
+// same name.
+func LoadDashboardTemplates(dir string) ([]*DashboardTemplate, error) {
+	byName := make(map[string]*DashboardTemplate)
+
+	entries, err := builtinTemplates.ReadDir("templates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read built-in templates: %w", err)
+	}
+	for _, entry := range entries {
+		name := path.Join("templates", entry.Name())
+		data, err := builtinTemplates.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read built-in template %s: %w", name, err)
+		}
+		tmpl, err := parseDashboardTemplate(data, entry.Name(), BuiltinTemplateSource)
+		if err != nil {
+			return nil, err
+		}
+		byName[tmpl.Name] = tmpl
+	}
+
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dashboard templates directory: %w", err)
+		}
+		custom := make(map[string]string)
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+				continue
+			}
+			file := filepath.Join(dir, entry.Name())
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", file, err)
+			}
+			tmpl, err := parseDashboardTemplate(data, entry.Name(), file)
+			if err != nil {
+				return nil, err
+			}
+			if other, ok := custom[tmpl.Name]; ok {
+				return nil, fmt.Errorf("%s: template %s is also defined by %s", file, tmpl.Name, other)
+			}
+			custom[tmpl.Name] = file
+			byName[tmpl.Name] = tmpl
+		}
+	}
+
+	templates := make([]*DashboardTemplate, 0, len(byName))
+	for _, tmpl := range byName {
+		templates = append(templates, tmpl)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// parseDashboardTemplate parses and checks a template file. Templates without
+// a name are named after their file.
+func parseDashboardTemplate(data []byte, fileName, source string) (*DashboardTemplate, error) {
+	var tmpl DashboardTemplate
+	if err := decodeYAML(data, &tmpl); err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", source, err)
+	}
+	tmpl.source = source
+	if tmpl.Name == "" {
+		tmpl.Name = strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	}
+	if tmpl.Dashboard.Version == 0 {
+		tmpl.Dashboard.Version = DashboardDocumentVersion
+	}
+
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("invalid template %s (%s): %s", tmpl.Name, source, fmt.Sprintf(format, args...))
+	}
+	if tmpl.Dashboard.Key == "" {
+		return nil, invalid("dashboard key is required")
+	}
+	if err := validateDocument(&tmpl.Dashboard); err != nil {
+		return nil, invalid("%v", err)
+	}
+
+	declared := make(map[string]bool, len(tmpl.Variables))
+	for _, variable := range tmpl.Variables {
+		if !templateVariableName.MatchString(variable.Name) {
+			return nil, invalid("invalid variable name %q", variable.Name)
+		}
+		if declared[variable.Name] {
+			return nil, invalid("variable %s is declared twice", variable.Name)
+		}
+		declared[variable.Name] = true
+	}
+	dashboard, err := json.Marshal(tmpl.Dashboard)
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+	for _, match := range templateVariablePattern.FindAllStringSubmatch(string(dashboard), -1) {
+		if !declared[match[1]] {
+			return nil, invalid("${%s} is not a declared variable", match[1])
+		}
+	}
+	return &tmpl, nil
+}
+
+// Instantiate returns the dashboard document of the template with the given
+// variable values, its widgets packed into the grid.
+func (t *DashboardTemplate) Instantiate(values map[string]string) (*DashboardDocument, error) {
+	resolved := make(map[string]string, len(t.Variables))
+	var filters []TemplateVariable
+	for _, variable := range t.Variables {
+		value, ok := values[variable.Name]
+		if !ok {
+			value = variable.Default
+		}
+		if value == "" && variable.Required {
+			return nil, invalidInput("template %s requires variable %s", t.Name, variable.Name)
+		}
+		resolved[variable.Name] = value
+		if variable.Attribute != "" && value != "" {
+			filters = append(filters, variable)
+		}
+	}
+	for name := range values {
+		if _, ok := resolved[name]; !ok {
+			return nil, invalidInput("template %s has no variable %s", t.Name, name)
+		}
+	}
+
+	// Substitute on a generic copy, so that the template itself is unchanged.
+	data, err := json.Marshal(t.Dashboard)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate template %s: %w", t.Name, err)
+	}
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to instantiate template %s: %w", t.Name, err)
+	}
+	if data, err = json.Marshal(substituteVariables(raw, resolved)); err != nil {
+		return nil, fmt.Errorf("failed to instantiate template %s: %w", t.Name, err)
+	}
+	var doc DashboardDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to instantiate template %s: %w", t.Name, err)
+	}
+
+	doc.Key = strings.Trim(dashboardKeyInvalid.ReplaceAllString(strings.ToLower(doc.Key), "-"), "-")
+	for i := range doc.Widgets {
+		for _, variable := range filters {
+			addQueryFilter(doc.Widgets[i].Config, variable.Attribute, resolved[variable.Name])
+		}
+	}
+	packLayouts(doc.Widgets)
+	return &doc, nil
+}
+
+// substituteVariables replaces ${name} in every string of value, as decoded
+// by encoding/json, and trims the surrounding space left by empty values.
+func substituteVariables(value any, values map[string]string) any {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "${") {
+			return v
+		}
+		replaced := templateVariablePattern.ReplaceAllStringFunc(v, func(match string) string {
+			return values[match[2:len(match)-1]]
+		})
+		return strings.Join(strings.Fields(replaced), " ")
+	case map[string]any:
+		for key, item := range v {
+			v[key] = substituteVariables(item, values)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = substituteVariables(item, values)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// addQueryFilter adds attribute = value to the filter of every query in a
+// widget config.
+func addQueryFilter(config any, attribute, value string) {
+	condition := map[string]any{attribute: map[string]any{"=": value}}
+	for _, query := range builderQueries(config) {
+		if query == nil {
+			continue
+		}
+		items, _ := query["with"].([]any)
+		found := false
+		for _, item := range items {
+			with, _ := item.(map[string]any)
+			if with["key"] != string(middleware.BuilderConfigWithKeyAttributeFilter) {
+				continue
+			}
+			found = true
+			filter, _ := with["value"].(map[string]any)
+			and, isAnd := filter["and"].([]any)
+			switch {
+			case len(filter) == 0:
+				with["value"] = map[string]any{"and": []any{condition}}
+			case isAnd && len(filter) == 1:
+				filter["and"] = append(and, condition)
+			default:
+				with["value"] = map[string]any{"and": []any{filter, condition}}
+			}
+		}
+		if !found {
+			query["with"] = append(items, map[string]any{
+				"key":    string(middleware.BuilderConfigWithKeyAttributeFilter),
+				"value":  map[string]any{"and": []any{condition}},
+				"is_arg": true,
+			})
+		}
+	}
+}
+
+// packLayouts places widgets in order, each at the highest free position of
+// the grid, keeping the size a widget's layout asks for. Widgets without one
+// are 6x6, and sizes are clamped to the grid.
+func packLayouts(widgets []DashboardDocWidget) {
+	var heights [gridColumns]int
+	for i := range widgets {
+		w, h := 6, 6
+		if layout := widgets[i].Layout; layout != nil {
+			if layout.W > 0 {
+				w = layout.W
+			}
+			if layout.H > 0 {
+				h = layout.H
+			}
+		}
+		w = min(max(w, minWidgetWidth), gridColumns)
+		h = max(h, minWidgetHeight)
+
+		bestX, bestY := 0, -1
+		for x := 0; x+w <= gridColumns; x++ {
+			y := 0
+			for _, height := range heights[x : x+w] {
+				y = max(y, height)
+			}
+			if bestY < 0 || y < bestY {
+				bestX, bestY = x, y
+			}
+		}
+		for x := bestX; x < bestX+w; x++ {
+			heights[x] = bestY + h
+		}
+		widgets[i].Layout = &WidgetLayout{X: bestX, Y: bestY, W: w, H: h}
+	}
+}
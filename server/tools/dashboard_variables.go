@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"mcp-middleware/middleware"
+)
+
+// dashboardVariablesKey is the key of the variables in a dashboard's metadata.
+const dashboardVariablesKey = "variables"
+
+// dashboardVariablePattern matches what may be a reference to a dashboard
+// variable, such as $host. Only the names of variables with a value are
+// substituted; other text, like a $ in a regular expression, is left as is.
+// Unlike template variables, which are substituted once when the dashboard is
+// created, dashboard variables are substituted on every data request.
+var dashboardVariablePattern = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// DashboardVariable is a variable of a dashboard that its widgets refer to as
+// $name in their filters and group by.
+type DashboardVariable struct {
+	Name        string `json:"name" yaml:"name" jsonschema:"Name of the variable, referred to as $name in widget filters and group by,required"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty" jsonschema:"What the variable selects"`
+	Default     string `json:"default,omitempty" yaml:"default,omitempty" jsonschema:"Value used when a data request doesn't set one. With no value, conditions and group by on the variable are left out"`
+}
+
+// validateDashboardVariables checks that variables have valid, unique names.
+func validateDashboardVariables(variables []DashboardVariable) error {
+	seen := make(map[string]bool)
+	for _, variable := range variables {
+		if !templateVariableName.MatchString(variable.Name) {
+			return invalidInput("invalid dashboard variable name %q", variable.Name)
+		}
+		if seen[variable.Name] {
+			return invalidInput("dashboard variable %s is defined twice", variable.Name)
+		}
+		seen[variable.Name] = true
+	}
+	return nil
+}
+
+// withDashboardVariables returns a copy of a dashboard's metadata with its
+// variables replaced by variables, removing them if there are none.
+func withDashboardVariables(metaData any, variables []DashboardVariable) (map[string]any, error) {
+	if err := validateDashboardVariables(variables); err != nil {
+		return nil, err
+	}
+	updated := make(map[string]any)
+	if fields, ok := metaData.(map[string]any); ok {
+		for key, value := range fields {
+			updated[key] = value
+		}
+	}
+	if len(variables) == 0 {
+		delete(updated, dashboardVariablesKey)
+	} else {
+		updated[dashboardVariablesKey] = variables
+	}
+	return updated, nil
+}
+
+// dashboardVariables returns the variables stored in a dashboard's metadata.
+func dashboardVariables(report *middleware.Report) ([]DashboardVariable, error) {
+	fields, _ := report.MetaData.(map[string]any)
+	value, ok := fields[dashboardVariablesKey]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal variables of dashboard %s: %w", report.Key, err)
+	}
+	var variables []DashboardVariable
+	if err := json.Unmarshal(data, &variables); err != nil {
+		return nil, fmt.Errorf("invalid variables in dashboard %s: %w", report.Key, err)
+	}
+	return variables, nil
+}
+
+// widgetVariables substitutes dashboard variables into the queries of data
+// requests.
+type widgetVariables struct {
+	values  map[string]string
+	widgets []middleware.Widget
+}
+
+// loadWidgetVariables works out the variable values of a data request. With a
+// dashboard key, its variables' defaults fill in the values not given, values
+// of variables the dashboard doesn't define are an error, and its widgets are
+// loaded so that requests without a config use the widget's own. It returns
+// nil if there are neither values nor a dashboard, leaving queries as they
+// are.
+func loadWidgetVariables(ctx context.Context, client *middleware.Client, reportKey string, values map[string]string) (*widgetVariables, error) {
+	if reportKey == "" && len(values) == 0 {
+		return nil, nil
+	}
+	vars := &widgetVariables{values: make(map[string]string)}
+	if reportKey == "" {
+		for name, value := range values {
+			vars.values[name] = value
+		}
+		return vars, nil
+	}
+
+	report, err := getDashboardByKey(ctx, client, reportKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up dashboard %s: %w", reportKey, err)
+	}
+	if report == nil {
+		return nil, fmt.Errorf("dashboard %s not found", reportKey)
+	}
+	widgets, err := client.GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: report.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get widgets: %w", err)
+	}
+	return newWidgetVariables(report, widgets, values)
+}
+
+// newWidgetVariables returns the variables of a dashboard with widgets, with
+// values overriding their defaults. It returns nil if the dashboard has no
+// variables and no values are given.
+func newWidgetVariables(report *middleware.Report, widgets []middleware.Widget, values map[string]string) (*widgetVariables, error) {
+	defined, err := dashboardVariables(report)
+	if err != nil {
+		return nil, err
+	}
+	if len(defined) == 0 && len(values) == 0 {
+		return nil, nil
+	}
+
+	vars := &widgetVariables{values: make(map[string]string), widgets: widgets}
+	known := make(map[string]bool)
+	for _, variable := range defined {
+		known[variable.Name] = true
+		vars.values[variable.Name] = variable.Default
+	}
+	for name, value := range values {
+		if !known[name] {
+			return nil, invalidInput("dashboard %s has no variable %s", report.Key, name)
+		}
+		vars.values[name] = value
+	}
+	return vars, nil
+}
+
+// apply substitutes the variables into a data request. A request without a
+// config gets the config of the dashboard's widget it names.
+func (v *widgetVariables) apply(widget *middleware.CustomWidget) error {
+	if v == nil {
+		return nil
+	}
+	if len(widget.BuilderConfig) == 0 {
+		for _, stored := range v.widgets {
+			if (widget.BuilderID > 0 && stored.ID == widget.BuilderID) ||
+				(widget.BuilderID <= 0 && widget.Key != "" && stored.Key == widget.Key) ||
+				(widget.BuilderID <= 0 && widget.Key == "" && widget.Label != "" && stored.Label == widget.Label) {
+				request, err := customWidget(newDashboardDocWidget(stored))
+				if err != nil {
+					return err
+				}
+				widget.BuilderConfig = request.BuilderConfig
+				break
+			}
+		}
+	}
+
+	for i := range widget.BuilderConfig {
+		with := widget.BuilderConfig[i].With[:0:0]
+		for _, item := range widget.BuilderConfig[i].With {
+			if item.Key == middleware.BuilderConfigWithKeyAttributeFilter || item.Key == middleware.BuilderConfigWithKeySelectDataBy {
+				value, err := normalizeValue(item.Value)
+				if err != nil {
+					return err
+				}
+				var keep bool
+				if item.Value, keep = resolveVariables(value, v.values); !keep {
+					continue
+				}
+			}
+			with = append(with, item)
+		}
+		widget.BuilderConfig[i].With = with
+	}
+	return nil
+}
+
+// normalizeValue converts a value to the generic form JSON decodes to.
+func normalizeValue(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal query: %w", err)
+	}
+	return normalized, nil
+}
+
+// resolveVariables replaces the references to the variables in values in the
+// strings of value, leaving other $ text alone. It reports false for a value
+// that should be left out because its variables have empty values: a string
+// that only held variables, or a list or object all of whose items were left
+// out.
+func resolveVariables(value any, values map[string]string) (any, bool) {
+	switch value := value.(type) {
+	case string:
+		substituted := false
+		replaced := dashboardVariablePattern.ReplaceAllStringFunc(value, func(ref string) string {
+			resolved, ok := values[ref[1:]]
+			if !ok {
+				return ref
+			}
+			substituted = true
+			return resolved
+		})
+		return replaced, !substituted || strings.TrimSpace(replaced) != ""
+	case []any:
+		kept := make([]any, 0, len(value))
+		for _, item := range value {
+			if item, keep := resolveVariables(item, values); keep {
+				kept = append(kept, item)
+			}
+		}
+		return kept, len(kept) > 0 || len(value) == 0
+	case map[string]any:
+		kept := make(map[string]any, len(value))
+		for key, item := range value {
+			if item, keep := resolveVariables(item, values); keep {
+				kept[key] = item
+			}
+		}
+		return kept, len(kept) > 0 || len(value) == 0
+	}
+	return value, true
+}
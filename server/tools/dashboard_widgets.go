@@ -0,0 +1,236 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"mcp-middleware/middleware"
+)
+
+// maxSampleRows is the number of rows of query data kept in a widget data
+// summary.
+const maxSampleRows = 3
+
+// GetDashboardResult is the result of get_dashboard: the dashboard and, when
+// asked for, a summary of each of its widgets.
+type GetDashboardResult struct {
+	middleware.ReportListResponse
+	Widgets []DashboardWidgetSummary `json:"widgets,omitempty" jsonschema:"The dashboard's widgets, with include_widgets or include_data"`
+}
+
+// DashboardWidgetSummary describes a widget on a dashboard in terms a reader
+// can follow without knowing the builder config format.
+type DashboardWidgetSummary struct {
+	ID     int                `json:"id" jsonschema:"Builder ID of the widget"`
+	Key    string             `json:"key" jsonschema:"Key of the widget"`
+	Label  string             `json:"label" jsonschema:"Title of the widget"`
+	Type   string             `json:"type" jsonschema:"Widget type, such as time_series_chart"`
+	Query  string             `json:"query,omitempty" jsonschema:"The widget's queries: columns, source, filter and group by"`
+	Layout *WidgetLayout      `json:"layout,omitempty" jsonschema:"Position and size of the widget in the 12-column grid"`
+	Data   *WidgetDataSummary `json:"data,omitempty" jsonschema:"Summary of the widget's current data, with include_data"`
+}
+
+// WidgetDataSummary is a compact summary of the data of a widget.
+type WidgetDataSummary struct {
+	Error               string           `json:"error,omitempty" jsonschema:"Error the API returned for the widget's query"`
+	NotAvailableMetrics []string         `json:"not_available_metrics,omitempty" jsonschema:"Metrics of the query that have no data in the account"`
+	Empty               bool             `json:"empty" jsonschema:"Whether the query returned no data"`
+	Series              int              `json:"series,omitempty" jsonschema:"Number of chart series"`
+	Points              int              `json:"points,omitempty" jsonschema:"Number of chart data points across all series"`
+	Rows                int              `json:"rows,omitempty" jsonschema:"Number of rows of query data"`
+	Columns             []string         `json:"columns,omitempty" jsonschema:"Columns of the query data"`
+	Sample              []map[string]any `json:"sample,omitempty" jsonschema:"The first rows of the query data"`
+	From                string           `json:"from,omitempty" jsonschema:"Start of the time range of the data"`
+	To                  string           `json:"to,omitempty" jsonschema:"End of the time range of the data"`
+}
+
+// summarizeWidgets describes the widgets of a dashboard.
+func summarizeWidgets(widgets []middleware.Widget) []DashboardWidgetSummary {
+	summaries := make([]DashboardWidgetSummary, len(widgets))
+	for i, widget := range widgets {
+		doc := newDashboardDocWidget(widget)
+		summaries[i] = DashboardWidgetSummary{
+			ID:     widget.ID,
+			Key:    widget.Key,
+			Label:  widget.Label,
+			Type:   widgetTypeOf(doc),
+			Query:  describeQueries(builderQueries(doc.Config)),
+			Layout: doc.Layout,
+		}
+	}
+	return summaries
+}
+
+// fetchWidgetData gets the data of widgets with a single request, with vars
+// substituted into their queries, and returns it in the order of widgets. A
+// widget whose config can't be sent gets a response with only an error.
+func fetchWidgetData(ctx context.Context, client *middleware.Client, widgets []middleware.Widget, vars *widgetVariables) ([]middleware.BuilderDataResponse, error) {
+	responses := make([]middleware.BuilderDataResponse, len(widgets))
+	var requests []middleware.CustomWidget
+	var indices []int
+	for i, widget := range widgets {
+		request, err := customWidget(newDashboardDocWidget(widget))
+		if err != nil {
+			responses[i] = middleware.BuilderDataResponse{Key: widget.Key, Error: err.Error()}
+			continue
+		}
+		request.BuilderID = widget.ID
+		request.ScopeID = 0
+		request.Layout = nil
+		if widget.Scope != nil {
+			request.ScopeID = widget.Scope.ID
+		}
+		if err := vars.apply(request); err != nil {
+			responses[i] = middleware.BuilderDataResponse{Key: widget.Key, Error: err.Error()}
+			continue
+		}
+		requests = append(requests, *request)
+		indices = append(indices, i)
+	}
+	if len(requests) == 0 {
+		return responses, nil
+	}
+
+	data, err := client.GetMultiWidgetData(ctx, requests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get widget data: %w", err)
+	}
+
+	// Responses carry the widget key; fall back to their order for those that
+	// don't.
+	byKey := make(map[string]middleware.BuilderDataResponse, len(data))
+	for _, response := range data {
+		if response.Key != "" {
+			byKey[response.Key] = response
+		}
+	}
+	for n, i := range indices {
+		if response, ok := byKey[widgets[i].Key]; ok && widgets[i].Key != "" {
+			responses[i] = response
+		} else if n < len(data) {
+			responses[i] = data[n]
+		}
+	}
+	return responses, nil
+}
+
+// summarizeWidgetData summarizes the data of a widget. The shape of chart data
+// varies by widget type, so series are counted as the items of its top-level
+// list and points as the items of their data lists.
+func summarizeWidgetData(response middleware.BuilderDataResponse) *WidgetDataSummary {
+	summary := &WidgetDataSummary{
+		Error:               response.Error,
+		NotAvailableMetrics: response.NotAvailableMetrics,
+	}
+	if response.ErrorDesc != "" {
+		summary.Error = strings.TrimPrefix(strings.TrimSpace(summary.Error+": "+response.ErrorDesc), ": ")
+	}
+
+	chart := response.ChartDataV2
+	if chart == nil {
+		chart = response.ChartData
+	}
+	summary.Series, summary.Points = countSeries(chart)
+
+	if query, ok := response.QueryData.(map[string]any); ok {
+		rows, _ := query["data"].([]any)
+		summary.Rows = len(rows)
+		for _, row := range rows[:min(len(rows), maxSampleRows)] {
+			if fields, ok := row.(map[string]any); ok {
+				summary.Sample = append(summary.Sample, fields)
+			}
+		}
+		columns, _ := query["columns"].([]any)
+		for _, column := range columns {
+			if fields, ok := column.(map[string]any); ok {
+				if accessor, ok := fields["accessor"].(string); ok {
+					summary.Columns = append(summary.Columns, accessor)
+				}
+			}
+		}
+	}
+
+	if tr := response.TimeRange; tr != nil {
+		summary.From = formatTimestamp(tr.FromTs)
+		summary.To = formatTimestamp(tr.ToTs)
+	}
+	summary.Empty = summary.Error == "" && summary.Points == 0 && summary.Rows == 0
+	return summary
+}
+
+// countSeries counts the series of chart data and their points.
+func countSeries(chart any) (series, points int) {
+	var items []any
+	switch chart := chart.(type) {
+	case []any:
+		items = chart
+	case map[string]any:
+		for _, key := range []string{"series", "data", "datasets"} {
+			if list, ok := chart[key].([]any); ok {
+				items = list
+				break
+			}
+		}
+	}
+
+	for _, item := range items {
+		series++
+		switch item := item.(type) {
+		case map[string]any:
+			counted := false
+			for _, key := range []string{"data", "values", "points"} {
+				if list, ok := item[key].([]any); ok {
+					points += len(list)
+					counted = true
+					break
+				}
+			}
+			if !counted {
+				points++
+			}
+		case []any:
+			points += len(item)
+		default:
+			points++
+		}
+	}
+	return series, points
+}
+
+// formatTimestamp formats a Unix timestamp in seconds or milliseconds.
+func formatTimestamp(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+	if ts > 1e11 {
+		return time.UnixMilli(ts).UTC().Format(time.RFC3339)
+	}
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
+
+// describeQueries describes builder queries, one per line, as
+// "columns from source where filter by group".
+func describeQueries(queries []map[string]any) string {
+	var lines []string
+	for i, query := range queries {
+		one := []map[string]any{query}
+		line := strings.Join(queryColumns(one), ", ")
+		if source, ok := query["source"].(map[string]any); ok {
+			if name, ok := source["name"].(string); ok && name != "" {
+				line += " from " + name
+			}
+		}
+		if filter := queryFilter(queries, i); filter != "" && filter != "{}" && filter != "null" {
+			line += " where " + filter
+		}
+		if groupBy := queryGroupBy(one); len(groupBy) > 0 {
+			line += " by " + strings.Join(groupBy, ", ")
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
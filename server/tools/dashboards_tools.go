@@ -16,6 +16,12 @@ func NewListDashboardsTool() mcp.Tool {
 	
 This tool retrieves dashboards from Middleware.io with support for searching, filtering by various criteria, and pagination. Use this to discover available dashboards, find specific dashboards by name, or filter by ownership and usage patterns.`),
 		mcp.WithInputSchema[ListDashboardsInput](),
+		mcp.WithOutputSchema[middleware.ReportListResponse](),
+		mcp.WithTitleAnnotation("List Dashboards"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -46,7 +52,7 @@ func HandleListDashboards(s ServerInterface, ctx context.Context, req mcp.CallTo
 		return nil, err
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
 
 func NewGetDashboardTool() mcp.Tool {
@@ -54,13 +60,23 @@ func NewGetDashboardTool() mcp.Tool {
 		"get_dashboard",
 		mcp.WithDescription(`Get detailed information about a specific dashboard by its unique key.
 	
-This tool retrieves complete dashboard configuration including widgets, layout, metadata, and settings. Use this when you need to inspect or work with a specific dashboard's structure and content.`),
+This tool retrieves complete dashboard configuration including widgets, layout, metadata, and settings. Use this when you need to inspect or work with a specific dashboard's structure and content.
+
+Set include_widgets to also get each widget's title, type, query and position, and include_data to also get a summary of each widget's current data (series, points, rows and errors), all in one call.`),
 		mcp.WithInputSchema[GetDashboardInput](),
+		mcp.WithOutputSchema[GetDashboardResult](),
+		mcp.WithTitleAnnotation("Get Dashboard"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
 type GetDashboardInput struct {
-	ReportKey string `json:"report_key" jsonschema:"The unique key identifier of the dashboard to retrieve,required"`
+	ReportKey      string `json:"report_key" jsonschema:"The unique key identifier of the dashboard to retrieve,required"`
+	IncludeWidgets bool   `json:"include_widgets,omitempty" jsonschema:"Include each widget's title, type, human-readable query and layout"`
+	IncludeData    bool   `json:"include_data,omitempty" jsonschema:"Include the widgets and a compact summary of each widget's current data. Implies include_widgets"`
 }
 
 func HandleGetDashboard(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -69,12 +85,37 @@ func HandleGetDashboard(s ServerInterface, ctx context.Context, req mcp.CallTool
 		return nil, fmt.Errorf("failed to parse input: %w", err)
 	}
 
-	result, err := s.Client().GetDashboardByKey(ctx, input.ReportKey)
+	client := s.Client()
+	reports, err := client.GetDashboardByKey(ctx, input.ReportKey)
 	if err != nil {
 		return nil, err
 	}
+	result := &GetDashboardResult{ReportListResponse: *reports}
+	if !(input.IncludeWidgets || input.IncludeData) || len(reports.Reports) == 0 {
+		return ToStructuredResult(result)
+	}
 
-	return ToTextResult(result)
+	widgets, err := client.GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: reports.Reports[0].ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get widgets: %w", err)
+	}
+	result.Widgets = summarizeWidgets(widgets)
+
+	if input.IncludeData {
+		// Queries referring to dashboard variables get their defaults.
+		vars, err := newWidgetVariables(&reports.Reports[0], widgets, nil)
+		if err != nil {
+			return nil, err
+		}
+		data, err := fetchWidgetData(ctx, client, widgets, vars)
+		if err != nil {
+			return nil, err
+		}
+		for i := range result.Widgets {
+			result.Widgets[i].Data = summarizeWidgetData(data[i])
+		}
+	}
+	return ToStructuredResult(result)
 }
 
 func NewCreateDashboardTool() mcp.Tool {
@@ -84,14 +125,21 @@ func NewCreateDashboardTool() mcp.Tool {
 	
 This tool creates a new dashboard with the specified configuration. Dashboards can be public (shared with team) or private (personal). You can organize dashboards using display scopes and provide custom keys for easier identification.`),
 		mcp.WithInputSchema[CreateDashboardInput](),
+		mcp.WithOutputSchema[middleware.Report](),
+		mcp.WithTitleAnnotation("Create Dashboard"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
 type CreateDashboardInput struct {
-	Label       string `json:"label" jsonschema:"The dashboard name/title. Must be at least 3 characters long,required,minLength=3"`
-	Visibility  string `json:"visibility" jsonschema:"Dashboard visibility setting. Must be either 'public' (shared with team) or 'private' (personal only),required,enum=public,enum=private"`
-	Description string `json:"description,omitempty" jsonschema:"Optional detailed description of the dashboard's purpose and contents"`
-	Key         string `json:"key,omitempty" jsonschema:"Optional unique key identifier for the dashboard. If not provided, will be auto-generated"`
+	Label       string              `json:"label" jsonschema:"The dashboard name/title. Must be at least 3 characters long,required,minLength=3"`
+	Visibility  string              `json:"visibility" jsonschema:"Dashboard visibility setting. Must be either 'public' (shared with team) or 'private' (personal only),required,enum=public,enum=private"`
+	Description string              `json:"description,omitempty" jsonschema:"Optional detailed description of the dashboard's purpose and contents"`
+	Key         string              `json:"key,omitempty" jsonschema:"Optional unique key identifier for the dashboard. If not provided, will be auto-generated"`
+	Variables   []DashboardVariable `json:"variables,omitempty" jsonschema:"Dashboard variables, such as host or service, that widget filters and group by refer to as $name. get_widget_data and get_multi_widget_data substitute their values"`
 }
 
 func HandleCreateDashboard(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -107,13 +155,20 @@ func HandleCreateDashboard(s ServerInterface, ctx context.Context, req mcp.CallT
 		DisplayScope: "", // Always empty string
 		Key:          input.Key,
 	}
+	if len(input.Variables) > 0 {
+		metaData, err := withDashboardVariables(nil, input.Variables)
+		if err != nil {
+			return nil, err
+		}
+		dashboardReq.MetaData = metaData
+	}
 
 	result, err := s.Client().CreateDashboard(ctx, dashboardReq)
 	if err != nil {
 		return nil, err
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
 
 func NewUpdateDashboardTool() mcp.Tool {
@@ -121,17 +176,26 @@ func NewUpdateDashboardTool() mcp.Tool {
 		"update_dashboard",
 		mcp.WithDescription(`Update an existing dashboard's configuration and metadata.
 	
-This tool modifies an existing dashboard identified by its ID. You can update the name, description, visibility settings, and display scope. Use this to rename dashboards, change sharing settings, or reorganize dashboard categories.`),
+This tool modifies an existing dashboard identified by its ID. You can update the name, description, visibility settings, and display scope. Use this to rename dashboards, change sharing settings, or reorganize dashboard categories.
+
+Pass variables to replace the dashboard's variables, which widget filters and group by refer to as $name; an empty list removes them. Without variables, they are left as they are.`),
 		mcp.WithInputSchema[UpdateDashboardInput](),
+		mcp.WithOutputSchema[middleware.Report](),
+		mcp.WithTitleAnnotation("Update Dashboard"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
 type UpdateDashboardInput struct {
-	ID          int    `json:"id" jsonschema:"The numeric ID of the dashboard to update,required"`
-	Label       string `json:"label" jsonschema:"The updated dashboard name/title. Must be at least 3 characters long,required,minLength=3"`
-	Visibility  string `json:"visibility" jsonschema:"Updated visibility setting. Must be either 'public' or 'private',required,enum=public,enum=private"`
-	Description string `json:"description,omitempty" jsonschema:"Updated description of the dashboard"`
-	Key         string `json:"key,omitempty" jsonschema:"Updated unique key identifier. Must be unique across all dashboards"`
+	ID          int                  `json:"id" jsonschema:"The numeric ID of the dashboard to update,required"`
+	Label       string               `json:"label" jsonschema:"The updated dashboard name/title. Must be at least 3 characters long,required,minLength=3"`
+	Visibility  string               `json:"visibility" jsonschema:"Updated visibility setting. Must be either 'public' or 'private',required,enum=public,enum=private"`
+	Description string               `json:"description,omitempty" jsonschema:"Updated description of the dashboard"`
+	Key         string               `json:"key,omitempty" jsonschema:"Updated unique key identifier. Must be unique across all dashboards"`
+	Variables   *[]DashboardVariable `json:"variables,omitempty" jsonschema:"Replaces the dashboard variables that widget filters and group by refer to as $name. An empty list removes them; leave unset to keep them"`
 }
 
 func HandleUpdateDashboard(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -148,13 +212,24 @@ func HandleUpdateDashboard(s ServerInterface, ctx context.Context, req mcp.CallT
 		DisplayScope: "", // Always empty string
 		Key:          input.Key,
 	}
+	if input.Variables != nil {
+		current, err := findDashboardByID(ctx, s.Client(), input.ID)
+		if err != nil {
+			return nil, err
+		}
+		metaData, err := withDashboardVariables(current.MetaData, *input.Variables)
+		if err != nil {
+			return nil, err
+		}
+		dashboardReq.MetaData = metaData
+	}
 
 	result, err := s.Client().UpdateDashboard(ctx, input.ID, dashboardReq)
 	if err != nil {
 		return nil, err
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
 
 func NewDeleteDashboardTool() mcp.Tool {
@@ -164,6 +239,12 @@ func NewDeleteDashboardTool() mcp.Tool {
 	
 This tool removes a dashboard from Middleware.io. Warning: This action cannot be undone. All widgets and configurations associated with the dashboard will be permanently deleted.`),
 		mcp.WithInputSchema[DeleteDashboardInput](),
+		mcp.WithOutputSchema[OperationResult](),
+		mcp.WithTitleAnnotation("Delete Dashboard"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -177,12 +258,39 @@ func HandleDeleteDashboard(s ServerInterface, ctx context.Context, req mcp.CallT
 		return nil, fmt.Errorf("failed to parse input: %w", err)
 	}
 
+	confirm, err := shouldConfirm(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if confirm {
+		dashboard, err := findDashboardByID(ctx, s.Client(), input.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dashboard: %w", err)
+		}
+		widgets, err := s.Client().GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: input.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get widgets: %w", err)
+		}
+
+		message := fmt.Sprintf("Permanently delete dashboard %q (ID %d) and its %d widgets? This cannot be undone.", dashboard.Label, input.ID, len(widgets))
+		confirmed, err := requestConfirmation(ctx, message)
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			return ToStructuredResult(OperationResult{Success: false, Message: "Dashboard deletion cancelled by user"})
+		}
+	}
+	if err := SnapshotDashboards(ctx, s, req); err != nil {
+		return nil, err
+	}
+
 	err = s.Client().DeleteDashboard(ctx, input.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	return ToTextResult(map[string]any{"success": true, "message": "Dashboard deleted successfully"})
+	return ToStructuredResult(OperationResult{Success: true, Message: "Dashboard deleted successfully"})
 }
 
 func NewCloneDashboardTool() mcp.Tool {
@@ -192,6 +300,12 @@ func NewCloneDashboardTool() mcp.Tool {
 	
 This tool duplicates an existing dashboard, creating a new dashboard with the same widgets, layout, and settings. Useful for creating variations of dashboards or starting from a template. The cloned dashboard will have a new ID and can have different visibility settings.`),
 		mcp.WithInputSchema[CloneDashboardInput](),
+		mcp.WithOutputSchema[middleware.Report](),
+		mcp.WithTitleAnnotation("Clone Dashboard"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -221,7 +335,7 @@ func HandleCloneDashboard(s ServerInterface, ctx context.Context, req mcp.CallTo
 		return nil, err
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
 
 func NewSetDashboardFavoriteTool() mcp.Tool {
@@ -231,6 +345,12 @@ func NewSetDashboardFavoriteTool() mcp.Tool {
 	
 This tool allows you to favorite dashboards for quick access. Favorited dashboards appear at the top of dashboard lists and can be filtered using the 'favorite' filter in list_dashboards. Use this to bookmark frequently accessed dashboards.`),
 		mcp.WithInputSchema[SetDashboardFavoriteInput](),
+		mcp.WithOutputSchema[OperationResult](),
+		mcp.WithTitleAnnotation("Set Dashboard Favorite"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -250,5 +370,5 @@ func HandleSetDashboardFavorite(s ServerInterface, ctx context.Context, req mcp.
 		return nil, err
 	}
 
-	return ToTextResult(map[string]any{"success": true, "message": "Dashboard favorite status updated"})
+	return ToStructuredResult(OperationResult{Success: true, Message: "Dashboard favorite status updated"})
 }
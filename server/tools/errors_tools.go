@@ -23,6 +23,12 @@ IMPORTANT - Time Parameters:
 
 IMPORTANT: Each error/incident in the response includes an 'issue_url' field that contains a direct, clickable URL link to view the issue details in the Middleware.io web interface. This URL can be used to redirect users to the full issue details page where they can see complete context, occurrence history, related information, and all technical details. The URL format is: https://[base-url]/ops-ai?fingerprint=[fingerprint]. Always include this URL when presenting error information to users so they can easily navigate to view more details.`),
 		mcp.WithInputSchema[ListErrorsInput](),
+		mcp.WithOutputSchema[middleware.IncidentsResponse](),
+		mcp.WithTitleAnnotation("List Errors"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -33,8 +39,12 @@ type ListErrorsInput struct {
 	Filter string `json:"filter,omitempty" jsonschema:"Optional filter string to narrow down results"`
 	Status string `json:"status" jsonschema:"Filter by status,required,enum=all,enum=for_review,enum=resolved,enum=reviewed,enum=ignored"`
 	Search string `json:"search,omitempty" jsonschema:"Search term to filter incidents by title or description"`
+	Pages  int    `json:"pages,omitempty" jsonschema:"Number of consecutive pages to fetch starting at page, merged into one list (default: 1, max: 10),minimum=1,maximum=10"`
 }
 
+// maxErrorPages is the most pages list_errors fetches in one call.
+const maxErrorPages = 10
+
 func HandleListErrors(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	input, err := ParseInput[ListErrorsInput](req)
 	if err != nil {
@@ -56,12 +66,40 @@ func HandleListErrors(s ServerInterface, ctx context.Context, req mcp.CallToolRe
 		Search: input.Search,
 	}
 
-	result, err := s.Client().GetIncidents(ctx, params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get errors/incidents: %w", err)
+	pages := min(max(input.Pages, 1), maxErrorPages)
+	if pages == 1 {
+		result, err := s.Client().GetIncidents(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get errors/incidents: %w", err)
+		}
+		return ToStructuredResult(result)
+	}
+
+	// Fetch the pages in turn, stopping early at the last one: an empty or
+	// short page, or one that reaches the total counting the pages before
+	// the starting one. The first page tells the page size.
+	progress := NewProgress(ctx, req, pages)
+	result := &middleware.IncidentsResponse{}
+	pageSize := 0
+	for n := 1; n <= pages; n++ {
+		params.Page = page + n - 1
+		pageResult, err := s.Client().GetIncidents(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get errors/incidents page %d: %w", params.Page, err)
+		}
+		result.Items = append(result.Items, pageResult.Items...)
+		result.TotalRecords = pageResult.TotalRecords
+		progress.Step(fmt.Sprintf("page %d of %d of incidents", n, pages))
+		if n == 1 {
+			pageSize = len(pageResult.Items)
+		}
+		seen := (page-1)*pageSize + len(result.Items)
+		if len(pageResult.Items) == 0 || len(pageResult.Items) < pageSize || (pageResult.TotalRecords > 0 && seen >= pageResult.TotalRecords) {
+			break
+		}
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
 
 func NewGetErrorDetailsTool() mcp.Tool {
@@ -76,6 +114,12 @@ IMPORTANT - Time Parameters:
 - Example: For January 1, 2024 00:00:00 UTC, use 1704067200000 (not 1704067200)
 - To convert seconds to milliseconds, multiply by 1000`),
 		mcp.WithInputSchema[GetErrorDetailsInput](),
+		mcp.WithOutputSchema[map[string]any](),
+		mcp.WithTitleAnnotation("Get Error Details"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -104,5 +148,5 @@ func HandleGetErrorDetails(s ServerInterface, ctx context.Context, req mcp.CallT
 		return nil, fmt.Errorf("failed to get error details: %w", err)
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
@@ -30,6 +30,42 @@ func ToTextResult(v any) (*mcp.CallToolResult, error) {
 	return mcp.NewToolResultText(string(jsonData)), nil
 }
 
+// ToStructuredResult returns v as structuredContent, with its JSON encoding as
+// the text fallback for clients that don't read structured output.
+func ToStructuredResult(v any) (*mcp.CallToolResult, error) {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return mcp.NewToolResultStructured(v, string(jsonData)), nil
+}
+
+// OperationResult is the result of tools that don't return an API object.
+type OperationResult struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// InputError is returned by tools for arguments they can't act on, as opposed
+// to failures of the Middleware API.
+type InputError struct {
+	err error
+}
+
+func (e *InputError) Error() string {
+	return e.err.Error()
+}
+
+func (e *InputError) Unwrap() error {
+	return e.err
+}
+
+// invalidInput returns an InputError with the formatted message.
+func invalidInput(format string, args ...any) error {
+	return &InputError{err: fmt.Errorf(format, args...)}
+}
+
 // ParseInput parses the arguments from a CallToolRequest into the target struct
 func ParseInput[T any](req mcp.CallToolRequest) (T, error) {
 	var input T
@@ -41,7 +77,7 @@ func ParseInput[T any](req mcp.CallToolRequest) (T, error) {
 	}
 
 	if err := json.Unmarshal(argsJSON, &input); err != nil {
-		return input, fmt.Errorf("failed to unmarshal arguments: %w", err)
+		return input, invalidInput("failed to unmarshal arguments: %w", err)
 	}
 
 	return input, nil
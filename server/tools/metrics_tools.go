@@ -70,6 +70,12 @@ Data Type Options:
 Resource Selection:
 - Use exact resource names returned by 'get_resources'.`),
 		mcp.WithInputSchema[GetMetricsInput](),
+		mcp.WithOutputSchema[middleware.MetricsV2Response](),
+		mcp.WithTitleAnnotation("Get Metrics"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -122,7 +128,7 @@ func HandleGetMetrics(s ServerInterface, ctx context.Context, req mcp.CallToolRe
 		return nil, fmt.Errorf("failed to get metrics: %w", err)
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
 
 func NewGetResourcesTool() mcp.Tool {
@@ -134,11 +140,21 @@ This tool returns all resource types that have data in your monitoring environme
 
 Example resources: host, container, pod, service, database, redis, mongodb, postgresql, mysql, nginx, etc.`),
 		mcp.WithInputSchema[GetResourcesInput](),
+		mcp.WithOutputSchema[GetResourcesResult](),
+		mcp.WithTitleAnnotation("Get Resources"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
 type GetResourcesInput struct{}
 
+type GetResourcesResult struct {
+	Resources []string `json:"resources"`
+}
+
 func HandleGetResources(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	input, err := ParseInput[GetResourcesInput](req)
 	if err != nil {
@@ -151,7 +167,7 @@ func HandleGetResources(s ServerInterface, ctx context.Context, req mcp.CallTool
 		return nil, fmt.Errorf("failed to get resources: %w", err)
 	}
 
-	return ToTextResult(map[string]any{"resources": result})
+	return ToStructuredResult(GetResourcesResult{Resources: result})
 }
 
 func NewQueryTool() mcp.Tool {
@@ -177,6 +193,12 @@ Use cases:
 - Group results by dimensions for aggregation
 - Query multiple data types in a single request`),
 		mcp.WithInputSchema[QueryInput](),
+		mcp.WithOutputSchema[middleware.QueryResponse](),
+		mcp.WithTitleAnnotation("Query Data"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -221,18 +243,19 @@ func HandleQuery(s ServerInterface, ctx context.Context, req mcp.CallToolRequest
 		}
 	}
 
-	queryReq := &middleware.QueryRequest{
-		Queries: queries,
-	}
+	// The queries go upstream in one batched request; progress reports it
+	// being sent and answered.
+	progress := NewProgress(ctx, req, 1)
+	progress.Report(fmt.Sprintf("running %d queries", len(queries)))
 
-	result, err := s.Client().Query(ctx, queryReq)
+	result, err := s.Client().Query(ctx, &middleware.QueryRequest{Queries: queries})
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
-
 	if result == nil {
 		return nil, fmt.Errorf("query returned nil result")
 	}
+	progress.Step(fmt.Sprintf("ran %d queries", len(queries)))
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"log"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Progress sends notifications/progress for a tool call. It is a no-op when the
+// client didn't send a progress token with the request.
+type Progress struct {
+	ctx   context.Context
+	token mcp.ProgressToken
+	total float64
+	done  float64
+}
+
+// NewProgress creates a Progress for req with the given number of steps.
+// A total of 0 means the number of steps is not known up front.
+func NewProgress(ctx context.Context, req mcp.CallToolRequest, total int) *Progress {
+	p := &Progress{ctx: ctx, total: float64(total)}
+	if req.Params.Meta != nil {
+		p.token = req.Params.Meta.ProgressToken
+	}
+	return p
+}
+
+// Step marks one more step as done and reports it with message.
+func (p *Progress) Step(message string) {
+	p.done++
+	p.send(message)
+}
+
+// Report sends message without marking a step as done, for work that is
+// about to start.
+func (p *Progress) Report(message string) {
+	p.send(message)
+}
+
+func (p *Progress) send(message string) {
+	if p.token == nil {
+		return
+	}
+	mcpServer := server.ServerFromContext(p.ctx)
+	if mcpServer == nil {
+		return
+	}
+
+	params := map[string]any{
+		"progressToken": p.token,
+		"progress":      p.done,
+		"message":       message,
+	}
+	if p.total > 0 {
+		params["total"] = p.total
+	}
+	if err := mcpServer.SendNotificationToClient(p.ctx, "notifications/progress", params); err != nil {
+		log.Printf("Failed to send progress notification: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 
+	"mcp-middleware/config"
 	"mcp-middleware/middleware"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -11,6 +12,7 @@ import (
 // ServerInterface defines the interface that tool handlers need from the server
 type ServerInterface interface {
 	Client() *middleware.Client
+	Config() *config.Config
 }
 
 // ToolHandler is a function type for tool handlers
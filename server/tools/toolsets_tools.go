@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ToolsetManager is implemented by servers that can enable toolsets at runtime.
+type ToolsetManager interface {
+	ListToolsets() []ToolsetInfo
+	EnableToolset(name string) ([]string, error)
+}
+
+type ToolsetInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Enabled     bool     `json:"enabled"`
+	Tools       []string `json:"tools"`
+}
+
+func toolsetManager(s ServerInterface) (ToolsetManager, error) {
+	manager, ok := s.(ToolsetManager)
+	if !ok {
+		return nil, fmt.Errorf("this server does not support enabling toolsets")
+	}
+	return manager, nil
+}
+
+func NewListToolsetsTool() mcp.Tool {
+	return mcp.NewTool(
+		"list_toolsets",
+		mcp.WithDescription(`List the groups of tools (toolsets) that can be enabled on this server.
+
+The server starts with only list_toolsets and enable_toolset. Each toolset groups related tools, such as 'dashboards' or 'metrics'; the response lists each toolset's tools and whether it is already enabled. Call enable_toolset with a toolset's name to make its tools available.`),
+		mcp.WithInputSchema[ListToolsetsInput](),
+		mcp.WithOutputSchema[ListToolsetsResult](),
+		mcp.WithTitleAnnotation("List Toolsets"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(false),
+	)
+}
+
+type ListToolsetsInput struct{}
+
+type ListToolsetsResult struct {
+	Toolsets []ToolsetInfo `json:"toolsets"`
+}
+
+func HandleListToolsets(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	manager, err := toolsetManager(s)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ListToolsetsResult{Toolsets: manager.ListToolsets()}
+	if result.Toolsets == nil {
+		result.Toolsets = []ToolsetInfo{}
+	}
+	return ToStructuredResult(result)
+}
+
+func NewEnableToolsetTool() mcp.Tool {
+	return mcp.NewTool(
+		"enable_toolset",
+		mcp.WithDescription(`Enable a toolset, making its tools available.
+
+Use list_toolsets to see the toolsets and the tools in each. After a toolset is enabled the server sends notifications/tools/list_changed, and its tools appear in the tool list. Enabling a toolset that is already enabled has no effect.`),
+		mcp.WithInputSchema[EnableToolsetInput](),
+		mcp.WithOutputSchema[EnableToolsetResult](),
+		mcp.WithTitleAnnotation("Enable Toolset"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(false),
+	)
+}
+
+type EnableToolsetInput struct {
+	Toolset string `json:"toolset" jsonschema:"Name of the toolset to enable, as returned by list_toolsets,required"`
+}
+
+type EnableToolsetResult struct {
+	Toolset string   `json:"toolset"`
+	Tools   []string `json:"tools"`
+}
+
+func HandleEnableToolset(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := ParseInput[EnableToolsetInput](req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	manager, err := toolsetManager(s)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := manager.EnableToolset(input.Toolset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable toolset: %w", err)
+	}
+
+	return ToStructuredResult(EnableToolsetResult{Toolset: input.Toolset, Tools: names})
+}
@@ -19,6 +19,12 @@ func NewListWidgetsTool() mcp.Tool {
 	
 This tool retrieves all widgets (charts, graphs, tables) that belong to a dashboard or scope. Widgets are the building blocks of dashboards - each widget represents a visualization of your monitoring data. Use this to discover what widgets are available in a dashboard or to inspect widget configurations.`),
 		mcp.WithInputSchema[ListWidgetsInput](),
+		mcp.WithOutputSchema[ListWidgetsResult](),
+		mcp.WithTitleAnnotation("List Widgets"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -28,6 +34,10 @@ type ListWidgetsInput struct {
 	Message      string `json:"message" jsonschema:"Message to know which widgets are being listed. Length should be less than 100 characters."`
 }
 
+type ListWidgetsResult struct {
+	Widgets []middleware.Widget `json:"widgets"`
+}
+
 func HandleListWidgets(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	input, err := ParseInput[ListWidgetsInput](req)
 	if err != nil {
@@ -44,7 +54,7 @@ func HandleListWidgets(s ServerInterface, ctx context.Context, req mcp.CallToolR
 		return nil, fmt.Errorf("failed to get widgets: %w", err)
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(ListWidgetsResult{Widgets: result})
 }
 
 func NewCreateWidgetTool() mcp.Tool {
@@ -74,6 +84,12 @@ IMPORTANT - Validation Rules:
 
 Use this tool to build rich, data-driven dashboards by combining resources, metrics, and visualizations.`),
 		mcp.WithInputSchema[CreateWidgetInput](),
+		mcp.WithOutputSchema[middleware.Widget](),
+		mcp.WithTitleAnnotation("Create Widget"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -85,27 +101,62 @@ func generateWidgetKey(label string) string {
 	return fmt.Sprintf("%s_%s", cleaned, randomID)
 }
 
+// widgetTypeIDs maps widget types to their WidgetAppID.
+var widgetTypeIDs = map[string]int{
+	"time_series_chart": 1,
+	"bar_chart":         2,
+	"pie_chart":         3,
+	"scatter_plot":      4,
+	"data_table":        5,
+	"count_chart":       7,
+	"tree_chart":        8,
+	"top_list_chart":    9,
+	"heatmap_chart":     10,
+	"hexagon_chart":     11,
+	"query_value":       12,
+}
+
 func getWidgetAppID(widgetType string) int {
-	widgetTypeMap := map[string]int{
-		"time_series_chart": 1,
-		"bar_chart":         2,
-		"pie_chart":         3,
-		"scatter_plot":      4,
-		"data_table":        5,
-		"count_chart":       7,
-		"tree_chart":        8,
-		"top_list_chart":    9,
-		"heatmap_chart":     10,
-		"hexagon_chart":     11,
-		"query_value":       12,
-	}
-
-	if id, ok := widgetTypeMap[widgetType]; ok {
+	if id, ok := widgetTypeIDs[widgetType]; ok {
 		return id
 	}
 	return 1
 }
 
+// warnUnknownWidgetType warns the client when a widget type isn't recognised and
+// getWidgetAppID falls back to a time series chart.
+func warnUnknownWidgetType(ctx context.Context, s ServerInterface, widgetType string) {
+	if _, ok := widgetTypeIDs[widgetType]; !ok {
+		s.Client().Log(ctx, middleware.LogLevelWarning, "unknown widget type, using time_series_chart", map[string]any{"widget_type": widgetType})
+	}
+}
+
+// widgetLayout converts a requested layout into the widget's layout, enlarging it
+// to the minimum widget size (4x6) and warning the client when it had to. A nil
+// layout gets the minimum size.
+func widgetLayout(ctx context.Context, s ServerInterface, input *LayoutItemInput) *middleware.LayoutItem {
+	layout := &middleware.LayoutItem{X: 0, Y: 0, W: 4, H: 6}
+	if input == nil {
+		return layout
+	}
+
+	if input.W > layout.W {
+		layout.W = input.W
+	}
+	if input.H > layout.H {
+		layout.H = input.H
+	}
+	if layout.W != input.W || layout.H != input.H {
+		s.Client().Log(ctx, middleware.LogLevelWarning, "widget layout is smaller than the minimum size and was enlarged", map[string]any{
+			"requested_w": input.W,
+			"requested_h": input.H,
+			"w":           layout.W,
+			"h":           layout.H,
+		})
+	}
+	return layout
+}
+
 type CreateWidgetInput struct {
 	Label             string                   `json:"label" jsonschema:"The display name for the widget (e.g., 'CPU Usage', 'Error Rate'),required"`
 	WidgetType        string                   `json:"widget_type" jsonschema:"The type of chart/widget to create,required,enum=time_series_chart,enum=bar_chart,enum=data_table,enum=query_value,enum=pie_chart,enum=scatter_plot,enum=count_chart,enum=tree_chart,enum=top_list_chart,enum=heatmap_chart,enum=hexagon_chart"`
@@ -323,32 +374,9 @@ func HandleCreateWidget(s ServerInterface, ctx context.Context, req mcp.CallTool
 	builderConfig := convertToMiddlewareBuilderConfig(input.BuilderConfig)
 
 	widgetAppID := getWidgetAppID(input.WidgetType)
+	warnUnknownWidgetType(ctx, s, input.WidgetType)
 
-	defaultLayout := middleware.LayoutItem{
-		X: 0,
-		Y: 0,
-		W: 4,
-		H: 6,
-	}
-
-	var layout *middleware.LayoutItem
-	if input.Layout != nil {
-		layout = &middleware.LayoutItem{
-			X: defaultLayout.X,
-			Y: defaultLayout.Y,
-			W: input.Layout.W,
-			H: input.Layout.H,
-		}
-
-		if layout.W < defaultLayout.W {
-			layout.W = defaultLayout.W
-		}
-		if layout.H < defaultLayout.H {
-			layout.H = defaultLayout.H
-		}
-	} else {
-		layout = &defaultLayout
-	}
+	layout := widgetLayout(ctx, s, input.Layout)
 
 	widget := &middleware.CustomWidget{
 		Label:              input.Label,
@@ -373,7 +401,7 @@ func HandleCreateWidget(s ServerInterface, ctx context.Context, req mcp.CallTool
 		return nil, fmt.Errorf("failed to create widget: %w", err)
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
 
 func NewUpdateWidgetTool() mcp.Tool {
@@ -399,6 +427,12 @@ IMPORTANT - Layout Requirements:
 - Based on the widget type, you MUST set proper layout. Width (w) must be minimum 4 (this is a strict minimum requirement) and height (h) must be minimum 6 (this is a strict minimum requirement). The layout dimensions should be appropriate for the widget type to ensure proper visualization.
 `),
 		mcp.WithInputSchema[UpdateWidgetInput](),
+		mcp.WithOutputSchema[middleware.Widget](),
+		mcp.WithTitleAnnotation("Update Widget"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -425,7 +459,7 @@ func HandleUpdateWidget(s ServerInterface, ctx context.Context, req mcp.CallTool
 	}
 
 	if input.BuilderID <= 0 {
-		return nil, fmt.Errorf("builder_id is required for updating a widget")
+		return nil, invalidInput("builder_id is required for updating a widget")
 	}
 
 	var builderViewOptions *middleware.BuilderViewOptions
@@ -467,32 +501,12 @@ func HandleUpdateWidget(s ServerInterface, ctx context.Context, req mcp.CallTool
 	// Set widget type if provided
 	if input.WidgetType != "" {
 		widget.WidgetAppID = getWidgetAppID(input.WidgetType)
+		warnUnknownWidgetType(ctx, s, input.WidgetType)
 	}
 
 	// Set layout if provided
 	if input.Layout != nil {
-		defaultLayout := middleware.LayoutItem{
-			X: 0,
-			Y: 0,
-			W: 4,
-			H: 6,
-		}
-
-		layout := &middleware.LayoutItem{
-			X: defaultLayout.X,
-			Y: defaultLayout.Y,
-			W: input.Layout.W,
-			H: input.Layout.H,
-		}
-
-		if layout.W < defaultLayout.W {
-			layout.W = defaultLayout.W
-		}
-		if layout.H < defaultLayout.H {
-			layout.H = defaultLayout.H
-		}
-
-		widget.Layout = layout
+		widget.Layout = widgetLayout(ctx, s, input.Layout)
 	}
 
 	result, err := s.Client().UpdateWidget(ctx, widget)
@@ -500,7 +514,7 @@ func HandleUpdateWidget(s ServerInterface, ctx context.Context, req mcp.CallTool
 		return nil, fmt.Errorf("failed to update widget: %w", err)
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
 
 func NewDeleteWidgetTool() mcp.Tool {
@@ -510,13 +524,20 @@ func NewDeleteWidgetTool() mcp.Tool {
 	
 This tool removes a widget (chart, graph, table) from its dashboard. Warning: This action cannot be undone. The widget configuration and data will be permanently deleted.`),
 		mcp.WithInputSchema[DeleteWidgetInput](),
+		mcp.WithOutputSchema[OperationResult](),
+		mcp.WithTitleAnnotation("Delete Widget"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
 type DeleteWidgetInput struct {
 	BuilderID   int    `json:"builder_id" jsonschema:"The numeric builder ID of the widget to delete permanently,required"`
-	Message     string `json:"message" jsonschema:"Message to know which widget is being deleted."`
-	WidgetLabel string `json:"widget_label" jsonschema:"Label of the widget to delete."`
+	ReportKey   string `json:"report_key,omitempty" jsonschema:"The unique key of the dashboard the widget is on. Needed when the user is asked to confirm the deletion"`
+	Message     string `json:"message" jsonschema:"Message to know which widget is being deleted. Shown to the user when asking to confirm the deletion."`
+	WidgetLabel string `json:"widget_label,omitempty" jsonschema:"Deprecated and ignored: the label shown when asking to confirm is looked up on the dashboard"`
 }
 
 func HandleDeleteWidget(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -525,12 +546,63 @@ func HandleDeleteWidget(s ServerInterface, ctx context.Context, req mcp.CallTool
 		return nil, fmt.Errorf("failed to parse input: %w", err)
 	}
 
+	confirm, err := shouldConfirm(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if confirm {
+		if input.ReportKey == "" {
+			return nil, invalidInput("report_key is required to confirm deleting widget %d", input.BuilderID)
+		}
+		report, widget, err := findDashboardWidget(ctx, s.Client(), input.ReportKey, input.BuilderID)
+		if err != nil {
+			return nil, err
+		}
+
+		message := fmt.Sprintf("Permanently delete widget %q (ID %d) from dashboard %q? This cannot be undone.", widget.Label, widget.ID, report.Label)
+		if input.Message != "" {
+			message += "\n\n" + input.Message
+		}
+		confirmed, err := requestConfirmation(ctx, message)
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			return ToStructuredResult(OperationResult{Success: false, Message: "Widget deletion cancelled by user"})
+		}
+	}
+	if err := SnapshotDashboards(ctx, s, req); err != nil {
+		return nil, err
+	}
+
 	err = s.Client().DeleteWidget(ctx, input.BuilderID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete widget: %w", err)
 	}
 
-	return ToTextResult(map[string]any{"success": true, "message": "Widget deleted successfully"})
+	return ToStructuredResult(OperationResult{Success: true, Message: "Widget deleted successfully"})
+}
+
+// findDashboardWidget looks up the widget with builderID among the widgets of
+// the dashboard with reportKey, since the API doesn't look widgets up by ID.
+func findDashboardWidget(ctx context.Context, client *middleware.Client, reportKey string, builderID int) (*middleware.Report, *middleware.Widget, error) {
+	report, err := getDashboardByKey(ctx, client, reportKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up dashboard %s: %w", reportKey, err)
+	}
+	if report == nil {
+		return nil, nil, fmt.Errorf("dashboard %s not found", reportKey)
+	}
+	widgets, err := client.GetWidgets(ctx, &middleware.GetWidgetsParams{ReportID: report.ID})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get widgets: %w", err)
+	}
+	for i := range widgets {
+		if widgets[i].ID == builderID {
+			return report, &widgets[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("widget %d not found on dashboard %s", builderID, reportKey)
 }
 
 func NewGetWidgetDataTool() mcp.Tool {
@@ -540,6 +612,12 @@ func NewGetWidgetDataTool() mcp.Tool {
 	
 This tool executes the widget's query and returns the visualization data (time series, metrics, logs, traces). Use this to get the current values shown in a widget, analyze trends, or export widget data. The data format depends on the widget type (timeseries, table, single value, etc.).`),
 		mcp.WithInputSchema[GetWidgetDataInput](),
+		mcp.WithOutputSchema[middleware.BuilderDataResponse](),
+		mcp.WithTitleAnnotation("Get Widget Data"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -549,6 +627,8 @@ type GetWidgetDataInput struct {
 	Label         string                   `json:"label,omitempty" jsonschema:"Alternative to builder_id: the label of the widget"`
 	BuilderConfig []BuilderConfigItemInput `json:"builder_config,omitempty" jsonschema:"Widget configuration array containing the query and data source settings. Each item's columns MUST be an object with name, aggregation_method, and rollup_method."`
 	UseV2         bool                     `json:"use_v2,omitempty" jsonschema:"Set to true to use the newer v2 data format (default: false)"`
+	ReportKey     string                   `json:"report_key,omitempty" jsonschema:"Key of the dashboard whose variables apply: their defaults fill in values not given, and without builder_config the widget's own query is used"`
+	Variables     map[string]string        `json:"variables,omitempty" jsonschema:"Values of dashboard variables, by name, substituted for $name in filter_with and group_by. Conditions and group by on a variable with an empty value are left out"`
 }
 
 func HandleGetWidgetData(s ServerInterface, ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -570,12 +650,20 @@ func HandleGetWidgetData(s ServerInterface, ctx context.Context, req mcp.CallToo
 		UseV2:         input.UseV2,
 	}
 
+	vars, err := loadWidgetVariables(ctx, s.Client(), input.ReportKey, input.Variables)
+	if err != nil {
+		return nil, err
+	}
+	if err := vars.apply(widget); err != nil {
+		return nil, err
+	}
+
 	result, err := s.Client().GetWidgetData(ctx, widget)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get widget data: %w", err)
 	}
 
-	return ToTextResult(result)
+	return ToStructuredResult(result)
 }
 
 func NewGetMultiWidgetDataTool() mcp.Tool {
@@ -585,11 +673,23 @@ func NewGetMultiWidgetDataTool() mcp.Tool {
 	
 This tool is optimized for loading data for multiple widgets at once, such as when refreshing an entire dashboard. It's more efficient than calling get_widget_data multiple times. Returns data for all requested widgets in a single response.`),
 		mcp.WithInputSchema[GetMultiWidgetDataInput](),
+		mcp.WithOutputSchema[GetMultiWidgetDataResult](),
+		mcp.WithTitleAnnotation("Get Multiple Widget Data"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
 type GetMultiWidgetDataInput struct {
-	Widgets []WidgetDataRequest `json:"widgets" jsonschema:"Array of widget specifications to fetch data for. Each widget can be identified by builder_id, key, or label,required"`
+	Widgets   []WidgetDataRequest `json:"widgets" jsonschema:"Array of widget specifications to fetch data for. Each widget can be identified by builder_id, key, or label,required"`
+	ReportKey string              `json:"report_key,omitempty" jsonschema:"Key of the dashboard whose variables apply: their defaults fill in values not given, and widgets without builder_config use their own query"`
+	Variables map[string]string   `json:"variables,omitempty" jsonschema:"Values of dashboard variables, by name, substituted for $name in every widget's filter_with and group_by. Conditions and group by on a variable with an empty value are left out"`
+}
+
+type GetMultiWidgetDataResult struct {
+	Widgets []middleware.BuilderDataResponse `json:"widgets"`
 }
 
 type WidgetDataRequest struct {
@@ -622,13 +722,28 @@ func HandleGetMultiWidgetData(s ServerInterface, ctx context.Context, req mcp.Ca
 		}
 	}
 
+	vars, err := loadWidgetVariables(ctx, s.Client(), input.ReportKey, input.Variables)
+	if err != nil {
+		return nil, err
+	}
+	for i := range widgets {
+		if err := vars.apply(&widgets[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	// The widgets go upstream in one batched request; progress reports it
+	// being sent and answered.
+	progress := NewProgress(ctx, req, 1)
+	progress.Report(fmt.Sprintf("fetching %d widgets", len(widgets)))
+
 	result, err := s.Client().GetMultiWidgetData(ctx, widgets)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get multi widget data: %w", err)
 	}
+	progress.Step(fmt.Sprintf("fetched %d widgets", len(widgets)))
 
-	// Return as JSON text only (no structuredContent)
-	return ToTextResult(map[string]any{"widgets": result})
+	return ToStructuredResult(GetMultiWidgetDataResult{Widgets: result})
 }
 
 func NewUpdateWidgetLayoutsTool() mcp.Tool {
@@ -638,6 +753,12 @@ func NewUpdateWidgetLayoutsTool() mcp.Tool {
 	
 This tool modifies the layout (position, size) of multiple widgets on a dashboard. Use this to rearrange widgets, resize them, or optimize dashboard layout. The dashboard uses a grid system where x,y represent position and w,h represent size in grid units. IMPORTANT: Based on the widget type, you MUST set proper layout. Width (w) must be minimum 4 (this is a strict minimum requirement) and height (h) must be minimum 6 (this is a strict minimum requirement). The layout dimensions should be appropriate for the widget type to ensure proper visualization.`),
 		mcp.WithInputSchema[UpdateWidgetLayoutsInput](),
+		mcp.WithOutputSchema[OperationResult](),
+		mcp.WithTitleAnnotation("Update Widget Layouts"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
 	)
 }
 
@@ -681,5 +802,5 @@ func HandleUpdateWidgetLayouts(s ServerInterface, ctx context.Context, req mcp.C
 		return nil, fmt.Errorf("failed to update widget layouts: %w", err)
 	}
 
-	return ToTextResult(map[string]any{"success": true, "message": input.OperationMessage})
+	return ToStructuredResult(OperationResult{Success: true, Message: input.OperationMessage})
 }
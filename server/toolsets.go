@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"mcp-middleware/config"
+	"mcp-middleware/server/tools"
+)
+
+// toolsetDescriptions are shown to clients by list_toolsets.
+var toolsetDescriptions = map[string]string{
+	config.ToolsetDashboards: "List, create, update, clone and delete dashboards",
+	config.ToolsetWidgets:    "Create and manage dashboard widgets and fetch their data",
+	config.ToolsetMetrics:    "Discover metrics and resources and run queries over logs, metrics and traces",
+	config.ToolsetAlerts:     "List and create alerts and get alert statistics",
+	config.ToolsetErrors:     "List errors/incidents and get their details",
+	config.ToolsetAccounts:   "List the configured Middleware accounts",
+}
+
+// ListToolsets describes every toolset the configuration makes available.
+func (s *Server) ListToolsets() []tools.ToolsetInfo {
+	s.toolsetsMu.Lock()
+	defer s.toolsetsMu.Unlock()
+
+	var toolsets []tools.ToolsetInfo
+	for _, name := range config.Toolsets {
+		if !s.Config().IsToolsetEnabled(name) {
+			continue
+		}
+		info := tools.ToolsetInfo{
+			Name:        name,
+			Description: toolsetDescriptions[name],
+			Enabled:     s.enabledToolsets[name],
+			Tools:       []string{},
+		}
+		for _, tool := range s.toolsetTools(name) {
+			info.Tools = append(info.Tools, tool.Tool.Name)
+		}
+		toolsets = append(toolsets, info)
+	}
+	return toolsets
+}
+
+// EnableToolset registers the tools of a toolset and returns their names.
+// Registering tools sends notifications/tools/list_changed to connected clients.
+func (s *Server) EnableToolset(name string) ([]string, error) {
+	if !isToolset(name) {
+		return nil, fmt.Errorf("unknown toolset: %s (must be one of %s)", name, strings.Join(config.Toolsets, ", "))
+	}
+
+	s.toolsetsMu.Lock()
+	defer s.toolsetsMu.Unlock()
+
+	if !s.Config().IsToolsetEnabled(name) {
+		return nil, fmt.Errorf("toolset %s is not available (see ENABLED_TOOLSETS)", name)
+	}
+
+	serverTools := s.toolsetTools(name)
+	names := make([]string, 0, len(serverTools))
+	for _, tool := range serverTools {
+		names = append(names, tool.Tool.Name)
+	}
+
+	if s.enabledToolsets[name] {
+		return names, nil
+	}
+	s.enabledToolsets[name] = true
+	if len(serverTools) > 0 {
+		s.mcpServer.AddTools(serverTools...)
+	}
+	return names, nil
+}
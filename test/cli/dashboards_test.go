@@ -0,0 +1,197 @@
+package cli_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDashboardsExportCommand(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/builder/report/infra":
+			w.Write([]byte(`{"reports": [{"id": 7, "key": "infra", "label": "Infra", "visibility": "public"}], "total": 1}`))
+		case "/api/v1/builder/widget":
+			w.Write([]byte(`[{"id": 20, "key": "cpu", "label": "CPU", "widget_app_id": 1, "config": {"layout": {"x": 0, "y": 0, "w": 6, "h": 6}}}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "not found"}`))
+		}
+	}))
+	defer api.Close()
+	t.Setenv("MIDDLEWARE_API_KEY", "test-key")
+	t.Setenv("MIDDLEWARE_BASE_URL", api.URL)
+
+	code, stdout, stderr := runCLI(t, "dashboards", "export", "infra")
+	if code != 0 {
+		t.Fatalf("dashboards export exited %d: %s", code, stderr)
+	}
+	var doc struct {
+		Version int    `json:"version"`
+		Label   string `json:"label"`
+		Widgets []struct {
+			Label      string `json:"label"`
+			WidgetType string `json:"widget_type"`
+		} `json:"widgets"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("Expected a JSON document, got %s: %v", stdout, err)
+	}
+	if doc.Version != 1 || doc.Label != "Infra" || len(doc.Widgets) != 1 || doc.Widgets[0].WidgetType != "time_series_chart" {
+		t.Errorf("Unexpected document: %+v", doc)
+	}
+
+	out := filepath.Join(t.TempDir(), "infra.json")
+	code, stdout, stderr = runCLI(t, "dashboards", "export", "infra", "--out", out)
+	if code != 0 || stdout != "" {
+		t.Fatalf("dashboards export --out exited %d with stdout %q: %s", code, stdout, stderr)
+	}
+	written, err := os.ReadFile(out)
+	if err != nil || !strings.Contains(string(written), `"label": "Infra"`) {
+		t.Errorf("Expected the document in %s, got %s: %v", out, written, err)
+	}
+
+	if code, _, stderr := runCLI(t, "dashboards", "export", "missing"); code != 1 || !strings.Contains(stderr, "export failed") {
+		t.Errorf("Expected exit code 1 for a missing dashboard, got %d: %s", code, stderr)
+	}
+	if code, _, _ := runCLI(t, "dashboards", "nope"); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown subcommand, got %d", code)
+	}
+}
+
+func TestDashboardsImportCommand(t *testing.T) {
+	var writes []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			writes = append(writes, r.Method+" "+r.URL.Path)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/builder/report/infra":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "not found"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/builder/report":
+			w.Write([]byte(`{"id": 12, "key": "infra", "label": "Infra"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/builder/widget":
+			w.Write([]byte(`{"id": 30, "key": "cpu", "label": "CPU", "scope": {"id": 31}}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer api.Close()
+	t.Setenv("MIDDLEWARE_API_KEY", "test-key")
+	t.Setenv("MIDDLEWARE_BASE_URL", api.URL)
+	t.Setenv("DASHBOARD_HISTORY_DIR", t.TempDir())
+
+	file := filepath.Join(t.TempDir(), "infra.json")
+	document := `{"version": 1, "key": "infra", "label": "Infra", "widgets": [{"key": "cpu", "label": "CPU", "widget_type": "time_series_chart", "layout": {"x": 0, "y": 0, "w": 6, "h": 6}}]}`
+	if err := os.WriteFile(file, []byte(document), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI(t, "dashboards", "import", file, "--dry-run")
+	if code != 0 || !strings.Contains(stdout, "Would create dashboard infra") || len(writes) != 0 {
+		t.Fatalf("dashboards import --dry-run exited %d with writes %v: %s%s", code, writes, stdout, stderr)
+	}
+
+	code, stdout, stderr = runCLI(t, "dashboards", "import", file)
+	if code != 0 || !strings.Contains(stdout, "Created dashboard infra") || !strings.Contains(stdout, "CPU (cpu)") {
+		t.Fatalf("dashboards import exited %d: %s%s", code, stdout, stderr)
+	}
+	if len(writes) != 3 {
+		t.Errorf("Expected the dashboard, widget and layout writes, got %v", writes)
+	}
+
+	if code, _, stderr := runCLI(t, "dashboards", "import", file, "--on-conflict", "merge"); code != 1 || !strings.Contains(stderr, "on_conflict") {
+		t.Errorf("Expected exit code 1 for an invalid policy, got %d: %s", code, stderr)
+	}
+}
+
+func TestDashboardsPlanAndApplyCommands(t *testing.T) {
+	var writes []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			writes = append(writes, r.Method+" "+r.URL.Path)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/builder/report":
+			w.Write([]byte(`{"reports": [{"id": 7, "key": "infra", "label": "Infra", "visibility": "public", "meta_data": {"managed_by": "mcp-middleware"}}], "total": 1}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/builder/widget":
+			w.Write([]byte(`[{"id": 50, "key": "cpu", "label": "CPU", "widget_app_id": 1, "scope": {"id": 500}}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/builder/widget":
+			w.Write([]byte(`{"id": 51, "key": "memory", "label": "Memory", "scope": {"id": 501}}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer api.Close()
+	t.Setenv("MIDDLEWARE_API_KEY", "test-key")
+	t.Setenv("MIDDLEWARE_BASE_URL", api.URL)
+	t.Setenv("DASHBOARD_HISTORY_DIR", t.TempDir())
+
+	dir := t.TempDir()
+	document := `key: infra
+label: Infra
+widgets:
+  - {key: cpu, label: CPU}
+  - {key: memory, label: Memory, widget_type: bar_chart, layout: {x: 6, y: 0, w: 6, h: 6}}
+`
+	if err := os.WriteFile(filepath.Join(dir, "infra.yaml"), []byte(document), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI(t, "dashboards", "plan", "--dir", dir)
+	if code != 0 || len(writes) != 0 {
+		t.Fatalf("dashboards plan exited %d with writes %v: %s", code, writes, stderr)
+	}
+	for _, want := range []string{`~ dashboard infra ("Infra")`, `+ widget "Memory"`, "0 dashboards to create, 1 to update; 1 widgets to add"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected plan to contain %q, got:\n%s", want, stdout)
+		}
+	}
+
+	// The directory can also be an argument, with flags after it
+	if code, stdout, _ := runCLI(t, "dashboards", "plan", dir, "--json"); code != 0 || !strings.HasPrefix(strings.TrimSpace(stdout), "{") {
+		t.Errorf("dashboards plan <dir> --json exited %d: %s", code, stdout)
+	}
+	if code, _, stderr := runCLI(t, "dashboards", "plan", dir, dir); code != 2 || !strings.Contains(stderr, "usage:") {
+		t.Errorf("Expected usage for two directories, got exit code %d: %s", code, stderr)
+	}
+
+	code, stdout, stderr = runCLI(t, "dashboards", "apply", dir)
+	if code != 0 || !strings.Contains(stdout, "Apply complete.") {
+		t.Fatalf("dashboards apply exited %d: %s%s", code, stdout, stderr)
+	}
+	if strings.Join(writes, ",") != "POST /api/v1/builder/widget,PUT /api/v1/builder/widget/scope/layouts" {
+		t.Errorf("Unexpected writes: %v", writes)
+	}
+
+	// Dropping CPU from the file removes it, which needs --yes
+	document = `key: infra
+label: Infra
+widgets:
+  - {key: memory, label: Memory}
+`
+	if err := os.WriteFile(filepath.Join(dir, "infra.yaml"), []byte(document), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	writes = nil
+	code, _, stderr = runCLI(t, "dashboards", "apply", "--dir", dir)
+	if code != 1 || !strings.Contains(stderr, "rerun with --yes") || len(writes) != 0 {
+		t.Fatalf("Expected apply to refuse removing a widget, exited %d with writes %v: %s", code, writes, stderr)
+	}
+	code, stdout, stderr = runCLI(t, "dashboards", "apply", "--dir", dir, "--yes")
+	if code != 0 || !strings.Contains(strings.Join(writes, ","), "DELETE /api/v1/builder/widget/50") {
+		t.Fatalf("dashboards apply --yes exited %d with writes %v: %s%s", code, writes, stdout, stderr)
+	}
+
+	if code, _, stderr := runCLI(t, "dashboards", "plan", "--dir", filepath.Join(dir, "missing")); code != 1 || stderr == "" {
+		t.Errorf("Expected exit code 1 for a missing directory, got %d", code)
+	}
+}
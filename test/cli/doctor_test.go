@@ -0,0 +1,130 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-middleware/cli"
+	"mcp-middleware/config"
+)
+
+func checkStatuses(report *cli.DoctorReport) string {
+	var statuses []string
+	for _, check := range report.Checks {
+		statuses = append(statuses, check.Name+"="+check.Status)
+	}
+	return strings.Join(statuses, ",")
+}
+
+func TestDoctor(t *testing.T) {
+	tests := []struct {
+		name         string
+		handler      http.HandlerFunc
+		basePath     string
+		wantStatuses string
+		wantFix      string
+	}{
+		{
+			name: "healthy",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/api/v1/builder/resources":
+					w.Write([]byte(`["host"]`))
+				case "/api/v1/builder/report":
+					w.Write([]byte(`{"reports": [], "total": 0}`))
+				default:
+					w.Write([]byte(`{"incidents": []}`))
+				}
+			},
+			wantStatuses: "config=pass,credentials=pass,base_url=pass,dns=pass,api_resources=pass,api_dashboards=pass,api_incidents=pass",
+		},
+		{
+			name: "rejected credentials",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error": "invalid api key"}`))
+			},
+			wantStatuses: "config=pass,credentials=pass,base_url=pass,dns=pass,api_resources=fail,api_dashboards=fail,api_incidents=fail",
+			wantFix:      "MIDDLEWARE_API_KEY",
+		},
+		{
+			name: "login page",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte(`<!DOCTYPE html><html><title>Login</title></html>`))
+			},
+			wantStatuses: "config=pass,credentials=pass,base_url=pass,dns=pass,api_resources=fail,api_dashboards=fail,api_incidents=fail",
+			wantFix:      "web app",
+		},
+		{
+			name:         "not found",
+			handler:      http.NotFound,
+			wantStatuses: "config=pass,credentials=pass,base_url=pass,dns=pass,api_resources=fail,api_dashboards=fail,api_incidents=fail",
+			wantFix:      "without a path",
+		},
+		{
+			name:         "api path in base URL",
+			handler:      http.NotFound,
+			basePath:     "/api/v1",
+			wantStatuses: "config=pass,credentials=pass,base_url=fail,dns=skip,api_resources=skip,api_dashboards=skip,api_incidents=skip",
+			wantFix:      "/api/v1 is added",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := httptest.NewServer(tt.handler)
+			defer api.Close()
+			t.Setenv("MIDDLEWARE_API_KEY", "test-key")
+			t.Setenv("MIDDLEWARE_BASE_URL", "")
+
+			report := cli.Doctor(context.Background(), config.Flags{MiddlewareBaseURL: api.URL + tt.basePath})
+			if got := checkStatuses(report); got != tt.wantStatuses {
+				t.Errorf("Expected %s, got %s", tt.wantStatuses, got)
+			}
+			if report.OK != (tt.wantFix == "") {
+				t.Errorf("Expected OK %v, got %v", tt.wantFix == "", report.OK)
+			}
+			if tt.wantFix != "" {
+				found := false
+				for _, check := range report.Checks {
+					found = found || strings.Contains(check.Fix, tt.wantFix)
+				}
+				if !found {
+					t.Errorf("Expected a fix mentioning %q, got %+v", tt.wantFix, report.Checks)
+				}
+			}
+		})
+	}
+}
+
+func TestDoctorInvalidConfig(t *testing.T) {
+	t.Setenv("MIDDLEWARE_API_KEY", "")
+	t.Setenv("AUTHORIZATION", "")
+	t.Setenv("MIDDLEWARE_BASE_URL", "https://test.middleware.io")
+
+	var stdout, stderr bytes.Buffer
+	code := cli.Run(context.Background(), []string{"doctor", "--json"}, &stdout, &stderr)
+	if code != 1 {
+		t.Errorf("Expected exit code 1, got %d (stderr: %s)", code, stderr.String())
+	}
+
+	var report cli.DoctorReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("Expected JSON output, got %s: %v", stdout.String(), err)
+	}
+	if report.OK || len(report.Checks) == 0 || report.Checks[0].Name != "config" || report.Checks[0].Status != cli.StatusFail {
+		t.Errorf("Expected a failed config check, got %+v", report)
+	}
+	for _, check := range report.Checks[1:] {
+		if check.Status != cli.StatusSkip {
+			t.Errorf("Expected %s to be skipped, got %s", check.Name, check.Status)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-middleware/cli"
+)
+
+func runCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := cli.Run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestToolsCommands(t *testing.T) {
+	t.Setenv("MIDDLEWARE_API_KEY", "test-key")
+	t.Setenv("MIDDLEWARE_BASE_URL", "https://test.middleware.io")
+
+	code, stdout, stderr := runCLI(t, "tools", "list", "--read-only")
+	if code != 0 {
+		t.Fatalf("tools list exited %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "list_dashboards") || strings.Contains(stdout, "delete_dashboard") {
+		t.Errorf("Expected only read-only tools, got:\n%s", stdout)
+	}
+
+	code, stdout, _ = runCLI(t, "tools", "list", "--json", "--toolsets", "errors")
+	var listed struct {
+		Tools []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &listed); err != nil || code != 0 {
+		t.Fatalf("Expected JSON tool list, got %s (exit %d): %v", stdout, code, err)
+	}
+	if len(listed.Tools) != 2 || listed.Tools[0].Name != "get_error_details" || listed.Tools[1].Name != "list_errors" {
+		t.Errorf("Expected the errors toolset, got %+v", listed.Tools)
+	}
+
+	code, stdout, _ = runCLI(t, "tools", "schema", "list_dashboards")
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(stdout), &schema); err != nil || code != 0 {
+		t.Fatalf("Expected JSON schema, got %s (exit %d): %v", stdout, code, err)
+	}
+	if properties, _ := schema["properties"].(map[string]any); properties["search"] == nil || properties["account"] == nil {
+		t.Errorf("Expected search and account properties, got %v", schema)
+	}
+
+	if code, _, _ := runCLI(t, "tools", "schema", "nope"); code != 1 {
+		t.Errorf("Expected exit code 1 for an unknown tool, got %d", code)
+	}
+}
+
+func TestCallCommand(t *testing.T) {
+	var gotQuery string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/builder/report" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		gotQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"reports": [{"id": 1, "key": "infra", "label": "Infra"}], "total": 1}`))
+	}))
+	defer api.Close()
+	t.Setenv("MIDDLEWARE_API_KEY", "test-key")
+	t.Setenv("MIDDLEWARE_BASE_URL", api.URL)
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantCode  int
+		wantQuery string
+		wantOut   string
+	}{
+		{"args object", []string{"call", "list_dashboards", "--args", `{"search": "infra", "limit": 5}`}, 0, "limit=5&search=infra", `"label": "Infra"`},
+		{"arg pairs override args", []string{"call", "--args", `{"limit": 5}`, "list_dashboards", "--arg", "limit=10", "--arg", "search=db"}, 0, "limit=10&search=db", `"key": "infra"`},
+		{"json output", []string{"call", "list_dashboards", "--json"}, 0, "", `"structuredContent"`},
+		{"invalid args", []string{"call", "list_dashboards", "--args", `[1]`}, 2, "", ""},
+		{"malformed arg", []string{"call", "list_dashboards", "--arg", "search"}, 2, "", ""},
+		{"unknown tool", []string{"call", "nope"}, 1, "", ""},
+		{"missing tool", []string{"call"}, 2, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotQuery = ""
+			code, stdout, stderr := runCLI(t, tt.args...)
+			if code != tt.wantCode {
+				t.Fatalf("Expected exit code %d, got %d (stderr: %s)", tt.wantCode, code, stderr)
+			}
+			if tt.wantCode != 0 {
+				return
+			}
+			if tt.wantQuery != "" && gotQuery != tt.wantQuery {
+				t.Errorf("Expected query %s, got %s", tt.wantQuery, gotQuery)
+			}
+			if !strings.Contains(stdout, tt.wantOut) {
+				t.Errorf("Expected output to contain %s, got:\n%s", tt.wantOut, stdout)
+			}
+		})
+	}
+}
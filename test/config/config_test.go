@@ -1,8 +1,12 @@
 package config_test
 
 import (
+	"context"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"mcp-middleware/config"
 )
@@ -85,7 +89,7 @@ func TestLoadMissingCredentials(t *testing.T) {
 		t.Error("Expected error when credentials are missing, got nil")
 	}
 
-	expectedMsg := "MIDDLEWARE_API_KEY or AUTHORIZATION is required"
+	expectedMsg := "MIDDLEWARE_API_KEY, AUTHORIZATION, MIDDLEWARE_API_KEY_FILE, AUTHORIZATION_FILE or CREDENTIAL_COMMAND is required"
 	if err.Error() != expectedMsg {
 		t.Errorf("Expected error message '%s', got '%s'", expectedMsg, err.Error())
 	}
@@ -282,3 +286,457 @@ func TestEmptyExcludedTools(t *testing.T) {
 		t.Errorf("Expected no excluded tools, got %d", len(cfg.ExcludedTools))
 	}
 }
+
+func TestConfirmDestructive(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"defaults to auto", "", "auto", false},
+		{"require", "require", "require", false},
+		{"off", "off", "off", false},
+		{"invalid value", "always", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("MIDDLEWARE_API_KEY", "test-api-key")
+			os.Setenv("MIDDLEWARE_BASE_URL", "https://test.middleware.io")
+			if tt.value != "" {
+				os.Setenv("CONFIRM_DESTRUCTIVE", tt.value)
+			} else {
+				os.Unsetenv("CONFIRM_DESTRUCTIVE")
+			}
+			defer func() {
+				os.Unsetenv("MIDDLEWARE_API_KEY")
+				os.Unsetenv("MIDDLEWARE_BASE_URL")
+				os.Unsetenv("CONFIRM_DESTRUCTIVE")
+			}()
+
+			cfg, err := config.Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.ConfirmDestructive != tt.want {
+				t.Errorf("Expected ConfirmDestructive %s, got %s", tt.want, cfg.ConfirmDestructive)
+			}
+		})
+	}
+}
+
+func TestDashboardHistory(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantVersions int
+		wantMaxAge   time.Duration
+		wantErr      string
+	}{
+		{"defaults", nil, 20, 0, ""},
+		{"retention", map[string]string{"DASHBOARD_HISTORY_VERSIONS": "5", "DASHBOARD_HISTORY_MAX_AGE": "720h"}, 5, 720 * time.Hour, ""},
+		{"disabled", map[string]string{"DASHBOARD_HISTORY_VERSIONS": "0"}, 0, 0, ""},
+		{"invalid versions", map[string]string{"DASHBOARD_HISTORY_VERSIONS": "many"}, 0, 0, "invalid DASHBOARD_HISTORY_VERSIONS"},
+		{"negative versions", map[string]string{"DASHBOARD_HISTORY_VERSIONS": "-1"}, 0, 0, "invalid DASHBOARD_HISTORY_VERSIONS"},
+		{"invalid max age", map[string]string{"DASHBOARD_HISTORY_MAX_AGE": "30d"}, 0, 0, "invalid DASHBOARD_HISTORY_MAX_AGE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MIDDLEWARE_API_KEY", "test-api-key")
+			t.Setenv("MIDDLEWARE_BASE_URL", "https://test.middleware.io")
+			t.Setenv("DASHBOARD_HISTORY_DIR", "/tmp/history")
+			t.Setenv("DASHBOARD_HISTORY_VERSIONS", "")
+			t.Setenv("DASHBOARD_HISTORY_MAX_AGE", "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := config.Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.DashboardHistoryDir != "/tmp/history" || cfg.DashboardHistoryVersions != tt.wantVersions || cfg.DashboardHistoryMaxAge != tt.wantMaxAge {
+				t.Errorf("Expected %d versions for %s, got %d for %s in %s",
+					tt.wantVersions, tt.wantMaxAge, cfg.DashboardHistoryVersions, cfg.DashboardHistoryMaxAge, cfg.DashboardHistoryDir)
+			}
+		})
+	}
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	yamlFile := `
+middleware_api_key: file-key
+middleware_base_url: https://file.middleware.io
+app_mode: http
+app_port: "9090"
+excluded_tools:
+  - delete_dashboard
+  - delete_widget
+dashboard_templates_dir: /etc/mcp-middleware/templates
+dashboard_history_versions: 5
+dashboard_history_max_age: 168h
+`
+	jsonFile := `{"middleware_api_key": "file-key", "middleware_base_url": "https://file.middleware.io", "app_mode": "sse"}`
+
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		env      map[string]string
+		flags    config.Flags
+		wantMode string
+		wantPort string
+		wantURL  string
+		wantErr  string
+	}{
+		{
+			name: "yaml file", file: "config.yaml", content: yamlFile,
+			wantMode: "http", wantPort: "9090", wantURL: "https://file.middleware.io",
+		},
+		{
+			name: "json file", file: "config.json", content: jsonFile,
+			wantMode: "sse", wantPort: "8080", wantURL: "https://file.middleware.io",
+		},
+		{
+			name: "env overrides file", file: "config.yaml", content: yamlFile,
+			env:      map[string]string{"APP_MODE": "stdio", "MIDDLEWARE_BASE_URL": "https://env.middleware.io"},
+			wantMode: "stdio", wantPort: "9090", wantURL: "https://env.middleware.io",
+		},
+		{
+			name: "flags override env", file: "config.yaml", content: yamlFile,
+			env:      map[string]string{"APP_MODE": "stdio"},
+			flags:    config.Flags{AppMode: "sse", AppPort: "7070"},
+			wantMode: "sse", wantPort: "7070", wantURL: "https://file.middleware.io",
+		},
+		{
+			name: "unknown yaml key", file: "config.yaml", content: yamlFile + "app_mdoe: http\n",
+			wantErr: "field app_mdoe not found",
+		},
+		{
+			name: "unknown json key", file: "config.json", content: `{"middleware_api_key": "k", "apikey": "x"}`,
+			wantErr: `unknown field "apikey"`,
+		},
+		{
+			name: "invalid value in file", file: "config.yaml", content: yamlFile + "confirm_destructive: always\n",
+			wantErr: "invalid CONFIRM_DESTRUCTIVE",
+		},
+		{
+			name: "invalid duration in file", file: "config.json", content: `{"middleware_api_key": "k", "dashboard_history_max_age": "a week"}`,
+			wantErr: "invalid dashboard_history_max_age",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"MIDDLEWARE_API_KEY", "AUTHORIZATION", "MIDDLEWARE_BASE_URL", "APP_MODE", "APP_PORT", "EXCLUDED_TOOLS", "DASHBOARD_TEMPLATES_DIR", "DASHBOARD_HISTORY_VERSIONS", "DASHBOARD_HISTORY_MAX_AGE"} {
+				t.Setenv(key, "")
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			t.Setenv(config.ConfigFileEnv, writeConfigFile(t, tt.file, tt.content))
+
+			cfg, err := config.LoadWithFlags(tt.flags)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadWithFlags() error = %v", err)
+			}
+
+			if cfg.MiddlewareAPIKey != "file-key" {
+				t.Errorf("Expected API key from file, got %q", cfg.MiddlewareAPIKey)
+			}
+			if cfg.AppMode != tt.wantMode || cfg.AppPort != tt.wantPort || cfg.MiddlewareBaseURL != tt.wantURL {
+				t.Errorf("Expected mode=%s port=%s url=%s, got mode=%s port=%s url=%s",
+					tt.wantMode, tt.wantPort, tt.wantURL, cfg.AppMode, cfg.AppPort, cfg.MiddlewareBaseURL)
+			}
+			if tt.file == "config.yaml" && (!cfg.IsToolExcluded("delete_dashboard") || !cfg.IsToolExcluded("delete_widget")) {
+				t.Errorf("Expected excluded tools from file, got %v", cfg.ExcludedTools)
+			}
+			if tt.file == "config.yaml" && cfg.DashboardTemplatesDir != "/etc/mcp-middleware/templates" {
+				t.Errorf("Expected the templates directory from file, got %q", cfg.DashboardTemplatesDir)
+			}
+			if tt.file == "config.yaml" && (cfg.DashboardHistoryVersions != 5 || cfg.DashboardHistoryMaxAge != 168*time.Hour) {
+				t.Errorf("Expected the history retention from file, got %d and %s", cfg.DashboardHistoryVersions, cfg.DashboardHistoryMaxAge)
+			}
+		})
+	}
+}
+
+func TestConfigFileFlagOverridesEnv(t *testing.T) {
+	t.Setenv("MIDDLEWARE_API_KEY", "")
+	t.Setenv("MIDDLEWARE_BASE_URL", "")
+	t.Setenv(config.ConfigFileEnv, writeConfigFile(t, "env.yaml", "middleware_api_key: env-file-key\nmiddleware_base_url: https://env.middleware.io\n"))
+	flagFile := writeConfigFile(t, "flag.yaml", "middleware_api_key: flag-file-key\nmiddleware_base_url: https://flag.middleware.io\n")
+
+	cfg, err := config.LoadWithFlags(config.Flags{ConfigFile: flagFile})
+	if err != nil {
+		t.Fatalf("LoadWithFlags() error = %v", err)
+	}
+	if cfg.MiddlewareAPIKey != "flag-file-key" || cfg.ConfigFile != flagFile {
+		t.Errorf("Expected --config file to be used, got key %q from %s", cfg.MiddlewareAPIKey, cfg.ConfigFile)
+	}
+}
+
+func TestToolSelectionFromEnv(t *testing.T) {
+	t.Setenv("MIDDLEWARE_API_KEY", "test-api-key")
+	t.Setenv("MIDDLEWARE_BASE_URL", "https://test.middleware.io")
+	t.Setenv("ENABLED_TOOLSETS", "dashboards, widgets")
+	t.Setenv("INCLUDED_TOOLS", "list_*,get_*")
+	t.Setenv("EXCLUDED_TOOLS", "get_multi_widget_data")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	tests := []struct {
+		toolset string
+		tool    string
+		want    bool
+	}{
+		{"dashboards", "list_dashboards", true},
+		{"widgets", "get_widget_data", true},
+		{"widgets", "get_multi_widget_data", false},
+		{"dashboards", "create_dashboard", false},
+		{"metrics", "get_metrics", false},
+	}
+	for _, tt := range tests {
+		if got := cfg.IsToolEnabled(tt.toolset, tt.tool); got != tt.want {
+			t.Errorf("IsToolEnabled(%q, %q) = %v, want %v", tt.toolset, tt.tool, got, tt.want)
+		}
+	}
+
+	t.Setenv("ENABLED_TOOLSETS", "dashboards,widgetz")
+	if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), "unknown toolset in ENABLED_TOOLSETS: widgetz") {
+		t.Errorf("Expected an error for an unknown toolset, got %v", err)
+	}
+}
+
+func TestInvalidToolPattern(t *testing.T) {
+	t.Setenv("MIDDLEWARE_API_KEY", "test-api-key")
+	t.Setenv("MIDDLEWARE_BASE_URL", "https://test.middleware.io")
+	t.Setenv("INCLUDED_TOOLS", "list_[")
+
+	if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), "invalid tool pattern") {
+		t.Errorf("Expected invalid tool pattern error, got %v", err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		flag    *bool
+		want    bool
+		wantErr bool
+	}{
+		{"defaults to false", "", nil, false, false},
+		{"env true", "true", nil, true, false},
+		{"flag overrides env", "true", new(bool), false, false},
+		{"invalid value", "yes please", nil, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MIDDLEWARE_API_KEY", "test-api-key")
+			t.Setenv("MIDDLEWARE_BASE_URL", "https://test.middleware.io")
+			t.Setenv("READ_ONLY", tt.env)
+
+			cfg, err := config.LoadWithFlags(config.Flags{ReadOnly: tt.flag})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadWithFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.ReadOnly != tt.want {
+				t.Errorf("Expected ReadOnly %v, got %v", tt.want, cfg.ReadOnly)
+			}
+		})
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	accounts := `
+accounts:
+  prod:
+    middleware_api_key: prod-key
+    middleware_base_url: https://prod.middleware.io
+  staging:
+    authorization: Bearer staging-token
+    middleware_base_url: https://staging.middleware.io
+`
+	tests := []struct {
+		name        string
+		content     string
+		env         map[string]string
+		wantDefault string
+		wantNames   string
+		wantErr     string
+	}{
+		{
+			name: "default account from file", content: accounts + "default_account: staging\n",
+			wantDefault: "staging", wantNames: "prod,staging",
+		},
+		{
+			name: "default account from env", content: accounts,
+			env:         map[string]string{"DEFAULT_ACCOUNT": "prod"},
+			wantDefault: "prod", wantNames: "prod,staging",
+		},
+		{
+			name: "top-level credentials become the default account", content: accounts,
+			env:         map[string]string{"MIDDLEWARE_API_KEY": "key", "MIDDLEWARE_BASE_URL": "https://default.middleware.io"},
+			wantDefault: "default", wantNames: "default,prod,staging",
+		},
+		{
+			name: "single account is the default", content: "accounts:\n  prod:\n    middleware_api_key: k\n    middleware_base_url: https://prod.middleware.io\n",
+			wantDefault: "prod", wantNames: "prod",
+		},
+		{
+			name: "several accounts without a default", content: accounts,
+			wantErr: "DEFAULT_ACCOUNT is required",
+		},
+		{
+			name: "unknown default account", content: accounts + "default_account: dev\n",
+			wantErr: "invalid DEFAULT_ACCOUNT: dev",
+		},
+		{
+			name: "account without base URL", content: "accounts:\n  prod:\n    middleware_api_key: k\n",
+			wantErr: "account prod: middleware_base_url is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"MIDDLEWARE_API_KEY", "AUTHORIZATION", "MIDDLEWARE_BASE_URL", "DEFAULT_ACCOUNT"} {
+				t.Setenv(key, "")
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			t.Setenv(config.ConfigFileEnv, writeConfigFile(t, "config.yaml", tt.content))
+
+			cfg, err := config.Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			account, err := cfg.GetAccount("")
+			if err != nil || account.Name != tt.wantDefault {
+				t.Errorf("Expected default account %s, got %v (err %v)", tt.wantDefault, account, err)
+			}
+			if names := strings.Join(cfg.AccountNames(), ","); names != tt.wantNames {
+				t.Errorf("Expected accounts %s, got %s", tt.wantNames, names)
+			}
+		})
+	}
+}
+
+func TestCredentialSources(t *testing.T) {
+	keyFile := writeConfigFile(t, "api-key", "file-key\n")
+	authFile := writeConfigFile(t, "authorization", "Bearer file-token\n")
+	emptyFile := writeConfigFile(t, "empty", "  \n")
+
+	tests := []struct {
+		name     string
+		account  config.Account
+		wantKey  string
+		wantAuth string
+		wantErr  bool
+	}{
+		{"value", config.Account{MiddlewareAPIKey: "value-key"}, "value-key", "", false},
+		{"value wins over file", config.Account{MiddlewareAPIKey: "value-key", MiddlewareAPIKeyFile: keyFile}, "value-key", "", false},
+		{"api key file", config.Account{MiddlewareAPIKeyFile: keyFile}, "file-key", "", false},
+		{"authorization file", config.Account{AuthorizationFile: authFile}, "", "Bearer file-token", false},
+		{"credential command", config.Account{CredentialCommand: "echo command-key"}, "command-key", "", false},
+		{"file wins over command", config.Account{MiddlewareAPIKeyFile: keyFile, CredentialCommand: "echo command-key"}, "file-key", "", false},
+		{"missing file", config.Account{MiddlewareAPIKeyFile: filepath.Join(t.TempDir(), "missing")}, "", "", true},
+		{"empty file", config.Account{MiddlewareAPIKeyFile: emptyFile}, "", "", true},
+		{"failing command", config.Account{CredentialCommand: "exit 1"}, "", "", true},
+		{"empty command output", config.Account{CredentialCommand: "true"}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiKey, authorization, err := tt.account.ResolveCredentials(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if apiKey != tt.wantKey || authorization != tt.wantAuth {
+				t.Errorf("Expected (%q, %q), got (%q, %q)", tt.wantKey, tt.wantAuth, apiKey, authorization)
+			}
+		})
+	}
+}
+
+func TestLoadWithCredentialFile(t *testing.T) {
+	keyFile := writeConfigFile(t, "api-key", "file-key")
+	t.Setenv("MIDDLEWARE_API_KEY", "")
+	t.Setenv("AUTHORIZATION", "")
+	t.Setenv("MIDDLEWARE_API_KEY_FILE", keyFile)
+	t.Setenv("MIDDLEWARE_BASE_URL", "https://test.middleware.io")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	account, err := cfg.GetAccount("")
+	if err != nil {
+		t.Fatalf("GetAccount() failed: %v", err)
+	}
+	if !account.HasRefreshableCredentials() {
+		t.Error("Expected credentials from a file to be refreshable")
+	}
+	if apiKey, _, err := account.ResolveCredentials(context.Background()); err != nil || apiKey != "file-key" {
+		t.Errorf("Expected file-key, got %q (err %v)", apiKey, err)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "app_mode: stdio\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan struct{}, 10)
+	go config.WatchFile(ctx, path, 10*time.Millisecond, func() { changes <- struct{}{} })
+
+	select {
+	case <-changes:
+		t.Fatal("Expected no change before the file is written")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(path, []byte("app_mode: http\nread_only: true\n"), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a change to be reported")
+	}
+}
@@ -3,8 +3,10 @@ package middleware_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -476,3 +478,143 @@ func TestCreateAlert(t *testing.T) {
 		t.Errorf("Expected ID = 789, got %d", result.ID)
 	}
 }
+
+func TestLogRedactsCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(middleware.QueryResponse{})
+	}))
+	defer server.Close()
+
+	type event struct {
+		level   middleware.LogLevel
+		message string
+		fields  map[string]any
+	}
+	var events []event
+
+	client := middleware.NewClientWithAuth(server.URL, "", "Bearer secret-token")
+	client.SetLogFunc(func(ctx context.Context, level middleware.LogLevel, message string, fields map[string]any) {
+		events = append(events, event{level, message, fields})
+	})
+
+	_, err := client.Query(context.Background(), &middleware.QueryRequest{
+		Queries: []middleware.Query{{ChartType: "data_table", Filters: map[string]any{"note": "uses secret-token"}}},
+	})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	client.Log(context.Background(), middleware.LogLevelInfo, "auth Bearer secret-token", map[string]any{"api_key": "abc"})
+
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 log events, got %d", len(events))
+	}
+	if events[0].message != "upstream request" || events[0].fields["path"] != "/query" {
+		t.Errorf("Expected upstream request event for /query, got %+v", events[0])
+	}
+	if events[1].message != "upstream response" || events[1].fields["status"] != http.StatusOK {
+		t.Errorf("Expected upstream response event with status 200, got %+v", events[1])
+	}
+	for _, e := range events {
+		for k, v := range e.fields {
+			if s, ok := v.(string); ok && strings.Contains(s, "secret-token") {
+				t.Errorf("Field %s of %q leaks the token: %s", k, e.message, s)
+			}
+		}
+	}
+	if events[2].message != "auth [REDACTED]" || events[2].fields["api_key"] != "[REDACTED]" {
+		t.Errorf("Expected redacted message and api_key, got %+v", events[2])
+	}
+}
+
+func TestReadOnlyClient(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client := middleware.NewClient(server.URL, "test-key")
+	client.SetReadOnly(true)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		call    func() error
+		allowed bool
+	}{
+		{"list dashboards", func() error { _, err := client.GetDashboards(ctx, nil); return err }, true},
+		{"query", func() error { _, err := client.Query(ctx, &middleware.QueryRequest{}); return err }, true},
+		{"widget data", func() error { _, err := client.GetWidgetData(ctx, &middleware.CustomWidget{}); return err }, true},
+		{"create dashboard", func() error { _, err := client.CreateDashboard(ctx, &middleware.UpsertReportRequest{}); return err }, false},
+		{"delete widget", func() error { return client.DeleteWidget(ctx, 1) }, false},
+		{"set favorite", func() error { return client.SetDashboardFavorite(ctx, 1, true) }, false},
+		{"update layouts", func() error { return client.UpdateWidgetLayouts(ctx, &middleware.LayoutRequest{}) }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = nil
+			err := tt.call()
+			if tt.allowed {
+				if err != nil || len(requests) != 1 {
+					t.Errorf("Expected request to be sent, got err=%v requests=%v", err, requests)
+				}
+				return
+			}
+			if !errors.Is(err, middleware.ErrReadOnly) {
+				t.Errorf("Expected ErrReadOnly, got %v", err)
+			}
+			if len(requests) != 0 {
+				t.Errorf("Expected no request to be sent, got %v", requests)
+			}
+		})
+	}
+}
+
+func TestRefreshCredentialsOnUnauthorized(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("ApiKey"))
+		if r.Header.Get("ApiKey") != "rotated-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "invalid api key"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"reports": [], "total": 0}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		provider middleware.CredentialProvider
+		wantErr  bool
+		wantKeys string
+	}{
+		{"no provider", nil, true, "old-key"},
+		{"rotated key", func(ctx context.Context) (string, string, error) { return "rotated-key", "", nil }, false, "old-key,rotated-key"},
+		{"unchanged key", func(ctx context.Context) (string, string, error) { return "old-key", "", nil }, true, "old-key"},
+		{"provider error", func(ctx context.Context) (string, string, error) { return "", "", errors.New("boom") }, true, "old-key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys = nil
+			client := middleware.NewClient(server.URL, "old-key")
+			if tt.provider != nil {
+				client.SetCredentialProvider(tt.provider)
+			}
+
+			_, err := client.GetDashboards(context.Background(), nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetDashboards() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := strings.Join(keys, ","); got != tt.wantKeys {
+				t.Errorf("Expected requests with keys %s, got %s", tt.wantKeys, got)
+			}
+		})
+	}
+}
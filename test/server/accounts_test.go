@@ -0,0 +1,201 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-middleware/config"
+	"mcp-middleware/middleware"
+	"mcp-middleware/server"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// newAccountAPI returns a mock API that records the API key of each dashboard listing.
+func newAccountAPI(t *testing.T, keys *[]string) *httptest.Server {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*keys = append(*keys, r.Header.Get("ApiKey"))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(middleware.ReportListResponse{})
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+func TestAccountArgument(t *testing.T) {
+	var prodKeys, stagingKeys []string
+	prod := newAccountAPI(t, &prodKeys)
+	staging := newAccountAPI(t, &stagingKeys)
+
+	cfg := &config.Config{
+		AppMode: "stdio",
+		Accounts: map[string]config.Account{
+			"prod":    {MiddlewareAPIKey: "prod-key", MiddlewareBaseURL: prod.URL},
+			"staging": {MiddlewareAPIKey: "staging-key", MiddlewareBaseURL: staging.URL},
+			"broken":  {MiddlewareAPIKeyFile: "/nonexistent/api-key", MiddlewareBaseURL: staging.URL},
+		},
+		DefaultAccount: "prod",
+	}
+	mcpSrv, ctx := newTestServerWithSession(t, cfg, newTestSession(t.Name()))
+
+	tests := []struct {
+		name        string
+		args        map[string]any
+		wantProd    int
+		wantStaging int
+		wantError   string
+	}{
+		{name: "default account", args: map[string]any{}, wantProd: 1},
+		{name: "named account", args: map[string]any{"account": "staging"}, wantStaging: 1},
+		{name: "unknown account", args: map[string]any{"account": "dev"}, wantError: "unknown account: dev"},
+		{name: "unreadable credentials", args: map[string]any{"account": "broken"}, wantError: "failed to load credentials for account broken"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prodKeys, stagingKeys = nil, nil
+			resp := mcpSrv.HandleMessage(ctx, toolCallMessage(t, i+1, "list_dashboards", tt.args, nil))
+
+			if tt.wantError != "" {
+				errResp, ok := resp.(mcp.JSONRPCError)
+				if !ok || !strings.Contains(errResp.Error.Message, tt.wantError) {
+					t.Fatalf("Expected error %q, got %#v", tt.wantError, resp)
+				}
+				return
+			}
+			if len(prodKeys) != tt.wantProd || len(stagingKeys) != tt.wantStaging {
+				t.Errorf("Expected %d prod and %d staging requests, got %v and %v", tt.wantProd, tt.wantStaging, prodKeys, stagingKeys)
+			}
+			for _, key := range stagingKeys {
+				if key != "staging-key" {
+					t.Errorf("Expected staging requests to use staging-key, got %s", key)
+				}
+			}
+		})
+	}
+
+	tool := mcpSrv.GetTool("list_dashboards")
+	var schema struct {
+		Properties map[string]struct {
+			Enum    []string `json:"enum"`
+			Default string   `json:"default"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(tool.Tool.RawInputSchema, &schema); err != nil {
+		t.Fatalf("failed to parse input schema: %v", err)
+	}
+	account, ok := schema.Properties["account"]
+	if !ok {
+		t.Fatal("Expected list_dashboards to have an account argument")
+	}
+	if strings.Join(account.Enum, ",") != "broken,prod,staging" || account.Default != "prod" {
+		t.Errorf("Expected account enum [broken prod staging] with default prod, got %v default %q", account.Enum, account.Default)
+	}
+}
+
+func TestListAccountsHidesSecrets(t *testing.T) {
+	cfg := &config.Config{
+		MiddlewareAPIKey:  "top-level-key",
+		MiddlewareBaseURL: "https://default.middleware.io",
+		AppMode:           "stdio",
+		Accounts: map[string]config.Account{
+			"bu-payments": {AuthorizationToken: "Bearer payments-token", MiddlewareBaseURL: "https://payments.middleware.io"},
+		},
+	}
+	mcpSrv, ctx := newTestServerWithSession(t, cfg, newTestSession(t.Name()))
+
+	resp, ok := mcpSrv.HandleMessage(ctx, toolCallMessage(t, 1, "list_accounts", map[string]any{}, nil)).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("Expected JSONRPCResponse, got %#v", resp)
+	}
+	encoded, _ := json.Marshal(resp.Result)
+	if strings.Contains(string(encoded), "top-level-key") || strings.Contains(string(encoded), "payments-token") {
+		t.Errorf("list_accounts leaks credentials: %s", encoded)
+	}
+
+	var result struct {
+		StructuredContent struct {
+			Accounts []struct {
+				Name     string `json:"name"`
+				BaseURL  string `json:"base_url"`
+				AuthType string `json:"auth_type"`
+				Default  bool   `json:"default"`
+			} `json:"accounts"`
+		} `json:"structuredContent"`
+	}
+	json.Unmarshal(encoded, &result)
+	accounts := result.StructuredContent
+
+	if len(accounts.Accounts) != 2 {
+		t.Fatalf("Expected 2 accounts, got %s", encoded)
+	}
+	payments, def := accounts.Accounts[0], accounts.Accounts[1]
+	if payments.Name != "bu-payments" || payments.AuthType != "authorization" || payments.Default {
+		t.Errorf("Unexpected bu-payments account: %+v", payments)
+	}
+	if def.Name != "default" || def.AuthType != "api_key" || !def.Default || def.BaseURL != "https://default.middleware.io" {
+		t.Errorf("Unexpected default account: %+v", def)
+	}
+}
+
+func TestClientForDoesNotWaitOnOtherAccounts(t *testing.T) {
+	cfg := &config.Config{
+		AppMode: "stdio",
+		Accounts: map[string]config.Account{
+			"prod": {MiddlewareAPIKey: "prod-key", MiddlewareBaseURL: "http://localhost"},
+			"slow": {CredentialCommand: "sleep 1; echo slow-key", MiddlewareBaseURL: "http://localhost"},
+		},
+		DefaultAccount: "prod",
+	}
+	srv := server.New(cfg)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := srv.ClientFor("slow")
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := srv.ClientFor("prod"); err != nil {
+		t.Fatalf("ClientFor(prod) error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected prod not to wait for the slow credential command, took %v", elapsed)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("ClientFor(slow) error = %v", err)
+	}
+	first, _ := srv.ClientFor("slow")
+	if second, _ := srv.ClientFor("slow"); first != second {
+		t.Error("Expected the slow account's client to be cached")
+	}
+}
+
+func TestMetaToolsWithoutCredentials(t *testing.T) {
+	cfg := &config.Config{
+		AppMode:         "stdio",
+		DynamicToolsets: true,
+		Accounts: map[string]config.Account{
+			"prod": {CredentialCommand: "exit 1", MiddlewareBaseURL: "http://localhost"},
+		},
+		DefaultAccount: "prod",
+	}
+	mcpSrv, ctx := newTestServerWithSession(t, cfg, newTestSession(t.Name()))
+
+	for i, tool := range []string{"list_toolsets", "enable_toolset", "list_accounts"} {
+		args := map[string]any{}
+		if tool == "enable_toolset" {
+			args["toolset"] = "accounts"
+		}
+		if resp, ok := mcpSrv.HandleMessage(ctx, toolCallMessage(t, i+1, tool, args, nil)).(mcp.JSONRPCResponse); !ok {
+			t.Errorf("Expected %s to work without the account's credentials, got %#v", tool, resp)
+		}
+	}
+}
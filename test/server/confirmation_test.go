@@ -0,0 +1,205 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"mcp-middleware/middleware"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// elicitingSession is a client session that declares elicitation support and
+// answers every elicitation request with a fixed response.
+type elicitingSession struct {
+	*testSession
+	response mcp.ElicitationResponse
+	messages []string
+}
+
+func (s *elicitingSession) GetClientInfo() mcp.Implementation            { return mcp.Implementation{} }
+func (s *elicitingSession) SetClientInfo(mcp.Implementation)             {}
+func (s *elicitingSession) SetClientCapabilities(mcp.ClientCapabilities) {}
+func (s *elicitingSession) GetClientCapabilities() mcp.ClientCapabilities {
+	return mcp.ClientCapabilities{Elicitation: &struct{}{}}
+}
+
+func (s *elicitingSession) RequestElicitation(ctx context.Context, req mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	s.messages = append(s.messages, req.Params.Message)
+	return &mcp.ElicitationResult{ElicitationResponse: s.response}, nil
+}
+
+// dashboardAPI mocks the dashboard endpoints used by delete_dashboard and records deletions.
+type dashboardAPI struct {
+	mu      sync.Mutex
+	deleted []string
+}
+
+func (a *dashboardAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == "DELETE":
+		a.mu.Lock()
+		a.deleted = append(a.deleted, r.URL.Path)
+		a.mu.Unlock()
+	case r.URL.Path == "/api/v1/builder/report/hosts":
+		json.NewEncoder(w).Encode(middleware.ReportListResponse{
+			Reports: []middleware.Report{{ID: 5, Key: "hosts", Label: "Production Hosts", Visibility: "public"}},
+			Total:   1,
+		})
+	case strings.HasPrefix(r.URL.Path, "/api/v1/builder/report/"):
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "report not found"}`))
+	case r.URL.Path == "/api/v1/builder/report":
+		json.NewEncoder(w).Encode(middleware.ReportListResponse{
+			Reports: []middleware.Report{{ID: 5, Label: "Production Hosts", Visibility: "public"}},
+			Total:   1,
+		})
+	case r.URL.Path == "/api/v1/builder/widget":
+		json.NewEncoder(w).Encode([]middleware.Widget{{ID: 8, Label: "CPU Usage"}, {ID: 9, Label: "Memory"}})
+	}
+}
+
+func TestDeleteConfirmation(t *testing.T) {
+	accept := mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionAccept, Content: map[string]any{"confirm": true}}
+	decline := mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionDecline}
+
+	tests := []struct {
+		name        string
+		mode        string
+		eliciting   bool
+		response    mcp.ElicitationResponse
+		tool        string
+		args        map[string]any
+		wantDeleted bool
+		wantError   bool
+		wantPrompt  string
+	}{
+		{
+			name: "accepted dashboard deletion", mode: "auto", eliciting: true, response: accept,
+			tool: "delete_dashboard", args: map[string]any{"id": 5},
+			wantDeleted: true, wantPrompt: `"Production Hosts" (ID 5) and its 2 widgets`,
+		},
+		{
+			name: "declined dashboard deletion", mode: "auto", eliciting: true, response: decline,
+			tool: "delete_dashboard", args: map[string]any{"id": 5},
+			wantDeleted: false, wantPrompt: "Production Hosts",
+		},
+		{
+			name: "accepted without confirm flag", mode: "auto", eliciting: true,
+			response: mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionAccept, Content: map[string]any{"confirm": false}},
+			tool:     "delete_widget", args: map[string]any{"builder_id": 8, "report_key": "hosts"},
+			wantDeleted: false, wantPrompt: `"CPU Usage" (ID 8) from dashboard "Production Hosts"`,
+		},
+		{
+			name: "accepted widget deletion", mode: "require", eliciting: true, response: accept,
+			tool: "delete_widget", args: map[string]any{"builder_id": 9, "report_key": "hosts", "message": "Removing unused memory chart"},
+			wantDeleted: true, wantPrompt: "Removing unused memory chart",
+		},
+		{
+			name: "widget not on the dashboard", mode: "auto", eliciting: true, response: accept,
+			tool: "delete_widget", args: map[string]any{"builder_id": 10, "report_key": "hosts"},
+			wantDeleted: false, wantError: true,
+		},
+		{
+			name: "widget on a missing dashboard", mode: "auto", eliciting: true, response: accept,
+			tool: "delete_widget", args: map[string]any{"builder_id": 8, "report_key": "gone"},
+			wantDeleted: false, wantError: true,
+		},
+		{
+			name: "confirmation without a dashboard key", mode: "auto", eliciting: true, response: accept,
+			tool: "delete_widget", args: map[string]any{"builder_id": 8, "widget_label": "CPU Usage"},
+			wantDeleted: false, wantError: true,
+		},
+		{
+			name: "client without elicitation in auto mode", mode: "auto", eliciting: false,
+			tool: "delete_widget", args: map[string]any{"builder_id": 8},
+			wantDeleted: true,
+		},
+		{
+			name: "client without elicitation in require mode", mode: "require", eliciting: false,
+			tool: "delete_dashboard", args: map[string]any{"id": 5},
+			wantDeleted: false, wantError: true,
+		},
+		{
+			name: "confirmation turned off", mode: "off", eliciting: true, response: decline,
+			tool: "delete_dashboard", args: map[string]any{"id": 5},
+			wantDeleted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &dashboardAPI{}
+			ts := httptest.NewServer(api)
+			defer ts.Close()
+
+			cfg := newTestConfig(ts.URL)
+			cfg.ConfirmDestructive = tt.mode
+
+			session := &elicitingSession{testSession: newTestSession(t.Name()), response: tt.response}
+			var clientSession mcpserver.ClientSession = session.testSession
+			if tt.eliciting {
+				clientSession = session
+			}
+			mcpSrv, ctx := newTestServerWithSession(t, cfg, clientSession)
+			resp := mcpSrv.HandleMessage(ctx, toolCallMessage(t, 1, tt.tool, tt.args, nil))
+
+			if _, isErr := resp.(mcp.JSONRPCError); isErr != tt.wantError {
+				t.Errorf("Expected error response %v, got %#v", tt.wantError, resp)
+			}
+			if deleted := len(api.deleted) > 0; deleted != tt.wantDeleted {
+				t.Errorf("Expected deleted %v, got DELETE requests %v", tt.wantDeleted, api.deleted)
+			}
+
+			if tt.wantPrompt == "" {
+				if len(session.messages) != 0 {
+					t.Errorf("Expected no confirmation prompt, got %v", session.messages)
+				}
+				return
+			}
+			if len(session.messages) != 1 || !strings.Contains(session.messages[0], tt.wantPrompt) {
+				t.Errorf("Expected confirmation prompt containing %q, got %v", tt.wantPrompt, session.messages)
+			}
+		})
+	}
+}
+
+func TestDeclinedDeletionKeepsHistory(t *testing.T) {
+	for _, tt := range []struct {
+		action       mcp.ElicitationResponseAction
+		wantVersions int
+	}{
+		{mcp.ElicitationResponseActionDecline, 0},
+		{mcp.ElicitationResponseActionAccept, 1},
+	} {
+		t.Run(string(tt.action), func(t *testing.T) {
+			ts := httptest.NewServer(&dashboardAPI{})
+			defer ts.Close()
+
+			cfg := newTestConfig(ts.URL)
+			cfg.ConfirmDestructive = "auto"
+			cfg.DashboardHistoryDir = t.TempDir()
+			cfg.DashboardHistoryVersions = 5
+
+			response := mcp.ElicitationResponse{Action: tt.action, Content: map[string]any{"confirm": true}}
+			session := &elicitingSession{testSession: newTestSession(t.Name()), response: response}
+			mcpSrv, ctx := newTestServerWithSession(t, cfg, session)
+			if resp := mcpSrv.HandleMessage(ctx, toolCallMessage(t, 1, "delete_dashboard", map[string]any{"id": 5}, nil)); resp == nil {
+				t.Fatal("Expected a response")
+			}
+
+			versions, _ := filepath.Glob(filepath.Join(cfg.DashboardHistoryDir, "*", "*.json"))
+			if len(versions) != tt.wantVersions {
+				t.Errorf("Expected %d saved versions, got %v", tt.wantVersions, versions)
+			}
+		})
+	}
+}
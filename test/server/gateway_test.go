@@ -0,0 +1,142 @@
+package server_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-middleware/server"
+)
+
+func TestRESTGateway(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/builder/report":
+			w.Write([]byte(`{"reports": [{"id": 1, "key": "infra", "label": "Infra"}], "total": 1}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "boom"}`))
+		}
+	}))
+	defer api.Close()
+
+	cfg := newValidConfig(api.URL)
+	cfg.RESTGateway = true
+	cfg.HTTPAuthToken = "secret"
+	cfg.ReadOnly = true
+	gateway := httptest.NewServer(server.New(cfg).HTTPHandler())
+	defer gateway.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"call tool", "POST", "/v1/tools/list_dashboards", "secret", `{"search": "infra"}`, http.StatusOK, `"label":"Infra"`},
+		{"empty body", "POST", "/v1/tools/list_dashboards", "secret", "", http.StatusOK, `"total":1`},
+		{"missing token", "POST", "/v1/tools/list_dashboards", "", `{}`, http.StatusUnauthorized, "bearer token"},
+		{"wrong token", "POST", "/v1/tools/list_dashboards", "nope", `{}`, http.StatusUnauthorized, "bearer token"},
+		{"wrong type", "POST", "/v1/tools/list_dashboards", "secret", `{"limit": "ten"}`, http.StatusBadRequest, "limit: must be of type integer"},
+		{"missing required", "POST", "/v1/tools/get_dashboard", "secret", `{}`, http.StatusBadRequest, "missing required property"},
+		{"invalid enum", "POST", "/v1/tools/list_dashboards", "secret", `{"account": "other"}`, http.StatusBadRequest, "account: must be one of"},
+		{"invalid JSON", "POST", "/v1/tools/list_dashboards", "secret", `{`, http.StatusBadRequest, "not valid JSON"},
+		{"not an object", "POST", "/v1/tools/list_dashboards", "secret", `[]`, http.StatusBadRequest, "must be a JSON object"},
+		{"null body", "POST", "/v1/tools/list_dashboards", "secret", `null`, http.StatusBadRequest, "must be a JSON object"},
+		{"unregistered tool", "POST", "/v1/tools/delete_dashboard", "secret", `{"id": 1}`, http.StatusNotFound, "unknown tool"},
+		{"rejected arguments", "POST", "/v1/tools/diff_dashboards", "secret", `{"base_key": "infra"}`, http.StatusBadRequest, "set exactly one of target_key and target_document"},
+		{"upstream error", "POST", "/v1/tools/get_resources", "secret", `{}`, http.StatusBadGateway, "boom"},
+		{"list tools", "GET", "/v1/tools", "secret", "", http.StatusOK, `"name":"list_dashboards"`},
+		{"openapi", "GET", "/v1/openapi.json", "secret", "", http.StatusOK, `"/v1/tools/list_dashboards"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, gateway.URL+tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, resp.StatusCode, body)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("Expected body to contain %s, got %s", tt.wantBody, body)
+			}
+		})
+	}
+}
+
+func TestRESTGatewayDisabled(t *testing.T) {
+	gateway := httptest.NewServer(server.New(newValidConfig("https://test.middleware.io")).HTTPHandler())
+	defer gateway.Close()
+
+	resp, err := http.Post(gateway.URL+"/v1/tools/list_dashboards", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 with the gateway disabled, got %d", resp.StatusCode)
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	cfg := newValidConfig("https://test.middleware.io")
+	cfg.EnabledToolsets = map[string]bool{"errors": true}
+	cfg.HTTPAuthToken = "secret"
+
+	encoded, err := json.Marshal(server.New(cfg).OpenAPI())
+	if err != nil {
+		t.Fatalf("failed to marshal OpenAPI document: %v", err)
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]struct {
+			Post struct {
+				OperationID string   `json:"operationId"`
+				Tags        []string `json:"tags"`
+				RequestBody struct {
+					Content map[string]struct {
+						Schema map[string]any `json:"schema"`
+					} `json:"content"`
+				} `json:"requestBody"`
+			} `json:"post"`
+		} `json:"paths"`
+		Security []map[string]any `json:"security"`
+	}
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		t.Fatalf("failed to decode OpenAPI document: %v", err)
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("Expected an OpenAPI 3 document, got %s", doc.OpenAPI)
+	}
+	if len(doc.Paths) != 2 {
+		t.Errorf("Expected one path per registered tool, got %d", len(doc.Paths))
+	}
+	op := doc.Paths["/v1/tools/list_errors"].Post
+	if op.OperationID != "list_errors" || len(op.Tags) != 1 || op.Tags[0] != "errors" {
+		t.Errorf("Unexpected list_errors operation: %+v", op)
+	}
+	schema := op.RequestBody.Content["application/json"].Schema
+	if properties, _ := schema["properties"].(map[string]any); properties["from_ts"] == nil {
+		t.Errorf("Expected the input schema in the request body, got %v", schema)
+	}
+	if len(doc.Security) != 1 {
+		t.Errorf("Expected bearer security when HTTP_AUTH_TOKEN is set, got %v", doc.Security)
+	}
+}
@@ -0,0 +1,103 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"mcp-middleware/middleware"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// loggingSession is a client session that supports logging/setLevel.
+type loggingSession struct {
+	*testSession
+	level atomic.Value
+}
+
+func (s *loggingSession) SetLogLevel(level mcp.LoggingLevel) { s.level.Store(level) }
+func (s *loggingSession) GetLogLevel() mcp.LoggingLevel {
+	if level, ok := s.level.Load().(mcp.LoggingLevel); ok {
+		return level
+	}
+	return mcp.LoggingLevelError
+}
+
+func TestLogMessagesFollowSessionLevel(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(middleware.Widget{ID: 1})
+	}))
+	defer api.Close()
+
+	tests := []struct {
+		name         string
+		level        string
+		wantMessages []string
+	}{
+		{
+			name:         "debug",
+			level:        "debug",
+			wantMessages: []string{"widget layout is smaller than the minimum size and was enlarged", "upstream request", "upstream response"},
+		},
+		{
+			name:         "warning",
+			level:        "warning",
+			wantMessages: []string{"widget layout is smaller than the minimum size and was enlarged"},
+		},
+		{
+			name:  "default level",
+			level: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &loggingSession{testSession: newTestSession(t.Name())}
+			mcpSrv, ctx := newTestServerWithSession(t, newTestConfig(api.URL), session)
+
+			if tt.level != "" {
+				setLevel, _ := json.Marshal(map[string]any{
+					"jsonrpc": "2.0",
+					"id":      1,
+					"method":  "logging/setLevel",
+					"params":  map[string]any{"level": tt.level},
+				})
+				if resp, ok := mcpSrv.HandleMessage(ctx, setLevel).(mcp.JSONRPCError); ok {
+					t.Fatalf("logging/setLevel failed: %v", resp.Error.Message)
+				}
+			}
+
+			args := map[string]any{
+				"label":          "CPU",
+				"widget_type":    "time_series_chart",
+				"builder_config": []map[string]any{},
+				"layout":         map[string]any{"w": 2, "h": 2},
+			}
+			mcpSrv.HandleMessage(ctx, toolCallMessage(t, 2, "create_widget", args, nil))
+
+			var messages []string
+			for len(session.notifications) > 0 {
+				n := <-session.notifications
+				if n.Method != "notifications/message" {
+					continue
+				}
+				data := n.Params.AdditionalFields["data"].(map[string]any)
+				messages = append(messages, data["message"].(string))
+
+				encoded, _ := json.Marshal(data)
+				if strings.Contains(string(encoded), "test-key") {
+					t.Errorf("Log message leaks the API key: %s", encoded)
+				}
+			}
+
+			if strings.Join(messages, "|") != strings.Join(tt.wantMessages, "|") {
+				t.Errorf("Expected log messages %v, got %v", tt.wantMessages, messages)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-middleware/config"
+	"mcp-middleware/middleware"
+	"mcp-middleware/server"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// testSession is a client session whose notifications can be read by the test.
+type testSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+}
+
+func newTestSession(id string) *testSession {
+	return &testSession{id: id, notifications: make(chan mcp.JSONRPCNotification, 100)}
+}
+
+func (s *testSession) Initialize()                                         {}
+func (s *testSession) Initialized() bool                                   { return true }
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+func (s *testSession) SessionID() string                                   { return s.id }
+
+func newTestConfig(baseURL string) *config.Config {
+	return &config.Config{
+		MiddlewareAPIKey:  "test-key",
+		MiddlewareBaseURL: baseURL,
+		AppMode:           "stdio",
+		ExcludedTools:     make(map[string]bool),
+	}
+}
+
+// newTestServerWithSession creates a server for cfg and returns a context bound to session.
+func newTestServerWithSession(t *testing.T, cfg *config.Config, session mcpserver.ClientSession) (*mcpserver.MCPServer, context.Context) {
+	t.Helper()
+	mcpSrv := server.New(cfg).GetMCPServer()
+	if err := mcpSrv.RegisterSession(context.Background(), session); err != nil {
+		t.Fatalf("RegisterSession() error = %v", err)
+	}
+	t.Cleanup(func() { mcpSrv.UnregisterSession(context.Background(), session.SessionID()) })
+
+	return mcpSrv, mcpSrv.WithContext(context.Background(), session)
+}
+
+func toolCallMessage(t *testing.T, id int, name string, args map[string]any, progressToken any) []byte {
+	t.Helper()
+	params := map[string]any{"name": name, "arguments": args}
+	if progressToken != nil {
+		params["_meta"] = map[string]any{"progressToken": progressToken}
+	}
+	data, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  "tools/call",
+		"params":  params,
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+	return data
+}
+
+func TestMultiWidgetDataProgress(t *testing.T) {
+	requests := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var widgets []middleware.CustomWidget
+		json.NewDecoder(r.Body).Decode(&widgets)
+
+		result := make([]middleware.BuilderDataResponse, len(widgets))
+		for i, widget := range widgets {
+			result[i] = middleware.BuilderDataResponse{Key: widget.Key}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}))
+	defer api.Close()
+
+	session := newTestSession(t.Name())
+	mcpSrv, ctx := newTestServerWithSession(t, newTestConfig(api.URL), session)
+
+	widgets := make([]map[string]any, 12)
+	for i := range widgets {
+		widgets[i] = map[string]any{"key": string(rune('a' + i))}
+	}
+	resp := mcpSrv.HandleMessage(ctx, toolCallMessage(t, 1, "get_multi_widget_data", map[string]any{"widgets": widgets}, "progress-1"))
+	if _, ok := resp.(mcp.JSONRPCResponse); !ok {
+		t.Fatalf("Expected JSONRPCResponse, got %#v", resp)
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected the widgets in one request, got %d", requests)
+	}
+
+	var messages []string
+	for len(session.notifications) > 0 {
+		n := <-session.notifications
+		if n.Method != "notifications/progress" {
+			continue
+		}
+		if token := n.Params.AdditionalFields["progressToken"]; token != "progress-1" {
+			t.Errorf("Expected progressToken 'progress-1', got %v", token)
+		}
+		messages = append(messages, n.Params.AdditionalFields["message"].(string))
+	}
+
+	expected := []string{"fetching 12 widgets", "fetched 12 widgets"}
+	if strings.Join(messages, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected progress messages %v, got %v", expected, messages)
+	}
+}
+
+func TestNoProgressWithoutToken(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(middleware.QueryResponse{})
+	}))
+	defer api.Close()
+
+	session := newTestSession(t.Name())
+	mcpSrv, ctx := newTestServerWithSession(t, newTestConfig(api.URL), session)
+
+	args := map[string]any{"queries": []map[string]any{
+		{"chartType": "data_table", "columns": []map[string]any{{"name": "body"}}, "resources": []string{"log"}, "timeRange": map[string]any{"from": 1, "to": 2}},
+	}}
+	mcpSrv.HandleMessage(ctx, toolCallMessage(t, 1, "query", args, nil))
+
+	if len(session.notifications) != 0 {
+		t.Errorf("Expected no notifications without a progress token, got %d", len(session.notifications))
+	}
+}
+
+func TestCancelledNotificationAbortsToolCall(t *testing.T) {
+	requestStarted := make(chan struct{})
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(requestStarted)
+		<-r.Context().Done()
+	}))
+	defer api.Close()
+
+	mcpSrv, ctx := newTestServerWithSession(t, newTestConfig(api.URL), newTestSession(t.Name()))
+
+	done := make(chan mcp.JSONRPCMessage, 1)
+	go func() {
+		done <- mcpSrv.HandleMessage(ctx, toolCallMessage(t, 42, "get_resources", map[string]any{}, nil))
+	}()
+
+	select {
+	case <-requestStarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Upstream request was never made")
+	}
+
+	cancelled, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"method":  "notifications/cancelled",
+		"params":  map[string]any{"requestId": 42, "reason": "user aborted"},
+	})
+	mcpSrv.HandleMessage(ctx, cancelled)
+
+	select {
+	case resp := <-done:
+		errResp, ok := resp.(mcp.JSONRPCError)
+		if !ok {
+			t.Fatalf("Expected JSONRPCError after cancellation, got %#v", resp)
+		}
+		if !strings.Contains(errResp.Error.Message, "context canceled") {
+			t.Errorf("Expected context canceled error, got %q", errResp.Error.Message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Tool call was not aborted by notifications/cancelled")
+	}
+}
+
+// progressMessages drains the progress notifications of session.
+func progressMessages(session *testSession) []string {
+	var messages []string
+	for len(session.notifications) > 0 {
+		n := <-session.notifications
+		if n.Method == "notifications/progress" {
+			messages = append(messages, n.Params.AdditionalFields["message"].(string))
+		}
+	}
+	return messages
+}
+
+func TestQueryAndIncidentsProgress(t *testing.T) {
+	var queryRequests, incidentPages int
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/query":
+			queryRequests++
+			var req middleware.QueryRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if len(req.Queries) != 3 {
+				t.Errorf("Expected 3 queries in one request, got %d", len(req.Queries))
+			}
+			json.NewEncoder(w).Encode(middleware.QueryResponse{})
+		case "/api/v1/ops-ai/incidents":
+			incidentPages++
+			items := []middleware.Incident{{Fingerprint: r.URL.Query().Get("page")}}
+			json.NewEncoder(w).Encode(middleware.IncidentsResponse{Items: items, TotalRecords: 2})
+		}
+	}))
+	defer api.Close()
+
+	session := newTestSession(t.Name())
+	mcpSrv, ctx := newTestServerWithSession(t, newTestConfig(api.URL), session)
+
+	query := map[string]any{"chartType": "data_table", "columns": []map[string]any{{"name": "body"}}, "resources": []string{"log"}, "timeRange": map[string]any{"from": 1, "to": 2}}
+	mcpSrv.HandleMessage(ctx, toolCallMessage(t, 1, "query", map[string]any{"queries": []map[string]any{query, query, query}}, "progress-1"))
+	if queryRequests != 1 {
+		t.Errorf("Expected one upstream query request, got %d", queryRequests)
+	}
+	if got := strings.Join(progressMessages(session), "|"); got != "running 3 queries|ran 3 queries" {
+		t.Errorf("Unexpected query progress %q", got)
+	}
+
+	args := map[string]any{"from_ts": 1, "to_ts": 2, "page": 1, "status": "all", "pages": 5}
+	resp := mcpSrv.HandleMessage(ctx, toolCallMessage(t, 2, "list_errors", args, "progress-2"))
+	if _, ok := resp.(mcp.JSONRPCResponse); !ok {
+		t.Fatalf("Expected JSONRPCResponse, got %#v", resp)
+	}
+	if incidentPages != 2 {
+		t.Errorf("Expected paging to stop after the 2 records, got %d pages", incidentPages)
+	}
+	if got := strings.Join(progressMessages(session), "|"); got != "page 1 of 5 of incidents|page 2 of 5 of incidents" {
+		t.Errorf("Unexpected incidents progress %q", got)
+	}
+
+	// Starting at page 2, the second of the 2 records is on the first page fetched
+	incidentPages = 0
+	args["page"] = 2
+	mcpSrv.HandleMessage(ctx, toolCallMessage(t, 3, "list_errors", args, "progress-3"))
+	if incidentPages != 1 {
+		t.Errorf("Expected paging from page 2 to stop at the last record, got %d pages", incidentPages)
+	}
+}
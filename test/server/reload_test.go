@@ -0,0 +1,115 @@
+package server_test
+
+import (
+	"context"
+	"testing"
+
+	"mcp-middleware/config"
+	"mcp-middleware/server"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// countListChanged drains the session's notifications and counts tools/list_changed.
+func countListChanged(session *testSession) int {
+	count := 0
+	for len(session.notifications) > 0 {
+		if n := <-session.notifications; n.Method == mcp.MethodNotificationToolsListChanged {
+			count++
+		}
+	}
+	return count
+}
+
+// newValidConfig returns a test configuration that passes Validate.
+func newValidConfig(baseURL string) *config.Config {
+	cfg := newTestConfig(baseURL)
+	cfg.ConfirmDestructive = "auto"
+	return cfg
+}
+
+func newReloadServer(t *testing.T, cfg *config.Config, session *testSession) (*server.Server, *mcpserver.MCPServer) {
+	t.Helper()
+	srv := server.New(cfg)
+	mcpSrv := srv.GetMCPServer()
+	if err := mcpSrv.RegisterSession(context.Background(), session); err != nil {
+		t.Fatalf("RegisterSession() error = %v", err)
+	}
+	t.Cleanup(func() { mcpSrv.UnregisterSession(context.Background(), session.SessionID()) })
+	return srv, mcpSrv
+}
+
+func TestReload(t *testing.T) {
+	cfg := newValidConfig("https://test.middleware.io")
+	cfg.EnabledToolsets = map[string]bool{"metrics": true, "alerts": true}
+
+	session := newTestSession(t.Name())
+	srv, mcpSrv := newReloadServer(t, cfg, session)
+	if names := toolNames(mcpSrv); names != "create_alert,get_alert_stats,get_metrics,get_resources,list_alerts,query" {
+		t.Fatalf("Unexpected tools at startup: %s", names)
+	}
+
+	newCfg := newValidConfig("https://test.middleware.io")
+	newCfg.EnabledToolsets = map[string]bool{"metrics": true, "alerts": true}
+	newCfg.ExcludedTools = map[string]bool{"create_*": true, "query": true}
+	if err := srv.Reload(newCfg); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if names := toolNames(mcpSrv); names != "get_alert_stats,get_metrics,get_resources,list_alerts" {
+		t.Errorf("Unexpected tools after reload: %s", names)
+	}
+	if srv.Config() != newCfg {
+		t.Error("Expected the reloaded configuration to be active")
+	}
+	if got := countListChanged(session); got != 1 {
+		t.Errorf("Expected 1 tools/list_changed notification, got %d", got)
+	}
+
+	invalid := newValidConfig("")
+	invalid.ExcludedTools = map[string]bool{"list_*": true}
+	if err := srv.Reload(invalid); err == nil {
+		t.Fatal("Expected an invalid configuration to be rejected")
+	}
+	if srv.Config() != newCfg {
+		t.Error("Expected the previous configuration to stay active")
+	}
+	if names := toolNames(mcpSrv); names != "get_alert_stats,get_metrics,get_resources,list_alerts" {
+		t.Errorf("Expected tools to be unchanged after a rejected reload, got %s", names)
+	}
+	if got := countListChanged(session); got != 0 {
+		t.Errorf("Expected no notification after a rejected reload, got %d", got)
+	}
+}
+
+func TestReloadKeepsDynamicToolsets(t *testing.T) {
+	cfg := newValidConfig("https://test.middleware.io")
+	cfg.DynamicToolsets = true
+
+	session := newTestSession(t.Name())
+	srv, mcpSrv := newReloadServer(t, cfg, session)
+	if _, err := srv.EnableToolset("metrics"); err != nil {
+		t.Fatalf("EnableToolset() error = %v", err)
+	}
+
+	newCfg := newValidConfig("https://test.middleware.io")
+	newCfg.DynamicToolsets = true
+	newCfg.ExcludedTools = map[string]bool{"query": true}
+	if err := srv.Reload(newCfg); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if names := toolNames(mcpSrv); names != "enable_toolset,get_metrics,get_resources,list_toolsets" {
+		t.Errorf("Expected the enabled toolset to survive the reload, got %s", names)
+	}
+
+	// A toolset the new configuration no longer allows is dropped
+	newCfg = newValidConfig("https://test.middleware.io")
+	newCfg.DynamicToolsets = true
+	newCfg.EnabledToolsets = map[string]bool{"alerts": true}
+	if err := srv.Reload(newCfg); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if names := toolNames(mcpSrv); names != "enable_toolset,list_toolsets" {
+		t.Errorf("Expected only the meta-tools after reload, got %s", names)
+	}
+}
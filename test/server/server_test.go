@@ -1,6 +1,8 @@
 package server_test
 
 import (
+	"sort"
+	"strings"
 	"testing"
 
 	"mcp-middleware/config"
@@ -99,3 +101,191 @@ func TestServerConfiguration(t *testing.T) {
 	}
 }
 
+func TestToolAnnotations(t *testing.T) {
+	cfg := &config.Config{
+		MiddlewareAPIKey:  "test-key",
+		MiddlewareBaseURL: "https://test.middleware.io",
+		AppMode:           "stdio",
+		ExcludedTools:     make(map[string]bool),
+	}
+	srv := server.New(cfg)
+
+	tests := []struct {
+		name        string
+		readOnly    bool
+		destructive bool
+		idempotent  bool
+		openWorld   bool
+	}{
+		{"list_dashboards", true, false, true, true},
+		{"get_dashboard", true, false, true, true},
+		{"create_dashboard", false, false, false, true},
+		{"update_dashboard", false, true, true, true},
+		{"delete_dashboard", false, true, true, true},
+		{"clone_dashboard", false, false, false, true},
+		{"set_dashboard_favorite", false, false, true, true},
+		{"export_dashboard", true, false, true, true},
+		{"import_dashboard", false, true, false, true},
+		{"diff_dashboards", true, false, true, true},
+		{"list_dashboard_templates", true, false, true, false},
+		{"create_dashboard_from_template", false, false, false, true},
+		{"list_dashboard_versions", true, false, true, false},
+		{"restore_dashboard_version", false, true, true, true},
+		{"lint_dashboard", true, false, true, true},
+		{"bulk_dashboards", false, true, false, true},
+		{"list_widgets", true, false, true, true},
+		{"create_widget", false, false, false, true},
+		{"update_widget", false, true, false, true},
+		{"delete_widget", false, true, true, true},
+		{"get_widget_data", true, false, true, true},
+		{"get_multi_widget_data", true, false, true, true},
+		{"update_widget_layouts", false, false, true, true},
+		{"get_metrics", true, false, true, true},
+		{"get_resources", true, false, true, true},
+		{"query", true, false, true, true},
+		{"list_alerts", true, false, true, true},
+		{"create_alert", false, false, false, true},
+		{"get_alert_stats", true, false, true, true},
+		{"list_errors", true, false, true, true},
+		{"get_error_details", true, false, true, true},
+		{"list_accounts", true, false, true, false},
+	}
+
+	registered := srv.GetMCPServer().ListTools()
+	if len(registered) != len(tests) {
+		t.Errorf("Expected %d registered tools, got %d", len(tests), len(registered))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := srv.GetMCPServer().GetTool(tt.name)
+			if tool == nil {
+				t.Fatalf("Tool %s is not registered", tt.name)
+			}
+
+			ann := tool.Tool.Annotations
+			if ann.Title == "" {
+				t.Errorf("Expected a title annotation")
+			}
+			if ann.ReadOnlyHint == nil || *ann.ReadOnlyHint != tt.readOnly {
+				t.Errorf("Expected readOnlyHint %v, got %v", tt.readOnly, ann.ReadOnlyHint)
+			}
+			if ann.DestructiveHint == nil || *ann.DestructiveHint != tt.destructive {
+				t.Errorf("Expected destructiveHint %v, got %v", tt.destructive, ann.DestructiveHint)
+			}
+			if ann.IdempotentHint == nil || *ann.IdempotentHint != tt.idempotent {
+				t.Errorf("Expected idempotentHint %v, got %v", tt.idempotent, ann.IdempotentHint)
+			}
+			if ann.OpenWorldHint == nil || *ann.OpenWorldHint != tt.openWorld {
+				t.Errorf("Expected openWorldHint %v, got %v", tt.openWorld, ann.OpenWorldHint)
+			}
+		})
+	}
+}
+
+func TestToolOutputSchemas(t *testing.T) {
+	cfg := &config.Config{
+		MiddlewareAPIKey:  "test-key",
+		MiddlewareBaseURL: "https://test.middleware.io",
+		AppMode:           "stdio",
+		ExcludedTools:     make(map[string]bool),
+	}
+	srv := server.New(cfg)
+
+	for name, tool := range srv.GetMCPServer().ListTools() {
+		t.Run(name, func(t *testing.T) {
+			if tool.Tool.OutputSchema.Type != "object" {
+				t.Errorf("Expected object output schema, got %q", tool.Tool.OutputSchema.Type)
+			}
+		})
+	}
+}
+
+func TestToolSelection(t *testing.T) {
+	tests := []struct {
+		name      string
+		toolsets  map[string]bool
+		included  map[string]bool
+		excluded  map[string]bool
+		wantTools []string
+	}{
+		{
+			name:      "single toolset",
+			toolsets:  map[string]bool{"metrics": true},
+			wantTools: []string{"get_metrics", "get_resources", "query"},
+		},
+		{
+			name:      "toolsets with exclusion pattern",
+			toolsets:  map[string]bool{"alerts": true, "errors": true},
+			excluded:  map[string]bool{"create_*": true},
+			wantTools: []string{"get_alert_stats", "get_error_details", "list_alerts", "list_errors"},
+		},
+		{
+			name:      "included tools pattern",
+			included:  map[string]bool{"list_*": true},
+			wantTools: []string{"list_accounts", "list_alerts", "list_dashboard_templates", "list_dashboard_versions", "list_dashboards", "list_errors", "list_widgets"},
+		},
+		{
+			name:      "included tools within toolset",
+			toolsets:  map[string]bool{"dashboards": true},
+			included:  map[string]bool{"list_*": true, "get_dashboard": true, "query": true},
+			wantTools: []string{"get_dashboard", "list_dashboard_templates", "list_dashboard_versions", "list_dashboards"},
+		},
+		{
+			name:      "all toolsets",
+			toolsets:  map[string]bool{"all": true},
+			included:  map[string]bool{"get_*_data": true},
+			wantTools: []string{"get_multi_widget_data", "get_widget_data"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				MiddlewareAPIKey:  "test-key",
+				MiddlewareBaseURL: "https://test.middleware.io",
+				AppMode:           "stdio",
+				EnabledToolsets:   tt.toolsets,
+				IncludedTools:     tt.included,
+				ExcludedTools:     tt.excluded,
+			}
+
+			var names []string
+			for name := range server.New(cfg).GetMCPServer().ListTools() {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			if strings.Join(names, ",") != strings.Join(tt.wantTools, ",") {
+				t.Errorf("Expected tools %v, got %v", tt.wantTools, names)
+			}
+		})
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	cfg := &config.Config{
+		MiddlewareAPIKey:  "test-key",
+		MiddlewareBaseURL: "https://test.middleware.io",
+		AppMode:           "stdio",
+		ReadOnly:          true,
+	}
+	srv := server.New(cfg)
+
+	var names []string
+	for name, tool := range srv.GetMCPServer().ListTools() {
+		if ann := tool.Tool.Annotations; ann.ReadOnlyHint == nil || !*ann.ReadOnlyHint {
+			t.Errorf("Mutating tool %s registered in read-only mode", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	expected := []string{
+		"diff_dashboards", "export_dashboard", "get_alert_stats", "get_dashboard", "get_error_details", "get_metrics", "get_multi_widget_data",
+		"get_resources", "get_widget_data", "lint_dashboard", "list_accounts", "list_alerts", "list_dashboard_templates", "list_dashboard_versions", "list_dashboards", "list_errors", "list_widgets", "query",
+	}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected tools %v, got %v", expected, names)
+	}
+}
@@ -0,0 +1,76 @@
+package server_test
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+func toolNames(mcpSrv *mcpserver.MCPServer) string {
+	var names []string
+	for name := range mcpSrv.ListTools() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestDynamicToolsets(t *testing.T) {
+	cfg := newTestConfig("https://test.middleware.io")
+	cfg.DynamicToolsets = true
+	cfg.EnabledToolsets = map[string]bool{"metrics": true, "alerts": true}
+	cfg.ExcludedTools = map[string]bool{"query": true}
+
+	session := newTestSession(t.Name())
+	mcpSrv, ctx := newTestServerWithSession(t, cfg, session)
+
+	if names := toolNames(mcpSrv); names != "enable_toolset,list_toolsets" {
+		t.Fatalf("Expected only the meta-tools at startup, got %s", names)
+	}
+
+	resp := mcpSrv.HandleMessage(ctx, toolCallMessage(t, 1, "list_toolsets", map[string]any{}, nil))
+	result, ok := resp.(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("Expected JSONRPCResponse, got %#v", resp)
+	}
+	encoded, _ := json.Marshal(result.Result)
+	text := string(encoded)
+	if !strings.Contains(text, `"name":"metrics"`) || !strings.Contains(text, `"tools":["get_metrics","get_resources"]`) || strings.Contains(text, `"dashboards"`) {
+		t.Errorf("Unexpected list_toolsets result: %s", text)
+	}
+
+	resp = mcpSrv.HandleMessage(ctx, toolCallMessage(t, 2, "enable_toolset", map[string]any{"toolset": "metrics"}, nil))
+	if _, ok := resp.(mcp.JSONRPCResponse); !ok {
+		t.Fatalf("Expected JSONRPCResponse, got %#v", resp)
+	}
+	if names := toolNames(mcpSrv); names != "enable_toolset,get_metrics,get_resources,list_toolsets" {
+		t.Errorf("Expected metrics tools after enabling, got %s", names)
+	}
+
+	listChanged := 0
+	for len(session.notifications) > 0 {
+		if n := <-session.notifications; n.Method == mcp.MethodNotificationToolsListChanged {
+			listChanged++
+		}
+	}
+	if listChanged != 1 {
+		t.Errorf("Expected 1 tools/list_changed notification, got %d", listChanged)
+	}
+
+	// Enabling again changes nothing
+	mcpSrv.HandleMessage(ctx, toolCallMessage(t, 3, "enable_toolset", map[string]any{"toolset": "metrics"}, nil))
+	if len(session.notifications) != 0 {
+		t.Errorf("Expected no notification when re-enabling a toolset, got %d", len(session.notifications))
+	}
+
+	for _, toolset := range []string{"dashboards", "nope"} {
+		resp = mcpSrv.HandleMessage(ctx, toolCallMessage(t, 4, "enable_toolset", map[string]any{"toolset": toolset}, nil))
+		if _, ok := resp.(mcp.JSONRPCError); !ok {
+			t.Errorf("Expected error enabling toolset %s, got %#v", toolset, resp)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package tools_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-middleware/middleware"
+	"mcp-middleware/server/tools"
+)
+
+func TestBulkDashboards(t *testing.T) {
+	ctx := context.Background()
+	old := time.Now().AddDate(0, 0, -100).UTC().Format(time.RFC3339)
+	recent := time.Now().AddDate(0, 0, -2).UTC().Format(time.RFC3339)
+
+	newAPI := func() *dashboardAPI {
+		return newDashboardAPI(
+			middleware.Report{ID: 1, Key: "auto_1", Label: "Auto Generated 1", Visibility: "public", User: &middleware.ReportUser{Name: "Bot"}, CreatedAt: old, UpdatedAt: old},
+			middleware.Report{ID: 2, Key: "auto_2", Label: "Auto Generated 2", Visibility: "private", User: &middleware.ReportUser{Name: "bot"}, CreatedAt: old, UpdatedAt: recent},
+			middleware.Report{ID: 3, Key: "auto_3", Label: "Auto Generated 3", Visibility: "public", Favorite: true, User: &middleware.ReportUser{Name: "Bot"}, CreatedAt: old, UpdatedAt: old},
+			middleware.Report{ID: 4, Key: "infra", Label: "Infra", Visibility: "public", User: &middleware.ReportUser{Name: "Ada"}, CreatedAt: old, UpdatedAt: old},
+		)
+	}
+	statusesOf := func(result *tools.BulkDashboardsResult) string {
+		var statuses []string
+		for _, item := range result.Items {
+			statuses = append(statuses, item.Key+"="+item.Status)
+		}
+		return strings.Join(statuses, ",")
+	}
+
+	tests := []struct {
+		name         string
+		args         map[string]any
+		failReport   int
+		wantStatuses string
+		wantWrites   string
+	}{
+		{
+			name:         "dry run by default",
+			args:         map[string]any{"action": "delete", "search": "auto", "owner": "BOT"},
+			wantStatuses: "auto_1=planned,auto_2=planned,auto_3=planned",
+		},
+		{
+			name:         "delete stale non-favorites",
+			args:         map[string]any{"action": "delete", "search": "auto", "match_favorite": false, "updated_older_than_days": float64(30), "dry_run": false},
+			wantStatuses: "auto_1=done",
+			wantWrites:   "DELETE /builder/report/1",
+		},
+		{
+			name:         "failure doesn't stop the others",
+			args:         map[string]any{"action": "delete", "owner": "bot", "dry_run": false},
+			failReport:   2,
+			wantStatuses: "auto_1=done,auto_2=failed,auto_3=done",
+			wantWrites:   "DELETE /builder/report/1,DELETE /builder/report/2,DELETE /builder/report/3",
+		},
+		{
+			name:         "set visibility skips unchanged",
+			args:         map[string]any{"action": "set_visibility", "visibility": "private", "search": "auto", "dry_run": false},
+			wantStatuses: "auto_1=done,auto_2=unchanged,auto_3=done",
+			wantWrites:   "PUT /builder/report/1,PUT /builder/report/3",
+		},
+		{
+			name:         "set favorite",
+			args:         map[string]any{"action": "set_favorite", "favorite": true, "match_visibility": "public", "dry_run": false},
+			wantStatuses: "auto_1=done,auto_3=unchanged,infra=done",
+			wantWrites:   "FAVOURITE /builder/report/favourite/1/true,FAVOURITE /builder/report/favourite/4/true",
+		},
+		{
+			name:         "clone",
+			args:         map[string]any{"action": "clone", "search": "infra", "created_older_than_days": float64(30), "dry_run": false},
+			wantStatuses: "infra=done",
+			wantWrites:   "POST /builder/report/clone",
+		},
+		{
+			name:         "no match",
+			args:         map[string]any{"action": "delete", "owner": "nobody", "dry_run": false},
+			wantStatuses: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newAPI()
+			api.failReport = tt.failReport
+			s := newTestServer(t, api.ServeHTTP)
+			result, err := tools.HandleBulkDashboards(s, ctx, newCallToolRequest(tt.args))
+			if err != nil {
+				t.Fatalf("HandleBulkDashboards() error = %v", err)
+			}
+			bulk := result.StructuredContent.(*tools.BulkDashboardsResult)
+			if got := statusesOf(bulk); got != tt.wantStatuses {
+				t.Errorf("Expected statuses %s, got %s", tt.wantStatuses, got)
+			}
+			if got := strings.Join(api.writes, ","); got != tt.wantWrites {
+				t.Errorf("Expected writes %q, got %q", tt.wantWrites, got)
+			}
+			if bulk.Matched != len(bulk.Items) || bulk.Message == "" {
+				t.Errorf("Unexpected result %+v", bulk)
+			}
+		})
+	}
+
+	t.Run("clone labels", func(t *testing.T) {
+		api := newAPI()
+		s := newTestServer(t, api.ServeHTTP)
+		result, err := tools.HandleBulkDashboards(s, ctx, newCallToolRequest(map[string]any{
+			"action": "clone", "search": "infra", "label_suffix": " v2", "visibility": "private", "dry_run": false,
+		}))
+		if err != nil {
+			t.Fatalf("HandleBulkDashboards() error = %v", err)
+		}
+		item := result.StructuredContent.(*tools.BulkDashboardsResult).Items[0]
+		if item.CloneID == 0 || item.CloneKey == "" || item.Detail != `cloned as "Infra v2"` {
+			t.Errorf("Unexpected clone %+v", item)
+		}
+	})
+
+	t.Run("snapshots before delete", func(t *testing.T) {
+		api := newAPI()
+		s := newTestServer(t, api.ServeHTTP)
+		s.config.MiddlewareAPIKey = "test-key"
+		s.config.DashboardHistoryDir = t.TempDir()
+		s.config.DashboardHistoryVersions = 5
+		_, err := tools.HandleBulkDashboards(s, ctx, newCallToolRequest(map[string]any{"action": "delete", "search": "auto", "dry_run": false}))
+		if err != nil {
+			t.Fatalf("HandleBulkDashboards() error = %v", err)
+		}
+		entries, _ := os.ReadDir(s.config.DashboardHistoryDir + "/default")
+		if len(entries) != 3 {
+			t.Errorf("Expected 3 saved versions, got %d", len(entries))
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		api := newAPI()
+		s := newTestServer(t, api.ServeHTTP)
+		for _, args := range []map[string]any{
+			{"action": "delete", "dry_run": false},
+			{"action": "set_visibility", "search": "auto"},
+			{"action": "set_favorite", "search": "auto"},
+			{"action": "archive", "search": "auto"},
+			{"action": "delete", "search": "auto", "max_dashboards": float64(2), "dry_run": false},
+		} {
+			if _, err := tools.HandleBulkDashboards(s, ctx, newCallToolRequest(args)); err == nil {
+				t.Errorf("Expected an error for %v", args)
+			}
+		}
+		if api.writes != nil {
+			t.Errorf("Expected no writes, got %v", api.writes)
+		}
+	})
+}
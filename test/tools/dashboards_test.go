@@ -0,0 +1,773 @@
+package tools_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"mcp-middleware/middleware"
+	"mcp-middleware/server/tools"
+)
+
+// exportDashboardJSON and exportWidgetsJSON are a dashboard with key "infra"
+// and ID 7 and its widgets, in the shape the Middleware API returns them.
+const exportDashboardJSON = `{"reports": [{"id": 7, "key": "infra", "label": "Infra", "description": "Hosts", "visibility": "public",
+	"account_id": 3, "project_id": 4, "user_id": 5, "created_at": "2026-01-01T00:00:00Z"}], "total": 1}`
+
+const exportWidgetsJSON = `[
+	{"id": 21, "key": "memory_2", "label": "Memory", "widget_app_id": 2, "account_id": 3, "dataset_id": 9,
+	 "config": {"builderConfig": [{"columns": ["system.memory.usage"], "source": {"name": "host", "dataset_id": 9}}],
+	            "builderViewOptions": {"displayScope": "infra", "report": {"reportId": 7, "reportKey": "infra"}},
+	            "layout": {"x": 0, "y": 0, "w": 4, "h": 6, "_scope_id": 40}},
+	 "scope": {"id": 41, "report_id": 7, "meta_data": {"layout": {"x": 6, "y": 6, "w": 6, "h": 6, "_scope_id": 41}}}},
+	{"id": 20, "key": "cpu_1", "label": "CPU", "widget_app_id": 1,
+	 "config": {"builderConfig": [{"columns": ["system.cpu.utilization"], "source": {"name": "host"}}],
+	            "layout": {"x": 0, "y": 0, "w": 6, "h": 6}},
+	 "created_at": "2026-01-01T00:00:00Z", "updated_at": "2026-01-02T00:00:00Z"},
+	{"id": 22, "key": "custom_3", "label": "Custom", "widget_app_id": 99}
+]`
+
+func TestGetDashboard(t *testing.T) {
+	api := newDashboardAPI(middleware.Report{ID: 7, Key: "infra", Label: "Infra"})
+	api.widgets[7] = []middleware.Widget{
+		{ID: 50, Key: "cpu_1", Label: "CPU", WidgetAppID: 1,
+			Scope: &middleware.WidgetScope{ID: 500, MetaData: map[string]any{"layout": map[string]any{"x": float64(0), "y": float64(0), "w": float64(6), "h": float64(6)}}},
+			Config: map[string]any{"builderConfig": []any{map[string]any{
+				"columns": []any{"system.cpu.utilization"},
+				"source":  map[string]any{"name": "host"},
+				"with": []any{
+					map[string]any{"key": "SELECT_DATA_BY", "value": []any{"host.name"}},
+					map[string]any{"key": "ATTRIBUTE_FILTER", "value": map[string]any{"and": []any{map[string]any{"host.name": map[string]any{"=": "web-1"}}}}},
+				},
+			}}}},
+		{ID: 51, Key: "logs_2", Label: "Logs", WidgetAppID: 5},
+		{ID: 52, Key: "gone_3", Label: "Gone", WidgetAppID: 99},
+	}
+	api.data = map[string]middleware.BuilderDataResponse{
+		"cpu_1": {
+			ChartData: []any{
+				map[string]any{"name": "web-1", "data": []any{[]any{1, 0.5}, []any{2, 0.6}}},
+				map[string]any{"name": "web-2", "data": []any{[]any{1, 0.1}}},
+			},
+			TimeRange: &middleware.TimeRange{FromTs: 1767225600000, ToTs: 1767229200000},
+		},
+		"logs_2": {QueryData: map[string]any{
+			"columns": []any{map[string]any{"accessor": "severity"}, map[string]any{"accessor": "count"}},
+			"data":    []any{map[string]any{"severity": "ERROR", "count": 3.0}, map[string]any{"severity": "INFO", "count": 9.0}},
+		}},
+		"gone_3": {NotAvailableMetrics: []string{"old.metric"}},
+	}
+	s := newTestServer(t, api.ServeHTTP)
+	ctx := context.Background()
+
+	t.Run("dashboard only", func(t *testing.T) {
+		result, err := tools.HandleGetDashboard(s, ctx, newCallToolRequest(map[string]any{"report_key": "infra"}))
+		if err != nil {
+			t.Fatalf("HandleGetDashboard() error = %v", err)
+		}
+		got := result.StructuredContent.(*tools.GetDashboardResult)
+		if len(got.Reports) != 1 || got.Reports[0].ID != 7 || got.Widgets != nil {
+			t.Errorf("Expected the dashboard without widgets, got %+v", got)
+		}
+	})
+
+	t.Run("widgets", func(t *testing.T) {
+		result, err := tools.HandleGetDashboard(s, ctx, newCallToolRequest(map[string]any{"report_key": "infra", "include_widgets": true}))
+		if err != nil {
+			t.Fatalf("HandleGetDashboard() error = %v", err)
+		}
+		got := result.StructuredContent.(*tools.GetDashboardResult)
+		if len(got.Widgets) != 3 {
+			t.Fatalf("Expected 3 widgets, got %+v", got.Widgets)
+		}
+		cpu := got.Widgets[0]
+		if cpu.Type != "time_series_chart" || cpu.Layout.String() != "0,0 6x6" {
+			t.Errorf("Unexpected CPU widget %+v", cpu)
+		}
+		if want := `system.cpu.utilization from host where {"and":[{"host.name":{"=":"web-1"}}]} by host.name`; cpu.Query != want {
+			t.Errorf("Expected query %q, got %q", want, cpu.Query)
+		}
+		if got.Widgets[1].Type != "data_table" || got.Widgets[2].Type != "widget app 99" || cpu.Data != nil {
+			t.Errorf("Unexpected widgets %+v", got.Widgets)
+		}
+		if api.dataCalls != 0 {
+			t.Errorf("Expected no data requests, got %d", api.dataCalls)
+		}
+	})
+
+	t.Run("data", func(t *testing.T) {
+		result, err := tools.HandleGetDashboard(s, ctx, newCallToolRequest(map[string]any{"report_key": "infra", "include_data": true}))
+		if err != nil {
+			t.Fatalf("HandleGetDashboard() error = %v", err)
+		}
+		got := result.StructuredContent.(*tools.GetDashboardResult)
+		if api.dataCalls != 1 || len(got.Widgets) != 3 {
+			t.Fatalf("Expected 3 widgets from one data request, got %d widgets from %d", len(got.Widgets), api.dataCalls)
+		}
+		cpu, logs, gone := got.Widgets[0].Data, got.Widgets[1].Data, got.Widgets[2].Data
+		if cpu.Series != 2 || cpu.Points != 3 || cpu.Empty || cpu.From != "2026-01-01T00:00:00Z" {
+			t.Errorf("Unexpected CPU data %+v", cpu)
+		}
+		if logs.Rows != 2 || strings.Join(logs.Columns, ",") != "severity,count" || len(logs.Sample) != 2 || logs.Empty {
+			t.Errorf("Unexpected Logs data %+v", logs)
+		}
+		if !gone.Empty || strings.Join(gone.NotAvailableMetrics, ",") != "old.metric" {
+			t.Errorf("Unexpected Gone data %+v", gone)
+		}
+	})
+}
+
+func TestExportDashboard(t *testing.T) {
+	var widgetsQuery string
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/builder/report/infra":
+			w.Write([]byte(exportDashboardJSON))
+		case "/api/v1/builder/widget":
+			widgetsQuery = r.URL.RawQuery
+			w.Write([]byte(exportWidgetsJSON))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "not found"}`))
+		}
+	})
+
+	result, err := tools.HandleExportDashboard(s, context.Background(), newCallToolRequest(map[string]any{"report_key": "infra"}))
+	if err != nil {
+		t.Fatalf("HandleExportDashboard() error = %v", err)
+	}
+	if widgetsQuery != "report_id=7" {
+		t.Errorf("Expected widgets of report 7, got query %q", widgetsQuery)
+	}
+
+	doc, ok := result.StructuredContent.(*tools.DashboardDocument)
+	if !ok {
+		t.Fatalf("Expected a DashboardDocument, got %#v", result.StructuredContent)
+	}
+	if doc.Version != tools.DashboardDocumentVersion || doc.Label != "Infra" || doc.Description != "Hosts" || doc.Visibility != "public" {
+		t.Errorf("Unexpected dashboard fields: %+v", doc)
+	}
+
+	var labels []string
+	for _, widget := range doc.Widgets {
+		labels = append(labels, widget.Label)
+	}
+	if strings.Join(labels, ",") != "CPU,Memory,Custom" {
+		t.Errorf("Expected widgets ordered by position, got %v", labels)
+	}
+
+	cpu, memory, custom := doc.Widgets[0], doc.Widgets[1], doc.Widgets[2]
+	if cpu.WidgetType != "time_series_chart" || memory.WidgetType != "bar_chart" {
+		t.Errorf("Expected widget types from their app IDs, got %q and %q", cpu.WidgetType, memory.WidgetType)
+	}
+	if custom.WidgetType != "" || custom.WidgetAppID != 99 {
+		t.Errorf("Expected an unknown app ID to be kept, got %+v", custom)
+	}
+	if memory.Layout == nil || memory.Layout.X != 6 || memory.Layout.Y != 6 || memory.Layout.W != 6 {
+		t.Errorf("Expected the scope layout to win, got %+v", memory.Layout)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Failed to marshal document: %v", err)
+	}
+	for _, field := range []string{`"id"`, "account_id", "project_id", "user_id", "dataset_id", "scope_id", "reportId", "created_at", "updated_at", `"report"`} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("Expected %s to be stripped, got %s", field, data)
+		}
+	}
+	if !strings.Contains(string(data), "system.memory.usage") || !strings.Contains(string(data), `"displayScope":"infra"`) {
+		t.Errorf("Expected the builder config to be kept, got %s", data)
+	}
+
+	if _, err := tools.HandleExportDashboard(s, context.Background(), newCallToolRequest(map[string]any{"report_key": "missing"})); err == nil {
+		t.Error("Expected an error for a missing dashboard")
+	}
+}
+
+// dashboardAPI is an in-memory Middleware API for the dashboard and widget
+// endpoints used by the dashboard tools. It records every write it receives.
+type dashboardAPI struct {
+	mu         sync.Mutex
+	reports    map[string]middleware.Report
+	widgets    map[int][]middleware.Widget
+	nextID     int
+	failWidget string
+	writes     []string
+	created    []middleware.CustomWidget
+	layouts    []middleware.LayoutItem
+	updated    []middleware.UpsertReportRequest
+	data       map[string]middleware.BuilderDataResponse
+	dataCalls  int
+	requested  []middleware.CustomWidget
+	resources  []string
+	failReport int
+}
+
+func newDashboardAPI(existing ...middleware.Report) *dashboardAPI {
+	api := &dashboardAPI{reports: map[string]middleware.Report{}, widgets: map[int][]middleware.Widget{}, nextID: 100}
+	for _, report := range existing {
+		api.reports[report.Key] = report
+	}
+	return api
+}
+
+func (a *dashboardAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+	if r.Method != http.MethodGet && path != "/builder/widget/data" && path != "/builder/widget/multi-data" {
+		a.writes = append(a.writes, r.Method+" "+path)
+	}
+
+	switch {
+	case r.Method == http.MethodGet && path == "/builder/report":
+		list := middleware.ReportListResponse{Reports: []middleware.Report{}}
+		search := strings.ToLower(r.URL.Query().Get("search"))
+		for _, report := range a.reports {
+			if strings.Contains(strings.ToLower(report.Label), search) {
+				list.Reports = append(list.Reports, report)
+			}
+		}
+		sort.Slice(list.Reports, func(i, j int) bool { return list.Reports[i].ID < list.Reports[j].ID })
+		list.Total = len(list.Reports)
+		json.NewEncoder(w).Encode(list)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/builder/report/favourite/"):
+		a.writes = append(a.writes, "FAVOURITE "+path)
+		w.Write([]byte(`{}`))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/builder/report/"):
+		report, ok := a.reports[strings.TrimPrefix(path, "/builder/report/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "report not found"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(middleware.ReportListResponse{Reports: []middleware.Report{report}, Total: 1})
+	case r.Method == http.MethodPost && path == "/builder/report/clone":
+		var req middleware.UpsertReportRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		a.nextID++
+		json.NewEncoder(w).Encode(middleware.Report{ID: a.nextID, Key: fmt.Sprintf("%s_%d", req.Key, a.nextID), Label: req.Label, Visibility: req.Visibility})
+	case r.Method == http.MethodPost && path == "/builder/report":
+		var req middleware.UpsertReportRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		a.nextID++
+		report := middleware.Report{ID: a.nextID, Key: req.Key, Label: req.Label, Visibility: req.Visibility, MetaData: req.MetaData}
+		a.reports[req.Key] = report
+		json.NewEncoder(w).Encode(report)
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/builder/report/"):
+		var req middleware.UpsertReportRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		a.updated = append(a.updated, req)
+		json.NewEncoder(w).Encode(middleware.Report{ID: req.ID, Key: req.Key, Label: req.Label})
+	case r.Method == http.MethodGet && path == "/builder/widget":
+		reportID, _ := strconv.Atoi(r.URL.Query().Get("report_id"))
+		json.NewEncoder(w).Encode(a.widgets[reportID])
+	case r.Method == http.MethodPost && path == "/builder/widget":
+		var widget middleware.CustomWidget
+		json.NewDecoder(r.Body).Decode(&widget)
+		if widget.Label == a.failWidget {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "widget rejected"}`))
+			return
+		}
+		a.created = append(a.created, widget)
+		a.nextID++
+		json.NewEncoder(w).Encode(middleware.Widget{ID: a.nextID, Key: widget.Key, Label: widget.Label, Scope: &middleware.WidgetScope{ID: a.nextID + 1000}})
+	case r.Method == http.MethodPost && path == "/builder/widget/multi-data":
+		var widgets []middleware.CustomWidget
+		json.NewDecoder(r.Body).Decode(&widgets)
+		a.dataCalls++
+		a.requested = append(a.requested, widgets...)
+		responses := []middleware.BuilderDataResponse{}
+		for _, widget := range widgets {
+			response := a.data[widget.Key]
+			response.Key = widget.Key
+			responses = append(responses, response)
+		}
+		json.NewEncoder(w).Encode(responses)
+	case r.Method == http.MethodPost && path == "/builder/widget/data":
+		var widget middleware.CustomWidget
+		json.NewDecoder(r.Body).Decode(&widget)
+		a.dataCalls++
+		a.requested = append(a.requested, widget)
+		response := a.data[widget.Key]
+		response.Key = widget.Key
+		json.NewEncoder(w).Encode(response)
+	case r.Method == http.MethodPut && path == "/builder/widget/scope/layouts":
+		var req middleware.LayoutRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		a.layouts = req.Layouts
+		w.Write([]byte(`{}`))
+	case r.Method == http.MethodGet && path == "/builder/resources" && a.resources != nil:
+		json.NewEncoder(w).Encode(a.resources)
+	case r.Method == http.MethodDelete && a.failReport > 0 && path == fmt.Sprintf("/builder/report/%d", a.failReport):
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "report rejected"}`))
+	case r.Method == http.MethodDelete:
+		w.Write([]byte(`{}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "not found"}`))
+	}
+}
+
+func importDocument() map[string]any {
+	return map[string]any{
+		"version":    1,
+		"key":        "infra",
+		"label":      "Infra",
+		"visibility": "public",
+		"widgets": []any{
+			map[string]any{
+				"key": "cpu_1", "label": "CPU", "widget_type": "time_series_chart",
+				"config": map[string]any{"builderConfig": []any{map[string]any{"columns": []any{"system.cpu.utilization"}}}},
+				"layout": map[string]any{"x": 0, "y": 0, "w": 6, "h": 6},
+			},
+			map[string]any{
+				"key": "memory_2", "label": "Memory", "widget_type": "bar_chart",
+				"layout": map[string]any{"x": 6, "y": 0, "w": 6, "h": 6},
+			},
+		},
+	}
+}
+
+func TestImportDashboard(t *testing.T) {
+	existing := middleware.Report{ID: 7, Key: "infra", Label: "Old Infra", Visibility: "private"}
+
+	tests := []struct {
+		name       string
+		api        *dashboardAPI
+		args       map[string]any
+		wantErr    string
+		wantAction string
+		wantKey    string
+		wantLabel  string
+		wantWrites []string
+		check      func(t *testing.T, api *dashboardAPI, result *tools.ImportDashboardResult)
+	}{
+		{
+			name:       "create",
+			api:        newDashboardAPI(),
+			args:       map[string]any{},
+			wantAction: "create",
+			wantKey:    "infra",
+			wantLabel:  "Infra",
+			wantWrites: []string{"POST /builder/report", "POST /builder/widget", "POST /builder/widget", "PUT /builder/widget/scope/layouts"},
+			check: func(t *testing.T, api *dashboardAPI, result *tools.ImportDashboardResult) {
+				if result.DashboardID != 101 || result.Widgets[0].Key != "cpu_1" || result.Widgets[1].ScopeID != 1103 {
+					t.Errorf("Unexpected result: %+v", result)
+				}
+				first := api.created[0]
+				if first.BuilderViewOptions == nil || first.BuilderViewOptions.Report == nil || first.BuilderViewOptions.Report.ReportID != 101 {
+					t.Errorf("Expected widgets created on the new dashboard, got %+v", first.BuilderViewOptions)
+				}
+				if first.WidgetAppID != 1 || api.created[1].WidgetAppID != 2 || len(first.BuilderConfig) != 1 || first.BuilderID != -1 {
+					t.Errorf("Unexpected widget request: %+v", first)
+				}
+				if len(api.layouts) != 2 || api.layouts[1].ScopeID != 1103 || api.layouts[1].X != 6 {
+					t.Errorf("Expected layouts with the new scope IDs, got %+v", api.layouts)
+				}
+			},
+		},
+		{
+			name:       "dry run",
+			api:        newDashboardAPI(),
+			args:       map[string]any{"dry_run": true},
+			wantAction: "create",
+			wantKey:    "infra",
+			wantLabel:  "Infra",
+			check: func(t *testing.T, api *dashboardAPI, result *tools.ImportDashboardResult) {
+				if !result.DryRun || len(result.Widgets) != 2 || !strings.HasPrefix(result.Message, "Would create") {
+					t.Errorf("Unexpected dry run result: %+v", result)
+				}
+			},
+		},
+		{
+			name:       "skip existing",
+			api:        newDashboardAPI(existing),
+			args:       map[string]any{},
+			wantAction: "skip",
+			wantKey:    "infra",
+			wantLabel:  "Infra",
+		},
+		{
+			name:       "rename",
+			api:        newDashboardAPI(existing, middleware.Report{ID: 8, Key: "infra-2", Label: "Infra (2)"}),
+			args:       map[string]any{"on_conflict": "rename"},
+			wantAction: "rename",
+			wantKey:    "infra-3",
+			wantLabel:  "Infra (3)",
+			wantWrites: []string{"POST /builder/report", "POST /builder/widget", "POST /builder/widget", "PUT /builder/widget/scope/layouts"},
+			check: func(t *testing.T, api *dashboardAPI, result *tools.ImportDashboardResult) {
+				if api.created[0].Key == "cpu_1" {
+					t.Error("Expected new widget keys for a renamed dashboard")
+				}
+			},
+		},
+		{
+			name: "overwrite",
+			api: func() *dashboardAPI {
+				api := newDashboardAPI(existing)
+				api.widgets[7] = []middleware.Widget{{ID: 50, Label: "Old"}}
+				return api
+			}(),
+			args:       map[string]any{"on_conflict": "overwrite"},
+			wantAction: "overwrite",
+			wantKey:    "infra",
+			wantLabel:  "Infra",
+			wantWrites: []string{"POST /builder/widget", "POST /builder/widget", "PUT /builder/widget/scope/layouts", "PUT /builder/report/7", "DELETE /builder/widget/50"},
+			check: func(t *testing.T, api *dashboardAPI, result *tools.ImportDashboardResult) {
+				if result.DashboardID != 7 || result.ReplacedWidgets != 1 || len(api.updated) != 1 || api.updated[0].Key != "infra" {
+					t.Errorf("Unexpected overwrite: %+v, updated %+v", result, api.updated)
+				}
+			},
+		},
+		{
+			name: "rollback",
+			api: func() *dashboardAPI {
+				api := newDashboardAPI()
+				api.failWidget = "Memory"
+				return api
+			}(),
+			args:       map[string]any{},
+			wantErr:    "import rolled back",
+			wantWrites: []string{"POST /builder/report", "POST /builder/widget", "POST /builder/widget", "DELETE /builder/widget/102", "DELETE /builder/report/101"},
+		},
+		{
+			name:    "unsupported version",
+			api:     newDashboardAPI(),
+			args:    map[string]any{"version": 2},
+			wantErr: "unsupported dashboard document version 2",
+		},
+		{
+			name:    "unknown widget type",
+			api:     newDashboardAPI(),
+			args:    map[string]any{"widget_type": "sparkline"},
+			wantErr: `unknown type "sparkline"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, tt.api.ServeHTTP)
+			doc := importDocument()
+			args := map[string]any{"document": doc}
+			for key, value := range tt.args {
+				switch key {
+				case "version":
+					doc["version"] = value
+				case "widget_type":
+					doc["widgets"].([]any)[1].(map[string]any)["widget_type"] = value
+				default:
+					args[key] = value
+				}
+			}
+
+			result, err := tools.HandleImportDashboard(s, context.Background(), newCallToolRequest(args))
+			if !reflect.DeepEqual(tt.api.writes, tt.wantWrites) {
+				t.Errorf("Expected writes %v, got %v", tt.wantWrites, tt.api.writes)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HandleImportDashboard() error = %v", err)
+			}
+
+			imported, ok := result.StructuredContent.(*tools.ImportDashboardResult)
+			if !ok {
+				t.Fatalf("Expected an ImportDashboardResult, got %#v", result.StructuredContent)
+			}
+			if imported.Action != tt.wantAction || imported.Key != tt.wantKey || imported.Label != tt.wantLabel {
+				t.Errorf("Expected %s of %s (%s), got %+v", tt.wantAction, tt.wantKey, tt.wantLabel, imported)
+			}
+			if tt.check != nil {
+				tt.check(t, tt.api, imported)
+			}
+		})
+	}
+}
+
+func TestPlanAndApplyDashboards(t *testing.T) {
+	api := newDashboardAPI(
+		middleware.Report{ID: 7, Key: "infra", Label: "Infra", Visibility: "public"},
+		middleware.Report{ID: 8, Key: "retired", Label: "Retired", MetaData: map[string]any{"managed_by": "mcp-middleware"}},
+	)
+	layout := func(x, y int) map[string]any {
+		return map[string]any{"layout": map[string]any{"x": float64(x), "y": float64(y), "w": float64(6), "h": float64(6)}}
+	}
+	api.widgets[7] = []middleware.Widget{
+		{ID: 50, Key: "cpu_1", Label: "CPU", WidgetAppID: 1, Scope: &middleware.WidgetScope{ID: 500, MetaData: layout(0, 0)},
+			Config: map[string]any{"category": "Metrics", "builderConfig": []any{map[string]any{"columns": []any{"system.cpu.utilization"}}}}},
+		{ID: 51, Key: "memory_2", Label: "Memory", WidgetAppID: 1, Scope: &middleware.WidgetScope{ID: 501, MetaData: layout(6, 0)},
+			Config: map[string]any{"category": "Metrics"}},
+		{ID: 52, Key: "disk_3", Label: "Disk", WidgetAppID: 1, Scope: &middleware.WidgetScope{ID: 502, MetaData: layout(0, 6)}},
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"infra.yaml": `key: infra
+label: Infra
+widgets:
+  - key: cpu_1
+    label: CPU
+    config:
+      builderConfig:
+        - columns: [system.cpu.utilization]
+    layout: {x: 0, y: 0, w: 6, h: 6}
+  - label: Memory
+    widget_type: bar_chart
+    layout: {x: 0, y: 6, w: 6, h: 6}
+  - key: network_4
+    label: Network
+    layout: {x: 6, y: 0, w: 6, h: 6}
+`,
+		"logs.yml": `key: logs
+label: Logs
+visibility: public
+widgets:
+  - label: Log volume
+    widget_type: count_chart
+    layout: {x: 0, y: 0, w: 4, h: 6}
+`,
+		"README.md": "not a dashboard",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	docs, paths, err := tools.LoadDashboardDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDashboardDir() error = %v", err)
+	}
+	if len(docs) != 2 || docs[0].Key != "infra" || docs[1].Key != "logs" || len(paths) != 2 {
+		t.Fatalf("Expected the infra and logs documents, got %d documents", len(docs))
+	}
+
+	s := newTestServer(t, api.ServeHTTP)
+	ctx := context.Background()
+	plan, err := tools.PlanDashboards(ctx, s.Client(), docs, paths, false)
+	if err != nil {
+		t.Fatalf("PlanDashboards() error = %v", err)
+	}
+	if infra := plan.Dashboards[0]; infra.Action != tools.PlanSkip || len(infra.Removed) != 0 {
+		t.Fatalf("Expected the unmanaged infra dashboard to be skipped, got %+v", infra)
+	}
+
+	plan, err = tools.PlanDashboards(ctx, s.Client(), docs, paths, true)
+	if err != nil {
+		t.Fatalf("PlanDashboards() error = %v", err)
+	}
+
+	infra, logs := plan.Dashboards[0], plan.Dashboards[1]
+	if infra.Action != tools.PlanUpdate || logs.Action != tools.PlanCreate {
+		t.Fatalf("Expected to update infra and create logs, got %s and %s", infra.Action, logs.Action)
+	}
+	if len(infra.Settings) != 1 || infra.Settings[0].Field != "meta_data.managed_by" {
+		t.Errorf("Expected only the managed tag to change, got %+v", infra.Settings)
+	}
+	if len(infra.Added) != 1 || infra.Added[0].Label != "Network" {
+		t.Errorf("Expected Network to be added, got %+v", infra.Added)
+	}
+	if len(infra.Changed) != 1 || infra.Changed[0].BuilderID != 51 || strings.Join(infra.Changed[0].Fields, ",") != "widget_type" {
+		t.Errorf("Expected the type of Memory to change, got %+v", infra.Changed)
+	}
+	if len(infra.Removed) != 1 || infra.Removed[0].BuilderID != 52 {
+		t.Errorf("Expected Disk to be removed, got %+v", infra.Removed)
+	}
+	if len(infra.Moved) != 1 || infra.Moved[0].Label != "Memory" || infra.Moved[0].To.Y != 6 {
+		t.Errorf("Expected Memory to move, got %+v", infra.Moved)
+	}
+	if strings.Join(plan.Unlisted, ",") != "retired" {
+		t.Errorf("Expected retired to be unlisted, got %v", plan.Unlisted)
+	}
+
+	var logged []string
+	err = tools.ApplyDashboardPlan(ctx, s.Client(), plan, func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	})
+	if err != nil {
+		t.Fatalf("ApplyDashboardPlan() error = %v", err)
+	}
+
+	wantWrites := []string{
+		"PUT /builder/report/7", "POST /builder/widget", "POST /builder/widget", "DELETE /builder/widget/52", "PUT /builder/widget/scope/layouts",
+		"POST /builder/report", "POST /builder/widget", "PUT /builder/widget/scope/layouts",
+	}
+	if !reflect.DeepEqual(api.writes, wantWrites) {
+		t.Errorf("Expected writes %v, got %v", wantWrites, api.writes)
+	}
+	if meta, _ := api.updated[0].MetaData.(map[string]any); meta["managed_by"] != "mcp-middleware" || api.updated[0].Visibility != "public" {
+		t.Errorf("Expected infra to be tagged and keep its visibility, got %+v", api.updated[0])
+	}
+	update := api.created[1]
+	if update.BuilderID != 51 || update.WidgetAppID != 2 || update.Category != "Metrics" || update.Key != "memory_2" {
+		t.Errorf("Expected Memory to be updated over its current config, got %+v", update)
+	}
+	if len(api.layouts) != 1 || api.layouts[0].ScopeID == 0 {
+		t.Errorf("Expected the new dashboard's layouts last, got %+v", api.layouts)
+	}
+	if len(logged) == 0 {
+		t.Error("Expected progress to be logged")
+	}
+}
+
+func TestApplyDashboardPlanSkipsUnscopedMoves(t *testing.T) {
+	api := newDashboardAPI(middleware.Report{ID: 7, Key: "infra", Label: "Infra", MetaData: map[string]any{"managed_by": "mcp-middleware"}})
+	api.widgets[7] = []middleware.Widget{{ID: 50, Key: "cpu_1", Label: "CPU", WidgetAppID: 1}}
+	docs := []*tools.DashboardDocument{{Key: "infra", Label: "Infra", Widgets: []tools.DashboardDocWidget{
+		{Key: "cpu_1", Label: "CPU", Layout: &tools.WidgetLayout{X: 6, Y: 0, W: 6, H: 6}},
+	}}}
+
+	s := newTestServer(t, api.ServeHTTP)
+	ctx := context.Background()
+	plan, err := tools.PlanDashboards(ctx, s.Client(), docs, nil, false)
+	if err != nil {
+		t.Fatalf("PlanDashboards() error = %v", err)
+	}
+	if len(plan.Dashboards[0].Moved) != 1 {
+		t.Fatalf("Expected CPU to move, got %+v", plan.Dashboards[0])
+	}
+
+	var logged []string
+	err = tools.ApplyDashboardPlan(ctx, s.Client(), plan, func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	})
+	if err != nil {
+		t.Fatalf("ApplyDashboardPlan() error = %v", err)
+	}
+	if len(api.writes) != 0 {
+		t.Errorf("Expected no layout sent for a widget without a scope, got %v", api.writes)
+	}
+	if !strings.Contains(strings.Join(logged, "\n"), "skipped the layouts of 1 widgets") {
+		t.Errorf("Expected the skipped layout to be logged, got %v", logged)
+	}
+}
+
+func TestDiffDashboards(t *testing.T) {
+	api := newDashboardAPI(
+		middleware.Report{ID: 7, Key: "infra", Label: "Infra", Visibility: "public"},
+		middleware.Report{ID: 8, Key: "infra-staging", Label: "Infra staging", Visibility: "public"},
+	)
+	layout := func(x, y int) map[string]any {
+		return map[string]any{"layout": map[string]any{"x": float64(x), "y": float64(y), "w": float64(6), "h": float64(6)}}
+	}
+	query := func(columns []any, filter any, groupBy ...any) map[string]any {
+		with := []any{map[string]any{"key": "SELECT_DATA_BY", "value": groupBy}}
+		if filter != nil {
+			with = append(with, map[string]any{"key": "ATTRIBUTE_FILTER", "value": filter})
+		}
+		return map[string]any{"builderConfig": []any{map[string]any{"columns": columns, "with": with}}, "category": "Metrics"}
+	}
+	hostFilter := map[string]any{"and": []any{map[string]any{"host.name": map[string]any{"=": "web-1"}}}}
+	api.widgets[7] = []middleware.Widget{
+		{ID: 50, Key: "cpu_1", Label: "CPU", WidgetAppID: 1, Scope: &middleware.WidgetScope{ID: 500, MetaData: layout(0, 0)},
+			Config: query([]any{"system.cpu.utilization"}, nil, "host.name")},
+		{ID: 51, Key: "memory_2", Label: "Memory", WidgetAppID: 1, Scope: &middleware.WidgetScope{ID: 501, MetaData: layout(6, 0)},
+			Config: map[string]any{"category": "Metrics"}},
+		{ID: 52, Key: "disk_3", Label: "Disk", WidgetAppID: 1, Scope: &middleware.WidgetScope{ID: 502, MetaData: layout(0, 6)}},
+	}
+	api.widgets[8] = []middleware.Widget{
+		{ID: 60, Key: "cpu_9", Label: "CPU", WidgetAppID: 2, Scope: &middleware.WidgetScope{ID: 600, MetaData: layout(0, 0)},
+			Config: query([]any{"system.cpu.utilization", "system.cpu.load_average.1m"}, hostFilter, "host.id")},
+		{ID: 61, Key: "memory_2", Label: "Memory", WidgetAppID: 1, Scope: &middleware.WidgetScope{ID: 601, MetaData: layout(0, 6)},
+			Config: map[string]any{"category": "Logs"}},
+		{ID: 62, Key: "network_4", Label: "Network", WidgetAppID: 1, Scope: &middleware.WidgetScope{ID: 602, MetaData: layout(6, 0)}},
+	}
+	s := newTestServer(t, api.ServeHTTP)
+	ctx := context.Background()
+
+	t.Run("two dashboards", func(t *testing.T) {
+		result, err := tools.HandleDiffDashboards(s, ctx, newCallToolRequest(map[string]any{"base_key": "infra", "target_key": "infra-staging"}))
+		if err != nil {
+			t.Fatalf("HandleDiffDashboards() error = %v", err)
+		}
+		diff, ok := result.StructuredContent.(*tools.DashboardDiff)
+		if !ok {
+			t.Fatalf("Expected a DashboardDiff, got %#v", result.StructuredContent)
+		}
+		if diff.Identical || len(diff.Settings) != 1 || diff.Settings[0].Field != "label" || diff.Settings[0].To != "Infra staging" {
+			t.Errorf("Expected the label to change, got %+v", diff.Settings)
+		}
+		if len(diff.Added) != 1 || diff.Added[0].Label != "Network" || len(diff.Removed) != 1 || diff.Removed[0].Label != "Disk" {
+			t.Errorf("Expected Network added and Disk removed, got %+v and %+v", diff.Added, diff.Removed)
+		}
+		if len(diff.Changed) != 2 {
+			t.Fatalf("Expected CPU and Memory to change, got %+v", diff.Changed)
+		}
+
+		cpu, memory := diff.Changed[0], diff.Changed[1]
+		if cpu.Label != "CPU" || cpu.ChartType == nil || cpu.ChartType.From != "time_series_chart" || cpu.ChartType.To != "bar_chart" {
+			t.Errorf("Expected the CPU chart type to change, got %+v", cpu.ChartType)
+		}
+		if cpu.Columns == nil || !reflect.DeepEqual(cpu.Columns.Added, []string{"system.cpu.load_average.1m"}) || len(cpu.Columns.Removed) != 0 {
+			t.Errorf("Expected a column to be added, got %+v", cpu.Columns)
+		}
+		if cpu.GroupBy == nil || !reflect.DeepEqual(cpu.GroupBy.Added, []string{"host.id"}) || !reflect.DeepEqual(cpu.GroupBy.Removed, []string{"host.name"}) {
+			t.Errorf("Expected the group by to change, got %+v", cpu.GroupBy)
+		}
+		if len(cpu.Filters) != 1 || cpu.Filters[0].From != "" || !strings.Contains(cpu.Filters[0].To, "web-1") {
+			t.Errorf("Expected a filter to be added, got %+v", cpu.Filters)
+		}
+		if len(cpu.Config) != 0 || cpu.Layout != nil {
+			t.Errorf("Expected no other CPU changes, got %+v and %+v", cpu.Config, cpu.Layout)
+		}
+		if !reflect.DeepEqual(memory.Config, []string{"config.category"}) || memory.Layout == nil || memory.Layout.To.Y != 6 {
+			t.Errorf("Expected Memory to change category and move, got %+v", memory)
+		}
+
+		for _, line := range []string{`+ widget "Network"`, `- widget "Disk"`, "chart type: time_series_chart -> bar_chart", "group by: +host.id -host.name", "moved from 6,0 6x6 to 0,6 6x6"} {
+			if !strings.Contains(diff.Text, line) {
+				t.Errorf("Expected %q in the text, got:\n%s", line, diff.Text)
+			}
+		}
+	})
+
+	t.Run("dashboard and its own document", func(t *testing.T) {
+		exported, err := tools.HandleExportDashboard(s, ctx, newCallToolRequest(map[string]any{"report_key": "infra"}))
+		if err != nil {
+			t.Fatalf("HandleExportDashboard() error = %v", err)
+		}
+		data, _ := json.Marshal(exported.StructuredContent)
+		var doc map[string]any
+		json.Unmarshal(data, &doc)
+
+		result, err := tools.HandleDiffDashboards(s, ctx, newCallToolRequest(map[string]any{"base_key": "infra", "target_document": doc}))
+		if err != nil {
+			t.Fatalf("HandleDiffDashboards() error = %v", err)
+		}
+		diff := result.StructuredContent.(*tools.DashboardDiff)
+		if !diff.Identical || !strings.Contains(diff.Text, "No differences.") {
+			t.Errorf("Expected no differences, got %+v", diff)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		for _, args := range []map[string]any{
+			{"base_key": "infra"},
+			{"base_key": "infra", "target_key": "infra-staging", "target_document": importDocument()},
+			{"base_key": "missing", "target_key": "infra"},
+		} {
+			if _, err := tools.HandleDiffDashboards(s, ctx, newCallToolRequest(args)); err == nil {
+				t.Errorf("Expected an error for %v", args)
+			}
+		}
+	})
+}
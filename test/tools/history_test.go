@@ -0,0 +1,232 @@
+package tools_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-middleware/middleware"
+	"mcp-middleware/server/tools"
+)
+
+// newHistoryAPI returns a dashboard API with the infra dashboard and three
+// widgets, and a test server keeping up to versions versions of it.
+func newHistoryAPI(t *testing.T, versions int) (*dashboardAPI, *testServer) {
+	api := newDashboardAPI(middleware.Report{ID: 7, Key: "infra", Label: "Infra", Visibility: "public"})
+	layout := func(x, y int) map[string]any {
+		return map[string]any{"layout": map[string]any{"x": float64(x), "y": float64(y), "w": float64(6), "h": float64(6)}}
+	}
+	api.widgets[7] = []middleware.Widget{
+		{ID: 50, Key: "cpu_1", Label: "CPU", WidgetAppID: 1, Scope: &middleware.WidgetScope{ID: 500, ReportID: 7, MetaData: layout(0, 0)},
+			Config: map[string]any{"category": "Metrics"}},
+		{ID: 51, Key: "memory_2", Label: "Memory", WidgetAppID: 1, Scope: &middleware.WidgetScope{ID: 501, ReportID: 7, MetaData: layout(6, 0)},
+			Config: map[string]any{"category": "Metrics"}},
+		{ID: 52, Key: "disk_3", Label: "Disk", WidgetAppID: 1, Scope: &middleware.WidgetScope{ID: 502, ReportID: 7, MetaData: layout(0, 6)}},
+	}
+	// Widgets listed without a report ID, for lookups by builder or scope ID
+	api.widgets[0] = api.widgets[7]
+	s := newTestServer(t, api.ServeHTTP)
+	s.config.MiddlewareAPIKey = "test-key"
+	s.config.DashboardHistoryDir = t.TempDir()
+	s.config.DashboardHistoryVersions = versions
+	return api, s
+}
+
+func listVersions(t *testing.T, s *testServer, args map[string]any) *tools.ListDashboardVersionsResult {
+	t.Helper()
+	result, err := tools.HandleListDashboardVersions(s, context.Background(), newCallToolRequest(args))
+	if err != nil {
+		t.Fatalf("HandleListDashboardVersions() error = %v", err)
+	}
+	return result.StructuredContent.(*tools.ListDashboardVersionsResult)
+}
+
+func snapshot(s *testServer, tool string, args map[string]any) error {
+	req := newCallToolRequest(args)
+	req.Params.Name = tool
+	return tools.SnapshotDashboards(context.Background(), s, req)
+}
+
+func TestSnapshotDashboards(t *testing.T) {
+	tests := []struct {
+		tool string
+		args map[string]any
+		want int
+	}{
+		{"delete_dashboard", map[string]any{"id": float64(7)}, 1},
+		{"update_widget", map[string]any{"report_key": "infra"}, 1},
+		{"update_widget", map[string]any{"builder_id": float64(51), "report_id": float64(7)}, 1},
+		{"update_widget", map[string]any{"builder_id": float64(51)}, 1},
+		{"delete_widget", map[string]any{"builder_id": float64(52)}, 1},
+		{"update_widget_layouts", map[string]any{"layouts": []any{map[string]any{"scope_id": float64(500)}}}, 1},
+		{"create_widget", map[string]any{"report_name": "New"}, 0},
+		{"import_dashboard", map[string]any{"document": map[string]any{"key": "infra"}}, 1},
+		{"import_dashboard", map[string]any{"document": map[string]any{"key": "new"}}, 0},
+		{"create_alert", map[string]any{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			_, s := newHistoryAPI(t, 5)
+			if err := snapshot(s, tt.tool, tt.args); err != nil {
+				t.Fatalf("SnapshotDashboards() error = %v", err)
+			}
+
+			list := listVersions(t, s, map[string]any{})
+			if list.Total != tt.want {
+				t.Fatalf("Expected %d versions, got %d", tt.want, list.Total)
+			}
+			if tt.want > 0 {
+				version := list.Versions[0]
+				if version.Key != "infra" || version.DashboardID != 7 || version.Tool != tt.tool || version.Widgets != 3 {
+					t.Errorf("Unexpected version %+v", version)
+				}
+			}
+		})
+	}
+
+	t.Run("dashboard not found", func(t *testing.T) {
+		_, s := newHistoryAPI(t, 5)
+		for tool, args := range map[string]map[string]any{
+			"update_widget":         {"builder_id": float64(99)},
+			"update_widget_layouts": {"layouts": []any{map[string]any{"scope_id": float64(999)}}},
+		} {
+			if err := snapshot(s, tool, args); err == nil {
+				t.Errorf("Expected %s to be refused when its dashboard can't be found", tool)
+			}
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, s := newHistoryAPI(t, 0)
+		snapshot(s, "delete_dashboard", map[string]any{"id": float64(7)})
+		if _, err := tools.HandleListDashboardVersions(s, context.Background(), newCallToolRequest(map[string]any{})); err == nil {
+			t.Error("Expected an error with the history disabled")
+		}
+	})
+}
+
+func TestDashboardHistoryRetention(t *testing.T) {
+	_, s := newHistoryAPI(t, 2)
+	s.config.DashboardHistoryMaxAge = time.Hour
+
+	// A version older than the max age, left by an earlier run
+	dir := filepath.Join(s.config.DashboardHistoryDir, "default")
+	old := tools.DashboardVersion{ID: "7-1", CreatedAt: time.Now().Add(-2 * time.Hour), Dashboard: middleware.Report{ID: 7, Key: "infra"}}
+	data, _ := json.Marshal(old)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "7-1.json"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot(s, "update_dashboard", map[string]any{"id": float64(7)})
+	if list := listVersions(t, s, map[string]any{}); list.Total != 1 || list.Versions[0].ID == "7-1" {
+		t.Fatalf("Expected the old version to be pruned, got %+v", list.Versions)
+	}
+
+	for i := 0; i < 3; i++ {
+		snapshot(s, "update_dashboard", map[string]any{"id": float64(7)})
+	}
+	list := listVersions(t, s, map[string]any{"report_key": "infra"})
+	if list.Total != 2 {
+		t.Fatalf("Expected 2 versions kept, got %d", list.Total)
+	}
+	if !(list.Versions[0].CreatedAt >= list.Versions[1].CreatedAt) {
+		t.Errorf("Expected the newest version first, got %+v", list.Versions)
+	}
+	if list := listVersions(t, s, map[string]any{"report_key": "other"}); list.Total != 0 {
+		t.Errorf("Expected no versions of another dashboard, got %d", list.Total)
+	}
+}
+
+func TestRestoreDashboardVersion(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("recreate deleted dashboard", func(t *testing.T) {
+		api, s := newHistoryAPI(t, 5)
+		snapshot(s, "delete_dashboard", map[string]any{"id": float64(7)})
+		versionID := listVersions(t, s, map[string]any{}).Versions[0].ID
+		delete(api.reports, "infra")
+
+		result, err := tools.HandleRestoreDashboardVersion(s, ctx, newCallToolRequest(map[string]any{"version_id": versionID}))
+		if err != nil {
+			t.Fatalf("HandleRestoreDashboardVersion() error = %v", err)
+		}
+		restored := result.StructuredContent.(*tools.RestoreDashboardVersionResult)
+		if restored.Action != "recreate" || len(restored.Recreated) != 3 || restored.DashboardID == 0 {
+			t.Errorf("Unexpected result %+v", restored)
+		}
+		if report, ok := api.reports["infra"]; !ok || report.Label != "Infra" || report.Visibility != "public" {
+			t.Errorf("Expected the dashboard to be recreated, got %+v", api.reports)
+		}
+		if len(api.created) != 3 || api.created[0].Key != "cpu_1" || len(api.layouts) != 3 {
+			t.Errorf("Expected 3 widgets recreated with their keys and layouts, got %d and %d", len(api.created), len(api.layouts))
+		}
+	})
+
+	t.Run("roll back widgets", func(t *testing.T) {
+		api, s := newHistoryAPI(t, 5)
+		snapshot(s, "update_widget", map[string]any{"report_key": "infra"})
+		versionID := listVersions(t, s, map[string]any{}).Versions[0].ID
+
+		// CPU reconfigured, Memory moved, Disk deleted and Network added since
+		widgets := api.widgets[7]
+		widgets[0].Config = map[string]any{"category": "Logs"}
+		widgets[1].Scope = &middleware.WidgetScope{ID: 501, ReportID: 7, MetaData: map[string]any{"layout": map[string]any{"x": float64(0), "y": float64(12), "w": float64(6), "h": float64(6)}}}
+		api.widgets[7] = []middleware.Widget{widgets[0], widgets[1], {ID: 53, Key: "network_4", Label: "Network", WidgetAppID: 1, Scope: &middleware.WidgetScope{ID: 503, ReportID: 7}}}
+
+		result, err := tools.HandleRestoreDashboardVersion(s, ctx, newCallToolRequest(map[string]any{"version_id": versionID, "dry_run": true}))
+		if err != nil {
+			t.Fatalf("HandleRestoreDashboardVersion() error = %v", err)
+		}
+		plan := result.StructuredContent.(*tools.RestoreDashboardVersionResult)
+		if plan.Action != "restore" || strings.Join(plan.Recreated, ",") != "Disk" || strings.Join(plan.Reverted, ",") != "CPU" ||
+			strings.Join(plan.Moved, ",") != "Memory" || strings.Join(plan.Kept, ",") != "Network" || len(plan.Removed) != 0 {
+			t.Errorf("Unexpected dry run %+v", plan)
+		}
+		if len(api.writes) != 0 {
+			t.Fatalf("Expected no writes on a dry run, got %v", api.writes)
+		}
+
+		result, err = tools.HandleRestoreDashboardVersion(s, ctx, newCallToolRequest(map[string]any{"version_id": versionID, "remove_new_widgets": true}))
+		if err != nil {
+			t.Fatalf("HandleRestoreDashboardVersion() error = %v", err)
+		}
+		restored := result.StructuredContent.(*tools.RestoreDashboardVersionResult)
+		if strings.Join(restored.Removed, ",") != "Network" || len(restored.Kept) != 0 {
+			t.Errorf("Expected Network removed, got %+v", restored)
+		}
+		if len(api.created) != 2 || api.created[0].Label != "Disk" || api.created[0].BuilderID > 0 {
+			t.Fatalf("Expected Disk recreated and CPU updated, got %+v", api.created)
+		}
+		if cpu := api.created[1]; cpu.BuilderID != 50 || cpu.Category != "Metrics" {
+			t.Errorf("Expected the CPU config rolled back, got %+v", cpu)
+		}
+		if !strings.Contains(strings.Join(api.writes, ","), "DELETE /builder/widget/53") {
+			t.Errorf("Expected Network deleted, got %v", api.writes)
+		}
+		if len(api.layouts) != 2 {
+			t.Errorf("Expected Memory and Disk positioned, got %+v", api.layouts)
+		}
+		for _, layout := range api.layouts {
+			if layout.ScopeID == 501 && (layout.X != 6 || layout.Y != 0) {
+				t.Errorf("Expected Memory moved back to 6,0, got %+v", layout)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, s := newHistoryAPI(t, 5)
+		for _, id := range []string{"", "../7-1", "7-1"} {
+			if _, err := tools.HandleRestoreDashboardVersion(s, ctx, newCallToolRequest(map[string]any{"version_id": id})); err == nil {
+				t.Errorf("Expected an error for version %q", id)
+			}
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package tools_test
+
+import (
+	"context"
+	"testing"
+
+	"mcp-middleware/middleware"
+	"mcp-middleware/server/tools"
+)
+
+func TestLintDashboard(t *testing.T) {
+	ctx := context.Background()
+
+	lintWidget := func(id int, key, label, source string, layout map[string]any) middleware.Widget {
+		config := map[string]any{"builderConfig": []any{map[string]any{
+			"columns": []any{"system.cpu.utilization"},
+			"source":  map[string]any{"name": source},
+		}}}
+		if layout != nil {
+			config["layout"] = layout
+		}
+		return middleware.Widget{ID: id, Key: key, Label: label, WidgetAppID: 1, Config: config}
+	}
+	newAPI := func() *dashboardAPI {
+		api := newDashboardAPI(middleware.Report{ID: 7, Key: "infra", Label: "Infra"})
+		api.widgets[7] = []middleware.Widget{
+			lintWidget(50, "cpu_1", "CPU", "host", map[string]any{"x": 0, "y": 0, "w": 6, "h": 6}),
+			lintWidget(51, "memory_2", "Memory", "host", map[string]any{"x": 6, "y": 0, "w": 6, "h": 6}),
+			lintWidget(52, "pods_3", "cpu", "k8s.pod", map[string]any{"x": 4, "y": 3, "w": 3, "h": 4}),
+			lintWidget(53, "network_4", "Network", "log", nil),
+		}
+		api.resources = []string{"host", "k8s.node"}
+		api.data = map[string]middleware.BuilderDataResponse{
+			"cpu_1":     {ChartData: []any{map[string]any{"data": []any{1, 2}}}},
+			"memory_2":  {NotAvailableMetrics: []string{"system.memory.usage"}},
+			"network_4": {Error: "bad query", ErrorDesc: "unknown column"},
+		}
+		return api
+	}
+
+	checksOf := func(result *tools.LintDashboardResult) map[string][]int {
+		checks := make(map[string][]int)
+		for _, finding := range result.Findings {
+			checks[finding.Check] = append(checks[finding.Check], finding.WidgetID)
+			if finding.Fix == "" {
+				t.Errorf("Expected a fix for %s of widget %d", finding.Check, finding.WidgetID)
+			}
+		}
+		return checks
+	}
+
+	tests := []struct {
+		name       string
+		args       map[string]any
+		wantChecks map[string][]int
+		wantData   int
+	}{
+		{
+			name: "all checks",
+			args: map[string]any{"report_key": "infra"},
+			wantChecks: map[string][]int{
+				"data_error":           {53},
+				"metric_not_available": {51},
+				"unknown_resource":     {52},
+				"empty_result":         {52},
+				"layout_too_small":     {52},
+				"layout_overlap":       {52, 52},
+				"duplicate_label":      {52},
+				"layout_missing":       {53},
+			},
+			wantData: 1,
+		},
+		{
+			name: "skip data",
+			args: map[string]any{"report_key": "infra", "skip_data": true},
+			wantChecks: map[string][]int{
+				"unknown_resource": {52},
+				"layout_too_small": {52},
+				"layout_overlap":   {52, 52},
+				"duplicate_label":  {52},
+				"layout_missing":   {53},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newAPI()
+			s := newTestServer(t, api.ServeHTTP)
+			result, err := tools.HandleLintDashboard(s, ctx, newCallToolRequest(tt.args))
+			if err != nil {
+				t.Fatalf("HandleLintDashboard() error = %v", err)
+			}
+			lint := result.StructuredContent.(*tools.LintDashboardResult)
+			if lint.Healthy || lint.Widgets != 4 {
+				t.Errorf("Expected 4 unhealthy widgets, got %d healthy=%v", lint.Widgets, lint.Healthy)
+			}
+			checks := checksOf(lint)
+			if len(checks) != len(tt.wantChecks) {
+				t.Errorf("Expected checks %v, got %v", tt.wantChecks, checks)
+			}
+			for check, ids := range tt.wantChecks {
+				if len(checks[check]) != len(ids) || checks[check][0] != ids[0] {
+					t.Errorf("Expected %s for widgets %v, got %v", check, ids, checks[check])
+				}
+			}
+			if api.dataCalls != tt.wantData {
+				t.Errorf("Expected %d data requests, got %d", tt.wantData, api.dataCalls)
+			}
+			if lint.Findings[0].Severity != tools.SeverityError || lint.Findings[len(lint.Findings)-1].Severity != tools.SeverityInfo {
+				t.Errorf("Expected findings sorted by severity, got %v", lint.Findings)
+			}
+			if api.writes != nil {
+				t.Errorf("Expected no writes, got %v", api.writes)
+			}
+		})
+	}
+
+	t.Run("healthy without resources", func(t *testing.T) {
+		api := newAPI()
+		api.widgets[7] = api.widgets[7][:2]
+		api.data["memory_2"] = api.data["cpu_1"]
+		api.resources = nil
+		s := newTestServer(t, api.ServeHTTP)
+		result, err := tools.HandleLintDashboard(s, ctx, newCallToolRequest(map[string]any{"report_key": "infra"}))
+		if err != nil {
+			t.Fatalf("HandleLintDashboard() error = %v", err)
+		}
+		lint := result.StructuredContent.(*tools.LintDashboardResult)
+		if !lint.Healthy || lint.Infos != 1 || lint.Findings[0].Check != "resources_unchecked" {
+			t.Errorf("Expected a healthy dashboard with a note about resources, got %+v", lint)
+		}
+	})
+
+	t.Run("missing dashboard", func(t *testing.T) {
+		s := newTestServer(t, newAPI().ServeHTTP)
+		if _, err := tools.HandleLintDashboard(s, ctx, newCallToolRequest(map[string]any{"report_key": "missing"})); err == nil {
+			t.Error("Expected an error for a missing dashboard")
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package tools_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mcp-middleware/middleware"
+	"mcp-middleware/server/tools"
+)
+
+func TestLoadDashboardTemplates(t *testing.T) {
+	builtin, err := tools.LoadDashboardTemplates("")
+	if err != nil {
+		t.Fatalf("LoadDashboardTemplates() error = %v", err)
+	}
+	var names []string
+	for _, tmpl := range builtin {
+		names = append(names, tmpl.Name)
+	}
+	if strings.Join(names, ",") != "container-fleet,host-overview,kubernetes-cluster,log-volume,service-red" {
+		t.Errorf("Unexpected built-in templates %v", names)
+	}
+
+	dir := t.TempDir()
+	custom := `description: Just the log volume
+variables:
+  - name: team
+    required: true
+dashboard:
+  key: logs-${team}
+  label: Logs of ${team}
+  widgets:
+    - label: Volume
+      widget_type: count_chart
+`
+	if err := os.WriteFile(filepath.Join(dir, "log-volume.yaml"), []byte(custom), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	templates, err := tools.LoadDashboardTemplates(dir)
+	if err != nil {
+		t.Fatalf("LoadDashboardTemplates() error = %v", err)
+	}
+	if len(templates) != len(builtin) {
+		t.Fatalf("Expected the custom template to replace the built-in one, got %d templates", len(templates))
+	}
+	for _, tmpl := range templates {
+		if tmpl.Name == "log-volume" && tmpl.Description != "Just the log volume" {
+			t.Errorf("Expected the custom log-volume template, got %q", tmpl.Description)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"undeclared.yaml": "dashboard: {key: x-${host}, label: X, widgets: []}\n",
+		"no-key.yaml":     "dashboard: {label: X, widgets: []}\n",
+		"bad-type.yaml":   "dashboard: {key: x, label: X, widgets: [{label: A, widget_type: gauge}]}\n",
+	} {
+		bad := t.TempDir()
+		if err := os.WriteFile(filepath.Join(bad, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tools.LoadDashboardTemplates(bad); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestInstantiateDashboardTemplate(t *testing.T) {
+	templates, err := tools.LoadDashboardTemplates("")
+	if err != nil {
+		t.Fatalf("LoadDashboardTemplates() error = %v", err)
+	}
+	var red *tools.DashboardTemplate
+	for _, tmpl := range templates {
+		if tmpl.Name == "service-red" {
+			red = tmpl
+		}
+	}
+
+	if _, err := red.Instantiate(nil); err == nil || !strings.Contains(err.Error(), "requires variable service") {
+		t.Errorf("Expected an error for the missing service, got %v", err)
+	}
+	if _, err := red.Instantiate(map[string]string{"service": "a", "region": "b"}); err == nil {
+		t.Error("Expected an error for an unknown variable")
+	}
+
+	doc, err := red.Instantiate(map[string]string{"service": "Checkout API"})
+	if err != nil {
+		t.Fatalf("Instantiate() error = %v", err)
+	}
+	if doc.Key != "service-checkout-api-red" || doc.Label != "Checkout API RED Metrics" {
+		t.Errorf("Expected variables substituted into key and label, got %q and %q", doc.Key, doc.Label)
+	}
+
+	var layouts []string
+	for _, widget := range doc.Widgets {
+		layouts = append(layouts, widget.Layout.String())
+	}
+	if strings.Join(layouts, "; ") != "0,0 4x6; 4,0 4x6; 8,0 4x6; 0,6 6x6; 6,6 6x6" {
+		t.Errorf("Expected packed layouts, got %v", layouts)
+	}
+
+	data, _ := json.Marshal(doc.Widgets)
+	var widgets []map[string]any
+	json.Unmarshal(data, &widgets)
+	filterOf := func(i int) string {
+		query := widgets[i]["config"].(map[string]any)["builderConfig"].([]any)[0].(map[string]any)
+		for _, item := range query["with"].([]any) {
+			if with := item.(map[string]any); with["key"] == "ATTRIBUTE_FILTER" {
+				data, _ := json.Marshal(with["value"])
+				return string(data)
+			}
+		}
+		return ""
+	}
+	if got := filterOf(0); got != `{"and":[{"service.name":{"=":"Checkout API"}}]}` {
+		t.Errorf("Expected a service filter on Requests, got %s", got)
+	}
+	if got := filterOf(1); got != `{"and":[{"status_code":{"=":"STATUS_CODE_ERROR"}},{"service.name":{"=":"Checkout API"}}]}` {
+		t.Errorf("Expected the service filter added to the Errors filter, got %s", got)
+	}
+	if strings.Contains(string(data), "deployment.environment") {
+		t.Errorf("Expected no filter for the empty environment, got %s", data)
+	}
+}
+
+func TestCreateDashboardFromTemplate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create", func(t *testing.T) {
+		api := newDashboardAPI()
+		s := newTestServer(t, api.ServeHTTP)
+		result, err := tools.HandleCreateDashboardFromTemplate(s, ctx, newCallToolRequest(map[string]any{
+			"template":  "host-overview",
+			"variables": map[string]any{"host": "web-1"},
+		}))
+		if err != nil {
+			t.Fatalf("HandleCreateDashboardFromTemplate() error = %v", err)
+		}
+		created := result.StructuredContent.(*tools.ImportDashboardResult)
+		if created.Key != "host-overview-web-1" || created.Label != "Host Overview web-1" || len(created.Widgets) != 6 {
+			t.Errorf("Unexpected result %+v", created)
+		}
+		if api.reports["host-overview-web-1"].Visibility != "private" {
+			t.Errorf("Expected a private dashboard, got %+v", api.reports["host-overview-web-1"])
+		}
+		if len(api.created) != 6 || len(api.layouts) != 6 {
+			t.Fatalf("Expected 6 widgets with layouts, got %d and %d", len(api.created), len(api.layouts))
+		}
+		if last := api.layouts[5]; last.X != 6 || last.Y != 12 || last.W != 6 || last.H != 6 {
+			t.Errorf("Expected the last widget packed at 6,12, got %+v", last)
+		}
+		if api.created[0].Key == "" || strings.Contains(api.created[0].Key, "${") {
+			t.Errorf("Expected a generated widget key, got %q", api.created[0].Key)
+		}
+	})
+
+	t.Run("dry run and custom key", func(t *testing.T) {
+		api := newDashboardAPI()
+		s := newTestServer(t, api.ServeHTTP)
+		result, err := tools.HandleCreateDashboardFromTemplate(s, ctx, newCallToolRequest(map[string]any{
+			"template": "log-volume", "key": "logs", "label": "Logs", "dry_run": true,
+		}))
+		if err != nil {
+			t.Fatalf("HandleCreateDashboardFromTemplate() error = %v", err)
+		}
+		created := result.StructuredContent.(*tools.ImportDashboardResult)
+		if !created.DryRun || created.Key != "logs" || !strings.HasPrefix(created.Message, "Would create dashboard logs") {
+			t.Errorf("Unexpected dry run result %+v", created)
+		}
+		if len(api.writes) != 0 {
+			t.Errorf("Expected no writes on a dry run, got %v", api.writes)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		api := newDashboardAPI(middleware.Report{ID: 7, Key: "log-volume", Label: "Log Volume"})
+		s := newTestServer(t, api.ServeHTTP)
+		for _, args := range []map[string]any{
+			{"template": "log-volume"},
+			{"template": "missing"},
+			{"template": "service-red"},
+		} {
+			if _, err := tools.HandleCreateDashboardFromTemplate(s, ctx, newCallToolRequest(args)); err == nil {
+				t.Errorf("Expected an error for %v", args)
+			}
+		}
+		if len(api.writes) != 0 {
+			t.Errorf("Expected no writes, got %v", api.writes)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package tools_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mcp-middleware/config"
+	"mcp-middleware/middleware"
+	"mcp-middleware/server/tools"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type testServer struct {
+	client *middleware.Client
+	config *config.Config
+}
+
+func (s *testServer) Client() *middleware.Client {
+	return s.client
+}
+
+func (s *testServer) Config() *config.Config {
+	return s.config
+}
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *testServer {
+	t.Helper()
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return &testServer{
+		client: middleware.NewClient(ts.URL, "test-key"),
+		config: &config.Config{MiddlewareBaseURL: ts.URL, ConfirmDestructive: "auto"},
+	}
+}
+
+func newCallToolRequest(args map[string]any) mcp.CallToolRequest {
+	var req mcp.CallToolRequest
+	req.Params.Arguments = args
+	return req
+}
+
+func TestHandlersReturnStructuredContent(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/builder/report":
+			json.NewEncoder(w).Encode(middleware.ReportListResponse{
+				Reports: []middleware.Report{{ID: 1, Label: "Test Dashboard", Visibility: "public"}},
+				Total:   1,
+			})
+		case "/api/v1/builder/widget":
+			json.NewEncoder(w).Encode([]middleware.Widget{{ID: 7, Label: "CPU"}})
+		case "/api/v1/builder/widget/12":
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("Unexpected request path %s", r.URL.Path)
+		}
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		handler func(tools.ServerInterface, context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+		args    map[string]any
+		check   func(t *testing.T, structured any)
+	}{
+		{
+			name:    "list_dashboards",
+			handler: tools.HandleListDashboards,
+			check: func(t *testing.T, structured any) {
+				res, ok := structured.(*middleware.ReportListResponse)
+				if !ok || res.Total != 1 {
+					t.Errorf("Expected ReportListResponse with Total 1, got %#v", structured)
+				}
+			},
+		},
+		{
+			name:    "list_widgets",
+			handler: tools.HandleListWidgets,
+			args:    map[string]any{"report_id": 3},
+			check: func(t *testing.T, structured any) {
+				res, ok := structured.(tools.ListWidgetsResult)
+				if !ok || len(res.Widgets) != 1 || res.Widgets[0].ID != 7 {
+					t.Errorf("Expected ListWidgetsResult with widget 7, got %#v", structured)
+				}
+			},
+		},
+		{
+			name:    "delete_widget",
+			handler: tools.HandleDeleteWidget,
+			args:    map[string]any{"builder_id": 12, "report_key": "infra"},
+			check: func(t *testing.T, structured any) {
+				res, ok := structured.(tools.OperationResult)
+				if !ok || !res.Success {
+					t.Errorf("Expected successful OperationResult, got %#v", structured)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.handler(s, ctx, newCallToolRequest(tt.args))
+			if err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+			if result.StructuredContent == nil {
+				t.Fatal("Expected structuredContent to be set")
+			}
+			tt.check(t, result.StructuredContent)
+
+			if len(result.Content) != 1 {
+				t.Fatalf("Expected a single text fallback, got %d content items", len(result.Content))
+			}
+			text, ok := result.Content[0].(mcp.TextContent)
+			if !ok {
+				t.Fatalf("Expected TextContent fallback, got %T", result.Content[0])
+			}
+			expected, _ := json.Marshal(result.StructuredContent)
+			if text.Text != string(expected) {
+				t.Errorf("Expected text fallback %s, got %s", expected, text.Text)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package tools_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"mcp-middleware/middleware"
+	"mcp-middleware/server/tools"
+)
+
+func TestDashboardVariables(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create and update", func(t *testing.T) {
+		api := newDashboardAPI(middleware.Report{ID: 7, Key: "infra", Label: "Infra", MetaData: map[string]any{"theme": "dark"}})
+		s := newTestServer(t, api.ServeHTTP)
+
+		_, err := tools.HandleCreateDashboard(s, ctx, newCallToolRequest(map[string]any{
+			"label": "Hosts", "visibility": "public", "key": "hosts",
+			"variables": []any{map[string]any{"name": "host", "default": "web-1"}},
+		}))
+		if err != nil {
+			t.Fatalf("HandleCreateDashboard() error = %v", err)
+		}
+		data, _ := json.Marshal(api.reports["hosts"].MetaData)
+		if string(data) != `{"variables":[{"default":"web-1","name":"host"}]}` {
+			t.Errorf("Expected the variables in the metadata, got %s", data)
+		}
+
+		_, err = tools.HandleUpdateDashboard(s, ctx, newCallToolRequest(map[string]any{
+			"id": float64(7), "label": "Infra", "visibility": "public",
+			"variables": []any{map[string]any{"name": "namespace"}},
+		}))
+		if err != nil {
+			t.Fatalf("HandleUpdateDashboard() error = %v", err)
+		}
+		data, _ = json.Marshal(api.updated[0].MetaData)
+		if string(data) != `{"theme":"dark","variables":[{"name":"namespace"}]}` {
+			t.Errorf("Expected the variables merged into the metadata, got %s", data)
+		}
+
+		if _, err := tools.HandleUpdateDashboard(s, ctx, newCallToolRequest(map[string]any{"id": float64(7), "label": "Infra", "visibility": "public"})); err != nil {
+			t.Fatalf("HandleUpdateDashboard() error = %v", err)
+		}
+		if api.updated[1].MetaData != nil {
+			t.Errorf("Expected the metadata untouched without variables, got %v", api.updated[1].MetaData)
+		}
+
+		for _, variables := range [][]any{
+			{map[string]any{"name": "host name"}},
+			{map[string]any{"name": "host"}, map[string]any{"name": "host"}},
+		} {
+			if _, err := tools.HandleCreateDashboard(s, ctx, newCallToolRequest(map[string]any{"label": "Bad", "visibility": "public", "variables": variables})); err == nil {
+				t.Errorf("Expected an error for variables %v", variables)
+			}
+		}
+	})
+
+	// newAPI returns the infra dashboard with a CPU widget filtered on $host
+	// and $env, and grouped by the undefined $group if groupBy is set.
+	newAPI := func(groupBy bool) *dashboardAPI {
+		api := newDashboardAPI(middleware.Report{ID: 7, Key: "infra", Label: "Infra", MetaData: map[string]any{"variables": []any{
+			map[string]any{"name": "host", "default": "web-1"},
+			map[string]any{"name": "env"},
+		}}})
+		with := []any{map[string]any{"key": "ATTRIBUTE_FILTER", "value": map[string]any{"and": []any{
+			map[string]any{"host.name": map[string]any{"=": "$host"}},
+			map[string]any{"deployment.environment": map[string]any{"=": "$env"}},
+		}}, "is_arg": true}}
+		if groupBy {
+			with = append(with, map[string]any{"key": "SELECT_DATA_BY", "value": []any{"$group"}, "is_arg": true})
+		}
+		api.widgets[7] = []middleware.Widget{{ID: 50, Key: "cpu_1", Label: "CPU", WidgetAppID: 1, Config: map[string]any{"builderConfig": []any{map[string]any{
+			"columns": []any{"system.cpu.utilization"},
+			"with":    with,
+		}}}}}
+		return api
+	}
+	withOf := func(widget middleware.CustomWidget) string {
+		data, _ := json.Marshal(widget.BuilderConfig[0].With)
+		return string(data)
+	}
+	filterInput := map[string]any{
+		"columns":     []any{map[string]any{"name": "system.memory.usage"}},
+		"group_by":    []any{"host.name", "$group"},
+		"filter_with": map[string]any{"field": "host.name", "operator": "=", "value": "$host"},
+	}
+
+	t.Run("values substituted into input", func(t *testing.T) {
+		api := newAPI(false)
+		s := newTestServer(t, api.ServeHTTP)
+		_, err := tools.HandleGetWidgetData(s, ctx, newCallToolRequest(map[string]any{
+			"key": "memory", "builder_config": []any{filterInput},
+			"variables": map[string]any{"host": "db-1", "group": "host.id"},
+		}))
+		if err != nil {
+			t.Fatalf("HandleGetWidgetData() error = %v", err)
+		}
+		if got := withOf(api.requested[0]); got != `[{"key":"SELECT_DATA_BY","value":["host.name","host.id"],"is_arg":true},{"key":"ATTRIBUTE_FILTER","value":{"host.name":{"=":"db-1"}},"is_arg":true}]` {
+			t.Errorf("Unexpected query %s", got)
+		}
+	})
+
+	t.Run("dashboard defaults and stored queries", func(t *testing.T) {
+		api := newAPI(false)
+		s := newTestServer(t, api.ServeHTTP)
+		_, err := tools.HandleGetMultiWidgetData(s, ctx, newCallToolRequest(map[string]any{
+			"report_key": "infra",
+			"widgets":    []any{map[string]any{"builder_id": float64(50), "key": "cpu_1"}},
+			"variables":  map[string]any{"env": ""},
+		}))
+		if err != nil {
+			t.Fatalf("HandleGetMultiWidgetData() error = %v", err)
+		}
+		if got := withOf(api.requested[0]); got != `[{"key":"ATTRIBUTE_FILTER","value":{"and":[{"host.name":{"=":"web-1"}}]},"is_arg":true}]` {
+			t.Errorf("Expected the default host and no environment condition, got %s", got)
+		}
+	})
+
+	t.Run("get_dashboard data uses defaults", func(t *testing.T) {
+		api := newAPI(false)
+		s := newTestServer(t, api.ServeHTTP)
+		if _, err := tools.HandleGetDashboard(s, ctx, newCallToolRequest(map[string]any{"report_key": "infra", "include_data": true})); err != nil {
+			t.Fatalf("HandleGetDashboard() error = %v", err)
+		}
+		if got := withOf(api.requested[0]); got != `[{"key":"ATTRIBUTE_FILTER","value":{"and":[{"host.name":{"=":"web-1"}}]},"is_arg":true}]` {
+			t.Errorf("Expected the default host substituted, got %s", got)
+		}
+	})
+
+	t.Run("other $ text left as is", func(t *testing.T) {
+		api := newAPI(true)
+		s := newTestServer(t, api.ServeHTTP)
+		if _, err := tools.HandleGetWidgetData(s, ctx, newCallToolRequest(map[string]any{"builder_id": float64(50), "report_key": "infra"})); err != nil {
+			t.Fatalf("HandleGetWidgetData() error = %v", err)
+		}
+		if got := withOf(api.requested[0]); got != `[{"key":"ATTRIBUTE_FILTER","value":{"and":[{"host.name":{"=":"web-1"}}]},"is_arg":true},{"key":"SELECT_DATA_BY","value":["$group"],"is_arg":true}]` {
+			t.Errorf("Expected $group untouched, got %s", got)
+		}
+
+		regex := map[string]any{"columns": []any{map[string]any{"name": "system.memory.usage"}}, "filter_with": map[string]any{"field": "host.name", "operator": "=~", "value": "^web$|$HOSTNAME"}}
+		if _, err := tools.HandleGetWidgetData(s, ctx, newCallToolRequest(map[string]any{
+			"key": "memory", "builder_config": []any{regex}, "variables": map[string]any{"host": "db-1"},
+		})); err != nil {
+			t.Fatalf("HandleGetWidgetData() error = %v", err)
+		}
+		if got := withOf(api.requested[1]); !strings.Contains(got, `"^web$|$HOSTNAME"`) {
+			t.Errorf("Expected the regular expression untouched, got %s", got)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		api := newAPI(true)
+		s := newTestServer(t, api.ServeHTTP)
+		for _, args := range []map[string]any{
+			{"key": "memory", "builder_config": []any{filterInput}, "report_key": "infra", "variables": map[string]any{"region": "eu"}},
+			{"key": "memory", "builder_config": []any{filterInput}, "report_key": "missing"},
+		} {
+			if _, err := tools.HandleGetWidgetData(s, ctx, newCallToolRequest(args)); err == nil {
+				t.Errorf("Expected an error for %v", args)
+			}
+		}
+		if api.dataCalls != 0 {
+			t.Errorf("Expected no data requests, got %d", api.dataCalls)
+		}
+	})
+}